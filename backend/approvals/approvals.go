@@ -0,0 +1,200 @@
+package approvals
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"backend/database"
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+// State is where a template stands under its organization's approval policy
+type State struct {
+	Status        models.ApprovalStatus `json:"status"`
+	Required      int                   `json:"required"`
+	Approvals     int                   `json:"approvals"`
+	ContentDigest string                `json:"content_digest"`
+}
+
+// ContentDigest hashes what approvers sign off on: the template's transfers,
+// schedule, recurrence, end date and the digest of its signed authorization,
+// which covers its max totals and condition. Any edit leaves earlier approvals
+// behind.
+func ContentDigest(template models.PaymentTemplate) string {
+	sorted := make([]models.Transfer, len(template.Transfers))
+	copy(sorted, template.Transfers)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	type transfer struct {
+		Destination  string   `json:"destination"`
+		AssetID      uint     `json:"asset_id"`
+		Amount       float64  `json:"amount"`
+		FiatAmount   *float64 `json:"fiat_amount,omitempty"`
+		FiatCurrency *string  `json:"fiat_currency,omitempty"`
+	}
+	type content struct {
+		Transfers           []transfer `json:"transfers"`
+		ScheduledAt         *int64     `json:"scheduled_at,omitempty"`
+		RecurringInterval   *int64     `json:"recurring_interval,omitempty"`
+		EndsAt              *int64     `json:"ends_at,omitempty"`
+		AuthorizationDigest string     `json:"authorization_digest,omitempty"`
+	}
+
+	c := content{Transfers: make([]transfer, len(sorted)), RecurringInterval: template.RecurringInterval}
+	for i, t := range sorted {
+		c.Transfers[i] = transfer{
+			Destination:  strings.ToLower(t.DestinationUserAddress),
+			AssetID:      t.AssetID,
+			Amount:       t.Amount,
+			FiatAmount:   t.FiatAmount,
+			FiatCurrency: t.FiatCurrency,
+		}
+	}
+	if template.ScheduledAt != nil {
+		unix := template.ScheduledAt.Unix()
+		c.ScheduledAt = &unix
+	}
+	if template.EndsAt != nil {
+		unix := template.EndsAt.Unix()
+		c.EndsAt = &unix
+	}
+	if template.Authorization != nil {
+		c.AuthorizationDigest = template.Authorization.Digest
+	}
+
+	data, _ := json.Marshal(c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Required returns how many approvals the transfers need under policy: the
+// policy's base number, raised by every tier whose threshold they reach. A
+// tier only applies to transfers in its asset or currency.
+func Required(policy models.ApprovalPolicy, transfers []models.Transfer) int {
+	required := policy.RequiredApprovals
+	for _, tier := range policy.Tiers {
+		total, matched := 0.0, false
+		for _, t := range transfers {
+			switch {
+			case tier.AssetID != nil && t.FiatAmount == nil && t.AssetID == *tier.AssetID:
+				total += t.Amount
+				matched = true
+			case tier.FiatCurrency != nil && t.FiatAmount != nil && t.FiatCurrency != nil && strings.EqualFold(*t.FiatCurrency, *tier.FiatCurrency):
+				total += *t.FiatAmount
+				matched = true
+			}
+		}
+		if matched && total >= tier.MinAmount && tier.RequiredApprovals > required {
+			required = tier.RequiredApprovals
+		}
+	}
+	return required
+}
+
+// Message is the text an approver signs with personal_sign to back a decision
+func Message(templateID uint, decision models.ApprovalDecision, digest string) string {
+	return fmt.Sprintf("GoPayments: %s payment template %d\nContent digest: %s", decision, templateID, digest)
+}
+
+// Evaluate works out the template's approval state from its current transfers
+// and the decisions of members who may still approve
+func Evaluate(template models.PaymentTemplate) (State, error) {
+	state := State{Status: models.ApprovalStatusNotRequired}
+	if template.OrganizationID == nil {
+		return state, nil
+	}
+
+	var policy models.ApprovalPolicy
+	err := database.DB.Preload("Tiers").Where("organization_id = ?", *template.OrganizationID).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return state, nil
+	}
+	if err != nil {
+		return state, err
+	}
+
+	if template.Transfers == nil {
+		if err := database.DB.Where("payment_template_id = ?", template.ID).Find(&template.Transfers).Error; err != nil {
+			return state, err
+		}
+	}
+	if template.Authorization == nil {
+		var auth models.PaymentAuthorization
+		err := database.DB.Where("payment_template_id = ?", template.ID).First(&auth).Error
+		switch {
+		case err == nil:
+			template.Authorization = &auth
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return state, err
+		}
+	}
+
+	state.ContentDigest = ContentDigest(template)
+	state.Required = Required(policy, template.Transfers)
+	if state.Required == 0 {
+		return state, nil
+	}
+
+	var decisions []models.TemplateApproval
+	err = database.DB.
+		Joins("JOIN organization_members ON organization_members.user_id = template_approvals.user_id AND organization_members.organization_id = ?", *template.OrganizationID).
+		Where("template_approvals.payment_template_id = ? AND template_approvals.content_digest = ?", template.ID, state.ContentDigest).
+		Where("organization_members.role IN ?", approverRoles()).
+		Find(&decisions).Error
+	if err != nil {
+		return state, err
+	}
+
+	state.Status = models.ApprovalStatusPending
+	for _, d := range decisions {
+		if d.UserID == template.UserID {
+			// Preparers cannot approve their own templates
+			continue
+		}
+		if d.Decision == models.ApprovalDecisionReject {
+			state.Status = models.ApprovalStatusRejected
+			return state, nil
+		}
+		state.Approvals++
+	}
+	if state.Approvals >= state.Required {
+		state.Status = models.ApprovalStatusApproved
+	}
+	return state, nil
+}
+
+// Refresh evaluates the template and stores the resulting status on it
+func Refresh(template *models.PaymentTemplate) (State, error) {
+	state, err := Evaluate(*template)
+	if err != nil {
+		return state, err
+	}
+	if template.ApprovalStatus != state.Status {
+		template.ApprovalStatus = state.Status
+		err = database.DB.Model(template).Update("approval_status", state.Status).Error
+	}
+	return state, err
+}
+
+func approverRoles() []models.OrganizationRole {
+	var roles []models.OrganizationRole
+	for _, role := range []models.OrganizationRole{
+		models.OrganizationRoleOwner,
+		models.OrganizationRoleAdmin,
+		models.OrganizationRolePreparer,
+		models.OrganizationRoleApprover,
+		models.OrganizationRoleViewer,
+	} {
+		if role.Can(models.PermissionApproveTemplates) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,145 @@
+package approvals
+
+import (
+	"testing"
+	"time"
+
+	"backend/models"
+)
+
+func TestRequired(t *testing.T) {
+	usdc, eth := uint(1), uint(2)
+	eur := "EUR"
+	fiat := func(amount float64, currency string) (*float64, *string) { return &amount, &currency }
+
+	policy := models.ApprovalPolicy{
+		RequiredApprovals: 1,
+		Tiers: []models.ApprovalTier{
+			{AssetID: &usdc, MinAmount: 10000, RequiredApprovals: 2},
+			{AssetID: &usdc, MinAmount: 100000, RequiredApprovals: 3},
+			{AssetID: &eth, MinAmount: 5, RequiredApprovals: 2},
+			{FiatCurrency: &eur, MinAmount: 1000, RequiredApprovals: 4},
+		},
+	}
+	// Tiers of any amount single out an asset or currency
+	anyAmount := models.ApprovalPolicy{
+		Tiers: []models.ApprovalTier{
+			{AssetID: &eth, MinAmount: 0, RequiredApprovals: 2},
+			{FiatCurrency: &eur, MinAmount: 0, RequiredApprovals: 3},
+		},
+	}
+	fiatAmount, fiatCurrency := fiat(1500, "eur")
+	smallFiat, smallCurrency := fiat(999, "EUR")
+
+	tests := []struct {
+		name      string
+		policy    models.ApprovalPolicy
+		transfers []models.Transfer
+		want      int
+	}{
+		{name: "no policy", policy: models.ApprovalPolicy{}, transfers: []models.Transfer{{AssetID: usdc, Amount: 1e6}}, want: 0},
+		{name: "below every tier", policy: policy, transfers: []models.Transfer{{AssetID: usdc, Amount: 9999}}, want: 1},
+		{name: "tier threshold is inclusive", policy: policy, transfers: []models.Transfer{{AssetID: usdc, Amount: 10000}}, want: 2},
+		{name: "amounts of an asset add up", policy: policy, transfers: []models.Transfer{{AssetID: usdc, Amount: 60000}, {AssetID: usdc, Amount: 40000}}, want: 3},
+		{name: "assets count separately", policy: policy, transfers: []models.Transfer{{AssetID: usdc, Amount: 9000}, {AssetID: eth, Amount: 4}}, want: 1},
+		{name: "highest tier wins", policy: policy, transfers: []models.Transfer{{AssetID: usdc, Amount: 100000}, {AssetID: eth, Amount: 5}}, want: 3},
+		{name: "fiat currency ignores case", policy: policy, transfers: []models.Transfer{{AssetID: usdc, Amount: 1400, FiatAmount: fiatAmount, FiatCurrency: fiatCurrency}}, want: 4},
+		{name: "fiat below its tier", policy: policy, transfers: []models.Transfer{{AssetID: usdc, Amount: 1e6, FiatAmount: smallFiat, FiatCurrency: smallCurrency}}, want: 1},
+		{name: "zero tier of an asset", policy: anyAmount, transfers: []models.Transfer{{AssetID: eth, Amount: 0.1}}, want: 2},
+		{name: "zero tier of another asset", policy: anyAmount, transfers: []models.Transfer{{AssetID: usdc, Amount: 1e6}}, want: 0},
+		{name: "zero tier of a currency", policy: anyAmount, transfers: []models.Transfer{{AssetID: usdc, Amount: 1, FiatAmount: smallFiat, FiatCurrency: smallCurrency}}, want: 3},
+		{name: "zero tiers without transfers", policy: anyAmount, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Required(tt.policy, tt.transfers); got != tt.want {
+				t.Errorf("Required = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestContentDigest(t *testing.T) {
+	scheduledAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	interval := int64(86400000)
+	base := func() models.PaymentTemplate {
+		at := scheduledAt
+		every := interval
+		return models.PaymentTemplate{
+			ID:                1,
+			ScheduledAt:       &at,
+			RecurringInterval: &every,
+			Transfers: []models.Transfer{
+				{ID: 1, DestinationUserAddress: "0x1234567890ABCDEF1234567890abcdef12345678", AssetID: 1, Amount: 10},
+				{ID: 2, DestinationUserAddress: "0x6969174FD72466430a46e18234D0b530c9FD5f49", AssetID: 2, Amount: 0.5},
+			},
+			Authorization: &models.PaymentAuthorization{Digest: "0xaaaa"},
+		}
+	}
+	digest := ContentDigest(base())
+
+	same := []struct {
+		name   string
+		modify func(t *models.PaymentTemplate)
+	}{
+		{"transfer order", func(t *models.PaymentTemplate) {
+			t.Transfers[0], t.Transfers[1] = t.Transfers[1], t.Transfers[0]
+		}},
+		{"destination case", func(t *models.PaymentTemplate) {
+			t.Transfers[0].DestinationUserAddress = "0x1234567890abcdef1234567890abcdef12345678"
+		}},
+		{"name", func(t *models.PaymentTemplate) { t.Name = "Renamed" }},
+		{"sub-second schedule", func(t *models.PaymentTemplate) {
+			at := scheduledAt.Add(300 * time.Millisecond)
+			t.ScheduledAt = &at
+		}},
+	}
+	for _, tt := range same {
+		t.Run("same "+tt.name, func(t *testing.T) {
+			template := base()
+			tt.modify(&template)
+			if got := ContentDigest(template); got != digest {
+				t.Errorf("digest changed to %s", got)
+			}
+		})
+	}
+
+	changed := []struct {
+		name   string
+		modify func(t *models.PaymentTemplate)
+	}{
+		{"amount", func(t *models.PaymentTemplate) { t.Transfers[0].Amount = 11 }},
+		{"asset", func(t *models.PaymentTemplate) { t.Transfers[0].AssetID = 3 }},
+		{"destination", func(t *models.PaymentTemplate) {
+			t.Transfers[0].DestinationUserAddress = "0x0000000000000000000000000000000000000001"
+		}},
+		{"extra transfer", func(t *models.PaymentTemplate) {
+			t.Transfers = append(t.Transfers, models.Transfer{ID: 3, DestinationUserAddress: "0x1", AssetID: 1, Amount: 1})
+		}},
+		{"fiat amount", func(t *models.PaymentTemplate) {
+			amount, currency := 10.0, "EUR"
+			t.Transfers[0].FiatAmount, t.Transfers[0].FiatCurrency = &amount, &currency
+		}},
+		{"schedule", func(t *models.PaymentTemplate) {
+			at := scheduledAt.Add(time.Hour)
+			t.ScheduledAt = &at
+		}},
+		{"interval", func(t *models.PaymentTemplate) { t.RecurringInterval = nil }},
+		{"end date", func(t *models.PaymentTemplate) {
+			endsAt := scheduledAt.Add(30 * 24 * time.Hour)
+			t.EndsAt = &endsAt
+		}},
+		{"authorization", func(t *models.PaymentTemplate) { t.Authorization = &models.PaymentAuthorization{Digest: "0xbbbb"} }},
+		{"no authorization", func(t *models.PaymentTemplate) { t.Authorization = nil }},
+	}
+	for _, tt := range changed {
+		t.Run("changed "+tt.name, func(t *testing.T) {
+			template := base()
+			tt.modify(&template)
+			if got := ContentDigest(template); got == digest {
+				t.Errorf("digest did not change")
+			}
+		})
+	}
+}
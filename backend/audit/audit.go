@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"backend/database"
+	"backend/models"
+	"backend/proxy"
+)
+
+// ActorSystem is the actor of changes made by the scheduler
+const ActorSystem = "system"
+
+const (
+	ActionTemplateCreate   = "template.create"
+	ActionTemplateUpdate   = "template.update"
+	ActionTemplateDelete   = "template.delete"
+	ActionTemplateApprove  = "template.approve"
+	ActionTemplateReject   = "template.reject"
+	ActionTokenIssue       = "token.issue"
+	ActionTokenRefresh     = "token.refresh"
+	ActionSessionRevoke    = "session.revoke"
+	ActionExecutionSubmit  = "execution.submit"
+	ActionExecutionConfirm = "execution.confirm"
+	ActionExecutionFail    = "execution.fail"
+	ActionExecutionUnknown = "execution.unknown"
+	ActionAssetCreate      = "asset.create"
+	ActionAssetUpdate      = "asset.update"
+	ActionAssetDelete      = "asset.delete"
+)
+
+const (
+	TargetPaymentTemplate = "payment_template"
+	TargetUser            = "user"
+	TargetExecution       = "execution"
+	TargetSession         = "session"
+	TargetAsset           = "asset"
+)
+
+// Entry describes a change to record; Before and After are stored as JSON snapshots
+type Entry struct {
+	UserID     uint
+	Actor      string
+	Action     string
+	TargetType string
+	TargetID   uint
+	Before     interface{}
+	After      interface{}
+}
+
+// Record appends the entry to the audit log, with the metadata of r when the
+// change came from a request. Failures are logged and do not undo the change.
+func Record(r *http.Request, entry Entry) {
+	event := models.AuditEvent{
+		UserID:     entry.UserID,
+		Actor:      entry.Actor,
+		Action:     entry.Action,
+		TargetType: entry.TargetType,
+		TargetID:   entry.TargetID,
+		Before:     snapshot(entry.Before),
+		After:      snapshot(entry.After),
+	}
+
+	if r != nil {
+		event.Method = r.Method
+		event.Path = r.URL.Path
+		event.IPAddress = truncate(proxy.ClientIP(r), 45)
+		event.UserAgent = truncate(r.UserAgent(), 512)
+	}
+
+	if err := database.DB.Create(&event).Error; err != nil {
+		log.Printf("could not record audit event %s on %s %d: %v", entry.Action, entry.TargetType, entry.TargetID, err)
+	}
+}
+
+func snapshot(v interface{}) json.RawMessage {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Printf("could not encode audit snapshot: %v", err)
+		return nil
+	}
+	return data
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
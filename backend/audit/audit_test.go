@@ -0,0 +1,60 @@
+package audit
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/models"
+	"backend/testdb"
+)
+
+func TestRecord(t *testing.T) {
+	db := testdb.Use(t, nil)
+
+	r := httptest.NewRequest("PUT", "/templates/5", nil)
+	r.RemoteAddr = "203.0.113.9:4000"
+	r.Header.Set("User-Agent", strings.Repeat("a", 600))
+	Record(r, Entry{
+		UserID:     7,
+		Actor:      "0x6969174FD72466430a46e18234D0b530c9FD5f49",
+		Action:     ActionTemplateUpdate,
+		TargetType: TargetPaymentTemplate,
+		TargetID:   5,
+		After:      models.PaymentAuthorization{Digest: "0xdigest", Signature: "0xsecret"},
+	})
+
+	inserts := db.Execs("INSERT INTO `audit_events`")
+	if len(inserts) != 1 {
+		t.Fatalf("recorded %d audit events, want 1", len(inserts))
+	}
+
+	stored := map[string]bool{}
+	var after string
+	for _, arg := range inserts[0].Args {
+		switch v := arg.(type) {
+		case string:
+			stored[v] = true
+		case []byte:
+			after = string(v)
+		}
+	}
+	for _, want := range []string{"PUT", "/templates/5", "203.0.113.9", strings.Repeat("a", 512), ActionTemplateUpdate} {
+		if !stored[want] {
+			t.Errorf("audit event does not store %.40q", want)
+		}
+	}
+	if !strings.Contains(after, `"digest":"0xdigest"`) || strings.Contains(after, "0xsecret") {
+		t.Errorf("after snapshot = %s, want the digest without the signature", after)
+	}
+}
+
+func TestRecordWithoutRequest(t *testing.T) {
+	db := testdb.Use(t, nil)
+
+	Record(nil, Entry{UserID: 7, Actor: ActorSystem, Action: ActionTemplateUpdate, TargetType: TargetPaymentTemplate, TargetID: 5})
+
+	if len(db.Execs("INSERT INTO `audit_events`")) != 1 {
+		t.Error("system changes are not recorded")
+	}
+}
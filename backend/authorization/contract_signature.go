@@ -0,0 +1,156 @@
+package authorization
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"backend/chain"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// EIP-1271 isValidSignature(bytes32,bytes) returns this selector when the signature is valid
+var erc1271MagicValue = common.FromHex("0x1626ba7e")
+
+// ERC-6492 signatures of not yet deployed accounts end with this suffix
+var erc6492MagicSuffix = common.FromHex("0x6492649264926492649264926492649264926492649264926492649264926492")
+
+const erc1271ABI = `[{
+	"name":"isValidSignature",
+	"type":"function",
+	"stateMutability":"view",
+	"inputs":[
+		{"name":"hash","type":"bytes32"},
+		{"name":"signature","type":"bytes"}
+	],
+	"outputs":[{"name":"","type":"bytes4"}]
+}]`
+
+var parsedERC1271ABI, _ = abi.JSON(strings.NewReader(erc1271ABI))
+
+// ERC-6492 wraps the signature as abi.encode(factory, factoryCalldata, signature) + magic suffix
+var erc6492Arguments = func() abi.Arguments {
+	addressType, _ := abi.NewType("address", "", nil)
+	bytesType, _ := abi.NewType("bytes", "", nil)
+	return abi.Arguments{{Type: addressType}, {Type: bytesType}, {Type: bytesType}}
+}()
+
+var ErrContractSignatureRejected = errors.New("signature rejected by account contract")
+
+// VerifyAccountSignature accepts ECDSA signatures of address and falls back to
+// EIP-1271/ERC-6492 for smart contract wallets on the given chain
+func VerifyAccountSignature(hash common.Hash, signature string, address string, chainID uint64) error {
+	ecdsaErr := VerifySignature(hash, signature, address)
+	if ecdsaErr == nil {
+		return nil
+	}
+
+	client, err := chain.GetClient(chainID)
+	if err != nil {
+		return ecdsaErr
+	}
+	defer client.Close()
+
+	if err := VerifyContractSignature(client, hash, signature, address); err != nil {
+		if errors.Is(err, ErrSignerMismatch) {
+			return ecdsaErr
+		}
+		return err
+	}
+	return nil
+}
+
+// VerifyContractSignature checks the signature over hash with the EIP-1271
+// isValidSignature of the contract at address. ERC-6492 signatures of accounts
+// that are not deployed yet are checked by simulating the deployment first.
+func VerifyContractSignature(client *ethclient.Client, hash common.Hash, signature string, address string) error {
+	ctx := context.Background()
+	account := common.HexToAddress(address)
+	sig := common.FromHex(signature)
+
+	var factory common.Address
+	var factoryCalldata []byte
+	wrapped := len(sig) > len(erc6492MagicSuffix) && bytes.HasSuffix(sig, erc6492MagicSuffix)
+	if wrapped {
+		values, err := erc6492Arguments.Unpack(sig[:len(sig)-len(erc6492MagicSuffix)])
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		factory = values[0].(common.Address)
+		factoryCalldata = values[1].([]byte)
+		sig = values[2].([]byte)
+	}
+
+	data, err := parsedERC1271ABI.Pack("isValidSignature", hash, sig)
+	if err != nil {
+		return err
+	}
+
+	code, err := client.CodeAt(ctx, account, nil)
+	if err != nil {
+		return fmt.Errorf("could not read account code: %w", err)
+	}
+
+	var out []byte
+	if len(code) > 0 {
+		out, err = client.CallContract(ctx, ethereum.CallMsg{To: &account, Data: data}, nil)
+		if err != nil {
+			return ErrContractSignatureRejected
+		}
+	} else if wrapped {
+		out, err = simulateDeployedCall(ctx, client, factory, factoryCalldata, account, data)
+		if err != nil {
+			return err
+		}
+	} else {
+		return ErrSignerMismatch
+	}
+
+	if len(out) < 4 || !bytes.Equal(out[:4], erc1271MagicValue) {
+		return ErrContractSignatureRejected
+	}
+	return nil
+}
+
+// simulateDeployedCall runs the factory call and then the account call in one
+// eth_simulateV1 block, so the account exists when it is called
+func simulateDeployedCall(ctx context.Context, client *ethclient.Client, factory common.Address, factoryCalldata []byte, account common.Address, data []byte) ([]byte, error) {
+	type call struct {
+		To    common.Address `json:"to"`
+		Input hexutil.Bytes  `json:"input"`
+	}
+	params := map[string]interface{}{
+		"blockStateCalls": []map[string]interface{}{{
+			"calls": []call{
+				{To: factory, Input: factoryCalldata},
+				{To: account, Input: data},
+			},
+		}},
+	}
+
+	var result []struct {
+		Calls []struct {
+			ReturnData hexutil.Bytes  `json:"returnData"`
+			Status     hexutil.Uint64 `json:"status"`
+		} `json:"calls"`
+	}
+	if err := client.Client().CallContext(ctx, &result, "eth_simulateV1", params, "latest"); err != nil {
+		return nil, fmt.Errorf("could not simulate account deployment: %w", err)
+	}
+	if len(result) != 1 || len(result[0].Calls) != 2 {
+		return nil, errors.New("unexpected simulation result")
+	}
+
+	deploy, check := result[0].Calls[0], result[0].Calls[1]
+	if deploy.Status != 1 || check.Status != 1 {
+		return nil, ErrContractSignatureRejected
+	}
+	return check.ReturnData, nil
+}
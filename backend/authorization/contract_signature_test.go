@@ -0,0 +1,162 @@
+package authorization
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// rpcHandler answers one JSON-RPC method
+type rpcHandler func(params []json.RawMessage) (interface{}, error)
+
+// fakeNode serves the given JSON-RPC methods; anything else fails the test
+func fakeNode(t *testing.T, methods map[string]rpcHandler) *ethclient.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request: %v", err)
+			return
+		}
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		handler, ok := methods[req.Method]
+		if !ok {
+			t.Errorf("unexpected call of %s", req.Method)
+			resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+		} else if result, err := handler(req.Params); err != nil {
+			resp["error"] = map[string]interface{}{"code": 3, "message": err.Error()}
+		} else {
+			resp["result"] = result
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+// returns answers every call with data
+func returns(data []byte) rpcHandler {
+	return func([]json.RawMessage) (interface{}, error) { return hexutil.Bytes(data), nil }
+}
+
+// word pads b to a 32 byte ABI word
+func word(b []byte) []byte {
+	return common.RightPadBytes(b, 32)
+}
+
+func TestVerifyContractSignature(t *testing.T) {
+	hash := common.HexToHash("0x01")
+	account := "0x1234567890AbcdEF1234567890aBcdef12345678"
+	signature := "0xaabbcc"
+	accountCode := returns([]byte{0x60, 0x80})
+	noCode := returns(nil)
+
+	t.Run("deployed account accepts", func(t *testing.T) {
+		var callData []byte
+		client := fakeNode(t, map[string]rpcHandler{
+			"eth_getCode": accountCode,
+			"eth_call": func(params []json.RawMessage) (interface{}, error) {
+				var msg struct {
+					Input hexutil.Bytes `json:"input"`
+				}
+				json.Unmarshal(params[0], &msg)
+				callData = msg.Input
+				return hexutil.Bytes(word(erc1271MagicValue)), nil
+			},
+		})
+		if err := VerifyContractSignature(client, hash, signature, account); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := parsedERC1271ABI.Pack("isValidSignature", hash, common.FromHex(signature))
+		if !bytes.Equal(callData, want) {
+			t.Errorf("isValidSignature called with %x, want %x", callData, want)
+		}
+	})
+
+	t.Run("deployed account rejects", func(t *testing.T) {
+		client := fakeNode(t, map[string]rpcHandler{
+			"eth_getCode": accountCode,
+			"eth_call":    returns(word([]byte{0xff, 0xff, 0xff, 0xff})),
+		})
+		if err := VerifyContractSignature(client, hash, signature, account); !errors.Is(err, ErrContractSignatureRejected) {
+			t.Errorf("err = %v, want %v", err, ErrContractSignatureRejected)
+		}
+	})
+
+	t.Run("no contract at the address", func(t *testing.T) {
+		client := fakeNode(t, map[string]rpcHandler{"eth_getCode": noCode})
+		if err := VerifyContractSignature(client, hash, signature, account); !errors.Is(err, ErrSignerMismatch) {
+			t.Errorf("err = %v, want %v", err, ErrSignerMismatch)
+		}
+	})
+
+	factory := common.HexToAddress("0x00000000000000000000000000000000000fac70")
+	factoryCalldata := []byte{0xde, 0xad}
+	packed, err := erc6492Arguments.Pack(factory, factoryCalldata, common.FromHex(signature))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wrapped := hexutil.Encode(append(packed, erc6492MagicSuffix...))
+
+	// simulation answers eth_simulateV1 with the given call statuses, checking the calls
+	simulation := func(t *testing.T, deployStatus, checkStatus uint64) rpcHandler {
+		return func(params []json.RawMessage) (interface{}, error) {
+			var opts struct {
+				BlockStateCalls []struct {
+					Calls []struct {
+						To    common.Address `json:"to"`
+						Input hexutil.Bytes  `json:"input"`
+					} `json:"calls"`
+				} `json:"blockStateCalls"`
+			}
+			json.Unmarshal(params[0], &opts)
+			calls := opts.BlockStateCalls[0].Calls
+			if len(calls) != 2 || calls[0].To != factory || !bytes.Equal(calls[0].Input, factoryCalldata) || calls[1].To != common.HexToAddress(account) {
+				t.Errorf("simulated calls %+v, want the factory then the account", calls)
+			}
+			return []interface{}{map[string]interface{}{"calls": []interface{}{
+				map[string]interface{}{"returnData": "0x", "status": hexutil.Uint64(deployStatus)},
+				map[string]interface{}{"returnData": hexutil.Bytes(word(erc1271MagicValue)), "status": hexutil.Uint64(checkStatus)},
+			}}}, nil
+		}
+	}
+
+	t.Run("undeployed account accepts after simulated deployment", func(t *testing.T) {
+		client := fakeNode(t, map[string]rpcHandler{"eth_getCode": noCode, "eth_simulateV1": simulation(t, 1, 1)})
+		if err := VerifyContractSignature(client, hash, wrapped, account); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("undeployed account whose deployment fails", func(t *testing.T) {
+		client := fakeNode(t, map[string]rpcHandler{"eth_getCode": noCode, "eth_simulateV1": simulation(t, 0, 1)})
+		if err := VerifyContractSignature(client, hash, wrapped, account); !errors.Is(err, ErrContractSignatureRejected) {
+			t.Errorf("err = %v, want %v", err, ErrContractSignatureRejected)
+		}
+	})
+
+	t.Run("malformed ERC-6492 wrapper", func(t *testing.T) {
+		client := fakeNode(t, nil)
+		malformed := hexutil.Encode(append([]byte{0x01, 0x02}, erc6492MagicSuffix...))
+		if err := VerifyContractSignature(client, hash, malformed, account); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("err = %v, want %v", err, ErrInvalidSignature)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+package authorization
+
+import (
+	"crypto/rand"
+	"math/big"
+
+	"backend/chain"
+	"backend/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+const (
+	domainName    = "GoPayments"
+	domainVersion = "1"
+)
+
+var paymentTypes = apitypes.Types{
+	"EIP712Domain": {
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+	},
+	"PaymentAuthorization": {
+		{Name: "user", Type: "address"},
+		{Name: "transfers", Type: "Transfer[]"},
+		{Name: "scheduledAt", Type: "uint256"},
+		{Name: "recurringInterval", Type: "uint256"},
+		{Name: "endsAt", Type: "uint256"},
+		{Name: "maxTotals", Type: "MaxTotal[]"},
+		{Name: "condition", Type: "Condition"},
+		{Name: "nonce", Type: "uint256"},
+	},
+	"Transfer": {
+		{Name: "asset", Type: "address"},
+		{Name: "to", Type: "address"},
+		{Name: "amount", Type: "uint256"},
+		{Name: "fiatCurrency", Type: "string"},
+		{Name: "fiatAmount", Type: "uint256"},
+	},
+	"MaxTotal": {
+		{Name: "asset", Type: "address"},
+		{Name: "amount", Type: "uint256"},
+	},
+	"Condition": {
+		{Name: "conditionType", Type: "string"},
+		{Name: "comparator", Type: "string"},
+		{Name: "threshold", Type: "uint256"},
+		{Name: "asset", Type: "address"},
+		{Name: "contractAddress", Type: "address"},
+		{Name: "callData", Type: "bytes"},
+		{Name: "repeat", Type: "bool"},
+		{Name: "expiresAt", Type: "uint256"},
+	},
+}
+
+// legacyPaymentTypes is the message of authorizations signed before nonces and
+// conditions were added; the scheduler still checks them, new ones always
+// carry a nonce
+var legacyPaymentTypes = func() apitypes.Types {
+	types := apitypes.Types{}
+	for name, fields := range paymentTypes {
+		if name != "Condition" {
+			types[name] = fields
+		}
+	}
+	types["PaymentAuthorization"] = paymentTypes["PaymentAuthorization"][:6]
+	return types
+}()
+
+// NewNonce returns a random uint256, in decimal, for a payment authorization
+func NewNonce() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return new(big.Int).SetBytes(b).String()
+}
+
+// PaymentTypedData builds the EIP-712 message a user signs to let the backend
+// execute a template. Transfers and max totals must have their Asset loaded.
+// Times are unix seconds and zero when unset. Fiat-denominated transfers sign
+// their fiat amount in cents with a zero asset amount. The condition of a
+// conditional template, with its Asset loaded, is signed too; other templates
+// sign an empty one. The nonce, issued by the backend and accepted once, makes
+// every authorization unique.
+func PaymentTypedData(template models.PaymentTemplate, userAddress string, chainID uint64, maxTotals []models.AuthorizationMaxTotal, nonce string) apitypes.TypedData {
+	transfers := make([]interface{}, len(template.Transfers))
+	for i, t := range template.Transfers {
+		amount := chain.ToBaseUnits(t.Amount, t.Asset.Decimals)
+		fiatCurrency, fiatAmount := "", big.NewInt(0)
+		if t.FiatAmount != nil && t.FiatCurrency != nil {
+			amount = big.NewInt(0)
+			fiatCurrency, fiatAmount = *t.FiatCurrency, chain.ToBaseUnits(*t.FiatAmount, 2)
+		}
+
+		transfers[i] = map[string]interface{}{
+			"asset":        common.HexToAddress(t.Asset.ContractAddress).Hex(),
+			"to":           common.HexToAddress(t.DestinationUserAddress).Hex(),
+			"amount":       amount.String(),
+			"fiatCurrency": fiatCurrency,
+			"fiatAmount":   fiatAmount.String(),
+		}
+	}
+
+	totals := make([]interface{}, len(maxTotals))
+	for i, m := range maxTotals {
+		totals[i] = map[string]interface{}{
+			"asset":  common.HexToAddress(m.Asset.ContractAddress).Hex(),
+			"amount": chain.ToBaseUnits(m.Amount, m.Asset.Decimals).String(),
+		}
+	}
+
+	var scheduledAt, recurringInterval, endsAt int64
+	if template.ScheduledAt != nil {
+		scheduledAt = template.ScheduledAt.Unix()
+	}
+	if template.RecurringInterval != nil {
+		recurringInterval = *template.RecurringInterval
+	}
+	if template.EndsAt != nil {
+		endsAt = template.EndsAt.Unix()
+	}
+
+	typedData := apitypes.TypedData{
+		Types:       paymentTypes,
+		PrimaryType: "PaymentAuthorization",
+		Domain: apitypes.TypedDataDomain{
+			Name:    domainName,
+			Version: domainVersion,
+			ChainId: math.NewHexOrDecimal256(int64(chainID)),
+		},
+		Message: apitypes.TypedDataMessage{
+			"user":              common.HexToAddress(userAddress).Hex(),
+			"transfers":         transfers,
+			"scheduledAt":       big.NewInt(scheduledAt).String(),
+			"recurringInterval": big.NewInt(recurringInterval).String(),
+			"endsAt":            big.NewInt(endsAt).String(),
+			"maxTotals":         totals,
+			"condition":         conditionMessage(template.Condition),
+			"nonce":             nonce,
+		},
+	}
+	if nonce == "" {
+		typedData.Types = legacyPaymentTypes
+		delete(typedData.Message, "nonce")
+		delete(typedData.Message, "condition")
+	}
+	return typedData
+}
+
+// conditionMessage is the signed form of a condition, zero valued without one
+func conditionMessage(condition *models.PaymentCondition) map[string]interface{} {
+	if condition == nil {
+		condition = &models.PaymentCondition{}
+	}
+
+	threshold, ok := new(big.Int).SetString(condition.Threshold, 10)
+	if !ok {
+		threshold = big.NewInt(0)
+	}
+	asset, expiresAt := common.Address{}.Hex(), "0"
+	if condition.Asset != nil {
+		asset = common.HexToAddress(condition.Asset.ContractAddress).Hex()
+	}
+	if !condition.ExpiresAt.IsZero() {
+		expiresAt = big.NewInt(condition.ExpiresAt.Unix()).String()
+	}
+
+	return map[string]interface{}{
+		"conditionType":   string(condition.Type),
+		"comparator":      string(condition.Comparator),
+		"threshold":       threshold.String(),
+		"asset":           asset,
+		"contractAddress": common.HexToAddress(condition.ContractAddress).Hex(),
+		"callData":        hexutil.Encode(common.FromHex(condition.CallData)),
+		"repeat":          condition.Repeat,
+		"expiresAt":       expiresAt,
+	}
+}
+
+// Digest returns the EIP-712 hash that is signed for the typed data
+func Digest(typedData apitypes.TypedData) (common.Hash, error) {
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(hash), nil
+}
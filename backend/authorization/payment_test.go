@@ -0,0 +1,45 @@
+package authorization
+
+import (
+	"testing"
+
+	"backend/models"
+)
+
+func TestPaymentTypedDataAmounts(t *testing.T) {
+	usdc := models.Asset{ContractAddress: "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", Decimals: 6}
+	eth := models.Asset{ContractAddress: "0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE", Decimals: 18}
+	fiat, usd := 12.34, "USD"
+
+	template := models.PaymentTemplate{
+		Transfers: []models.Transfer{
+			{DestinationUserAddress: "0x6969174FD72466430a46e18234D0b530c9FD5f49", Amount: 0.3, Asset: usdc},
+			{DestinationUserAddress: "0x6969174FD72466430a46e18234D0b530c9FD5f49", Amount: 0.07, Asset: eth},
+			{DestinationUserAddress: "0x6969174FD72466430a46e18234D0b530c9FD5f49", FiatAmount: &fiat, FiatCurrency: &usd, Asset: usdc},
+		},
+	}
+	maxTotals := []models.AuthorizationMaxTotal{{Amount: 12.34, Asset: usdc}}
+	typedData := PaymentTypedData(template, "0x6969174FD72466430a46e18234D0b530c9FD5f49", 8453, maxTotals, "1")
+
+	transfers := typedData.Message["transfers"].([]interface{})
+	tests := []struct {
+		name string
+		got  interface{}
+		want string
+	}{
+		{"USDC amount", transfers[0].(map[string]interface{})["amount"], "300000"},
+		{"ETH amount", transfers[1].(map[string]interface{})["amount"], "70000000000000000"},
+		{"fiat cents", transfers[2].(map[string]interface{})["fiatAmount"], "1234"},
+		{"fiat asset amount", transfers[2].(map[string]interface{})["amount"], "0"},
+		{"max total", typedData.Message["maxTotals"].([]interface{})[0].(map[string]interface{})["amount"], "12340000"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %s", tt.name, tt.got, tt.want)
+		}
+	}
+
+	if _, err := Digest(typedData); err != nil {
+		t.Errorf("Digest: %v", err)
+	}
+}
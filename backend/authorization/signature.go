@@ -0,0 +1,38 @@
+package authorization
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	ErrInvalidSignatureLength = errors.New("invalid signature length")
+	ErrInvalidSignature       = errors.New("invalid signature")
+	ErrSignerMismatch         = errors.New("signature does not match address")
+)
+
+// VerifySignature checks that the 65 byte ECDSA signature over hash was produced by address
+func VerifySignature(hash common.Hash, signature string, address string) error {
+	sig := common.FromHex(signature)
+	if len(sig) != 65 {
+		return ErrInvalidSignatureLength
+	}
+
+	if sig[64] >= 27 {
+		sig[64] -= 27
+	}
+
+	pubKey, err := crypto.SigToPub(hash.Bytes(), sig)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	recoveredAddr := crypto.PubkeyToAddress(*pubKey)
+	if !strings.EqualFold(recoveredAddr.Hex(), address) {
+		return ErrSignerMismatch
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package chain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Network describes a chain the backend can execute payments on
+type Network struct {
+	RPC string
+	// PollInterval is how often on-chain conditions are evaluated
+	PollInterval time.Duration
+}
+
+// Networks lists the supported chains by chain ID
+var Networks = map[uint64]Network{
+	10:   {RPC: "https://invictus.ambire.com/optimism", PollInterval: 15 * time.Second},
+	8453: {RPC: "https://invictus.ambire.com/base", PollInterval: 15 * time.Second},
+}
+
+// GetClient dials the RPC endpoint for the given chain
+func GetClient(chainID uint64) (*ethclient.Client, error) {
+	network, ok := Networks[chainID]
+	if !ok {
+		return nil, fmt.Errorf("unsupported chain id: %d", chainID)
+	}
+
+	client, err := ethclient.Dial(network.RPC)
+	if err != nil {
+		return nil, err
+	}
+
+	return client, nil
+}
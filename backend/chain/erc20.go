@@ -0,0 +1,62 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// NativeAssetAddress is the placeholder contract address used for the chain's native coin
+const NativeAssetAddress = "0xeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeeee"
+
+const erc20MetadataABI = `[
+	{"name":"name","type":"function","stateMutability":"view","inputs":[],"outputs":[{"type":"string"}]},
+	{"name":"symbol","type":"function","stateMutability":"view","inputs":[],"outputs":[{"type":"string"}]},
+	{"name":"decimals","type":"function","stateMutability":"view","inputs":[],"outputs":[{"type":"uint8"}]}
+]`
+
+var parsedERC20MetadataABI, _ = abi.JSON(strings.NewReader(erc20MetadataABI))
+
+// TokenMetadata is what an ERC-20 contract reports about itself
+type TokenMetadata struct {
+	Name     string
+	Symbol   string
+	Decimals uint8
+}
+
+// ReadTokenMetadata calls name(), symbol() and decimals() on an ERC-20 contract
+func ReadTokenMetadata(ctx context.Context, client *ethclient.Client, token common.Address) (TokenMetadata, error) {
+	var metadata TokenMetadata
+
+	code, err := client.CodeAt(ctx, token, nil)
+	if err != nil {
+		return metadata, err
+	}
+	if len(code) == 0 {
+		return metadata, fmt.Errorf("no contract at %s", token.Hex())
+	}
+
+	for method, dest := range map[string]interface{}{
+		"name":     &metadata.Name,
+		"symbol":   &metadata.Symbol,
+		"decimals": &metadata.Decimals,
+	} {
+		data, err := parsedERC20MetadataABI.Pack(method)
+		if err != nil {
+			return metadata, err
+		}
+		out, err := client.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+		if err != nil {
+			return metadata, fmt.Errorf("%s() call failed: %w", method, err)
+		}
+		if err := parsedERC20MetadataABI.UnpackIntoInterface(dest, method, out); err != nil {
+			return metadata, fmt.Errorf("%s() returned an invalid value: %w", method, err)
+		}
+	}
+	return metadata, nil
+}
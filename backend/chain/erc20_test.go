@@ -0,0 +1,79 @@
+package chain
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// tokenNode is a node with an ERC-20 contract at every address that answers
+// name(), symbol() and decimals(), or no code at all without a contract
+func tokenNode(t *testing.T, contract bool) *ethclient.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage   `json:"id"`
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		var result []byte
+		switch req.Method {
+		case "eth_getCode":
+			if contract {
+				result = []byte{0x60, 0x80}
+			}
+		case "eth_call":
+			var msg struct {
+				Input hexutil.Bytes `json:"input"`
+			}
+			json.Unmarshal(req.Params[0], &msg)
+			method, err := parsedERC20MetadataABI.MethodById(msg.Input)
+			if err != nil {
+				t.Errorf("unexpected call %x", msg.Input)
+				return
+			}
+			switch method.Name {
+			case "name":
+				result, _ = method.Outputs.Pack("USD Coin")
+			case "symbol":
+				result, _ = method.Outputs.Pack("USDC")
+			case "decimals":
+				result, _ = method.Outputs.Pack(uint8(6))
+			}
+		default:
+			t.Errorf("unexpected call of %s", req.Method)
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": hexutil.Bytes(result)})
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := ethclient.Dial(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestReadTokenMetadata(t *testing.T) {
+	token := common.HexToAddress("0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913")
+
+	metadata, err := ReadTokenMetadata(t.Context(), tokenNode(t, true), token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (TokenMetadata{Name: "USD Coin", Symbol: "USDC", Decimals: 6}); metadata != want {
+		t.Errorf("metadata = %+v, want %+v", metadata, want)
+	}
+
+	if _, err := ReadTokenMetadata(t.Context(), tokenNode(t, false), token); err == nil {
+		t.Error("address without a contract was read as a token")
+	}
+}
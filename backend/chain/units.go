@@ -0,0 +1,32 @@
+package chain
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// ToBaseUnits converts a human readable amount to the asset's smallest unit.
+// The amount is read as the shortest decimal that gives back the same float64,
+// which is what the user entered, so 0.3 at 6 decimals is exactly 300000.
+func ToBaseUnits(amount float64, decimals uint8) *big.Int {
+	value, ok := new(big.Rat).SetString(strconv.FormatFloat(amount, 'f', -1, 64))
+	if !ok {
+		return big.NewInt(0)
+	}
+	return RatToBaseUnits(value, decimals)
+}
+
+// RatToBaseUnits converts an exact amount to the asset's smallest unit,
+// rounding digits beyond decimals half to even
+func RatToBaseUnits(amount *big.Rat, decimals uint8) *big.Int {
+	// amount * 10^decimals
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	num := new(big.Int).Mul(amount.Num(), scale)
+
+	quo, rem := new(big.Int).QuoRem(num, amount.Denom(), new(big.Int))
+	half := new(big.Int).Lsh(new(big.Int).Abs(rem), 1).Cmp(amount.Denom())
+	if half > 0 || half == 0 && quo.Bit(0) == 1 {
+		quo.Add(quo, big.NewInt(int64(num.Sign())))
+	}
+	return quo
+}
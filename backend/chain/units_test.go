@@ -0,0 +1,62 @@
+package chain
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestToBaseUnits(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		decimals uint8
+		want     string
+	}{
+		{0.3, 6, "300000"},
+		{0.29, 6, "290000"},
+		{12.34, 6, "12340000"},
+		{1.005, 6, "1005000"},
+		{0.000001, 6, "1"},
+		{100, 6, "100000000"},
+		{0, 6, "0"},
+		{0.07, 18, "70000000000000000"},
+		{1e-18, 18, "1"},
+		{123456789.123456789, 18, "123456789123456790000000000"},
+		{12.34, 2, "1234"},
+		{0.29, 2, "29"},
+		{30, 9, "30000000000"},
+		// Digits beyond the asset's decimals round half to even
+		{0.125, 2, "12"},
+		{0.135, 2, "14"},
+		{0.1251, 2, "13"},
+		{2.5, 0, "2"},
+		{3.5, 0, "4"},
+		{-0.125, 2, "-12"},
+		{-0.135, 2, "-14"},
+	}
+
+	for _, tt := range tests {
+		if got := ToBaseUnits(tt.amount, tt.decimals); got.String() != tt.want {
+			t.Errorf("ToBaseUnits(%v, %d) = %s, want %s", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestRatToBaseUnits(t *testing.T) {
+	tests := []struct {
+		amount   *big.Rat
+		decimals uint8
+		want     string
+	}{
+		{big.NewRat(1, 3), 6, "333333"},
+		{big.NewRat(2, 3), 6, "666667"},
+		{big.NewRat(1, 8), 2, "12"},
+		{big.NewRat(3, 8), 2, "38"},
+		{big.NewRat(1234, 100), 6, "12340000"},
+	}
+
+	for _, tt := range tests {
+		if got := RatToBaseUnits(tt.amount, tt.decimals); got.String() != tt.want {
+			t.Errorf("RatToBaseUnits(%s, %d) = %s, want %s", tt.amount, tt.decimals, got, tt.want)
+		}
+	}
+}
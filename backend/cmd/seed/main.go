@@ -14,6 +14,7 @@ func main() {
 	// Parse command-line flags
 	cleanFlag := flag.Bool("clean", false, "Clean database before seeding")
 	cleanOnlyFlag := flag.Bool("clean-only", false, "Only clean database, do not seed")
+	adminFlag := flag.String("admin", "", "Grant the admin role to this Ethereum address, do not seed")
 	flag.Parse()
 
 	// Initialize database
@@ -25,6 +26,16 @@ func main() {
 			log.Println("DB close error:", err)
 		}
 	}()
+
+	// Grant the admin role if requested
+	if *adminFlag != "" {
+		if err := grantAdmin(*adminFlag); err != nil {
+			log.Fatalf("Failed to grant admin role: %v", err)
+		}
+		log.Printf("Granted admin role to %s", *adminFlag)
+		return
+	}
+
 	// Clean database if requested
 	if *cleanFlag || *cleanOnlyFlag {
 		log.Println("Cleaning database...")
@@ -114,7 +125,7 @@ func seedAssets() ([]models.Asset, error) {
 	for i := range assets {
 		// Check if asset already exists
 		var existing models.Asset
-		result := database.DB.Where("symbol = ?", assets[i].Symbol).First(&existing)
+		result := database.DB.Where("chain_id = ? AND contract_address = ?", assets[i].ChainID, assets[i].ContractAddress).First(&existing)
 		if result.Error == nil {
 			log.Printf("Asset %s already exists, skipping", assets[i].Symbol)
 			assets[i] = existing
@@ -152,6 +163,18 @@ func seedUser(ethAddress string, email string, username string) (*models.User, e
 	return &user, nil
 }
 
+// grantAdmin makes the user of ethAddress an admin, creating the user if needed
+func grantAdmin(ethAddress string) error {
+	var user models.User
+	err := database.DB.Where("ethereum_address = ?", ethAddress).
+		Attrs(models.User{EthereumAddress: ethAddress}).
+		FirstOrCreate(&user).Error
+	if err != nil {
+		return fmt.Errorf("failed to load user: %w", err)
+	}
+	return database.DB.Model(&user).Update("is_admin", true).Error
+}
+
 func seedPaymentTemplate(userID uint, assetID uint) (*models.PaymentTemplate, error) {
 	now := time.Now()
 	tomorrow := now.Add(24 * time.Hour)
@@ -26,7 +26,8 @@ func InitDB() error {
 	dialector = mysql.Open(mysqlDSN)
 
 	DB, err = gorm.Open(dialector, &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+		Logger:         logger.Default.LogMode(logger.Info),
+		TranslateError: true,
 	})
 
 	if err != nil {
@@ -45,13 +46,54 @@ func InitDB() error {
 
 // AutoMigrate runs migrations for all models
 func AutoMigrate() error {
-	return DB.AutoMigrate(
+	err := DB.AutoMigrate(
 		&models.User{},
 		&models.Asset{},
+		&models.Organization{},
+		&models.OrganizationMember{},
+		&models.OrganizationInvitation{},
+		&models.ApprovalPolicy{},
+		&models.ApprovalTier{},
 		&models.PaymentTemplate{},
 		&models.Transfer{},
+		&models.TemplateVersion{},
+		&models.TemplateApproval{},
+		&models.SmartAccount{},
+		&models.Execution{},
+		&models.ExecutionTransfer{},
+		&models.PaymentAuthorization{},
+		&models.AuthorizationMaxTotal{},
+		&models.AuthorizationNonce{},
+		&models.TokenPermit{},
+		&models.PaymentCondition{},
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
+		&models.NotificationPreferences{},
+		&models.Notification{},
+		&models.EmailVerification{},
+		&models.AuditEvent{},
+		&models.AuthNonce{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.APIKey{},
+		&models.Contact{},
+		&models.RateLimitBucket{},
 		// Add more models here as you create them
 	)
+	if err != nil {
+		return err
+	}
+	return dropStaleIndexes()
+}
+
+// dropStaleIndexes removes indexes the models no longer declare, which
+// AutoMigrate leaves in place
+func dropStaleIndexes() error {
+	// Assets are unique per chain and contract address, not per symbol
+	if DB.Migrator().HasIndex(&models.Asset{}, "idx_assets_symbol") {
+		return DB.Migrator().DropIndex(&models.Asset{}, "idx_assets_symbol")
+	}
+	return nil
 }
 
 // CloseDB closes the database connection
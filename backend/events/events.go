@@ -0,0 +1,78 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Type identifies what happened
+type Type string
+
+const (
+	TemplateCreated    Type = "template.created"
+	TemplateUpdated    Type = "template.updated"
+	TemplateCancelled  Type = "template.cancelled"
+	TemplateScheduled  Type = "template.scheduled"
+	ExecutionSubmitted Type = "execution.submitted"
+	ExecutionConfirmed Type = "execution.confirmed"
+	ExecutionFailed    Type = "execution.failed"
+	PermitExpiring     Type = "permit.expiring"
+)
+
+// Types lists every event type, e.g. for validating subscription filters
+var Types = []Type{
+	TemplateCreated,
+	TemplateUpdated,
+	TemplateCancelled,
+	TemplateScheduled,
+	ExecutionSubmitted,
+	ExecutionConfirmed,
+	ExecutionFailed,
+	PermitExpiring,
+}
+
+// Event is something that happened to one of a user's templates
+type Event struct {
+	ID         string      `json:"id"`
+	Type       Type        `json:"type"`
+	UserID     uint        `json:"-"`
+	OccurredAt time.Time   `json:"created_at"`
+	Data       interface{} `json:"data"`
+}
+
+var (
+	mu          sync.RWMutex
+	subscribers []func(Event)
+)
+
+// Subscribe registers fn to be called with every published event
+func Subscribe(fn func(Event)) {
+	mu.Lock()
+	defer mu.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// Publish stamps the event and hands it to every subscriber in turn
+func Publish(userID uint, eventType Type, data interface{}) {
+	event := Event{
+		ID:         newID(),
+		Type:       eventType,
+		UserID:     userID,
+		OccurredAt: time.Now(),
+		Data:       data,
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	for _, fn := range subscribers {
+		fn(event)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "evt_" + hex.EncodeToString(b)
+}
@@ -0,0 +1,53 @@
+package exports
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Payment is one transfer of a Batch: planned by a template, or made by an execution
+type Payment struct {
+	ID          string  // Unique reference, at most 35 characters
+	Amount      float64 // In Currency
+	Currency    string  // Asset symbol, or the fiat currency of fiat-denominated transfers
+	Destination string  // Address paid
+	TxHash      string  // Empty for planned payments
+}
+
+// Batch is a set of payments from one account on one chain, made on one date
+type Batch struct {
+	ID          string // Unique reference, at most 35 characters
+	Date        time.Time
+	Description string // Name of the template
+	Debtor      string // Name of the payer
+	Account     string // Address paying
+	ChainID     uint64
+	Payments    []Payment
+}
+
+// accountScheme names the chain of an address in account identifications, as
+// in CAIP-10 (eip155:<chain id>)
+func accountScheme(chainID uint64) string {
+	return "eip155:" + strconv.FormatUint(chainID, 10)
+}
+
+// formatAmount writes amount with as many decimals as it has
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', -1, 64)
+}
+
+// sumAmounts adds amounts as decimals, without float rounding errors
+func sumAmounts(amounts []float64) string {
+	sum := new(big.Rat)
+	for _, amount := range amounts {
+		r, ok := new(big.Rat).SetString(formatAmount(amount))
+		if ok {
+			sum.Add(sum, r)
+		}
+	}
+	s := sum.FloatString(18)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -0,0 +1,49 @@
+package exports
+
+import (
+	"testing"
+	"time"
+)
+
+// testBatches is a made payment of two transfers and a planned one
+var testBatches = []Batch{
+	{
+		ID:          "EXEC-41",
+		Date:        time.Date(2026, 3, 2, 0, 30, 0, 0, time.FixedZone("CET", 3600)),
+		Description: "Payroll",
+		Debtor:      "0x6969174FD72466430a46e18234D0b530c9FD5f49",
+		Account:     "0x6969174FD72466430a46e18234D0b530c9FD5f49",
+		ChainID:     8453,
+		Payments: []Payment{
+			{ID: "EXEC-41-1", Amount: 0.1, Currency: "USDC", Destination: "0x1234567890AbcdEF1234567890aBcdef12345678", TxHash: "0xabc"},
+			{ID: "EXEC-41-2", Amount: 0.2, Currency: "USDC", Destination: "0x1234567890AbcdEF1234567890aBcdef12345678", TxHash: "0xabc"},
+		},
+	},
+	{
+		ID:      "TPL-5",
+		Date:    time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC),
+		Debtor:  "0x6969174FD72466430a46e18234D0b530c9FD5f49",
+		Account: "0x6969174FD72466430a46e18234D0b530c9FD5f49",
+		ChainID: 10,
+		Payments: []Payment{
+			{ID: "TPL-5-1", Amount: 1.5, Currency: "ETH", Destination: "0x1234567890AbcdEF1234567890aBcdef12345678"},
+		},
+	},
+}
+
+func TestSumAmounts(t *testing.T) {
+	tests := []struct {
+		amounts []float64
+		want    string
+	}{
+		{nil, "0"},
+		{[]float64{0.1, 0.2}, "0.3"},
+		{[]float64{1, 2.5}, "3.5"},
+		{[]float64{0.000000000000000001, 1}, "1.000000000000000001"},
+	}
+	for _, tt := range tests {
+		if got := sumAmounts(tt.amounts); got != tt.want {
+			t.Errorf("sumAmounts(%v) = %s, want %s", tt.amounts, got, tt.want)
+		}
+	}
+}
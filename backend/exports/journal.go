@@ -0,0 +1,82 @@
+package exports
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JournalFormat is an accounting package whose journal import a CSV is laid out for
+type JournalFormat string
+
+const (
+	JournalQuickBooks JournalFormat = "quickbooks"
+	JournalXero       JournalFormat = "xero"
+)
+
+// SymbolPlaceholder in a JournalAccounts account is replaced by the currency of the payment
+const SymbolPlaceholder = "{symbol}"
+
+// JournalAccounts are the ledger accounts payments are booked on
+type JournalAccounts struct {
+	Debit   string // Expense account, e.g. "Crypto Payments"
+	Credit  string // Account of the paying wallet, e.g. "{symbol} Wallet"
+	TaxRate string // Xero tax rate name, e.g. "Tax Exempt"
+}
+
+// ParseJournalFormat returns the format named s
+func ParseJournalFormat(s string) (JournalFormat, error) {
+	switch format := JournalFormat(strings.ToLower(s)); format {
+	case JournalQuickBooks, JournalXero:
+		return format, nil
+	}
+	return "", fmt.Errorf("unsupported journal format: %s", s)
+}
+
+// WriteJournal renders batches as a journal CSV in format: a journal entry per
+// batch, and per payment a debit of accounts.Debit and a credit of
+// accounts.Credit. Amounts stay in the currency of the payment.
+func WriteJournal(w io.Writer, format JournalFormat, accounts JournalAccounts, batches []Batch) error {
+	cw := csv.NewWriter(w)
+
+	switch format {
+	case JournalQuickBooks:
+		cw.Write([]string{"Journal No", "Journal Date", "Currency", "Account Name", "Debits", "Credits", "Description", "Name"})
+	case JournalXero:
+		cw.Write([]string{"*Narration", "*Date", "Description", "*AccountCode", "*TaxRate", "*Amount"})
+	default:
+		return fmt.Errorf("unsupported journal format: %s", format)
+	}
+
+	for _, b := range batches {
+		date := b.Date.UTC().Format("2006-01-02")
+		// Xero groups lines by narration and date into a journal
+		narration := b.ID
+		if b.Description != "" {
+			narration = b.Description + " " + b.ID
+		}
+
+		for _, p := range b.Payments {
+			description := fmt.Sprintf("%s %s to %s", formatAmount(p.Amount), p.Currency, p.Destination)
+			if p.TxHash != "" {
+				description += " (tx " + p.TxHash + ")"
+			}
+			debit := strings.ReplaceAll(accounts.Debit, SymbolPlaceholder, p.Currency)
+			credit := strings.ReplaceAll(accounts.Credit, SymbolPlaceholder, p.Currency)
+			amount := formatAmount(p.Amount)
+
+			switch format {
+			case JournalQuickBooks:
+				cw.Write([]string{b.ID, date, p.Currency, debit, amount, "", description, p.Destination})
+				cw.Write([]string{b.ID, date, p.Currency, credit, "", amount, description, p.Destination})
+			case JournalXero:
+				cw.Write([]string{narration, date, description, debit, accounts.TaxRate, amount})
+				cw.Write([]string{narration, date, description, credit, accounts.TaxRate, "-" + amount})
+			}
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,49 @@
+package exports
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteJournal(t *testing.T) {
+	accounts := JournalAccounts{Debit: "Crypto Payments", Credit: "{symbol} Wallet", TaxRate: "Tax Exempt"}
+	planned := testBatches[1:]
+
+	tests := []struct {
+		format JournalFormat
+		want   string
+	}{
+		{JournalQuickBooks, "Journal No,Journal Date,Currency,Account Name,Debits,Credits,Description,Name\n" +
+			"TPL-5,2026-03-05,ETH,Crypto Payments,1.5,,1.5 ETH to 0x1234567890AbcdEF1234567890aBcdef12345678,0x1234567890AbcdEF1234567890aBcdef12345678\n" +
+			"TPL-5,2026-03-05,ETH,ETH Wallet,,1.5,1.5 ETH to 0x1234567890AbcdEF1234567890aBcdef12345678,0x1234567890AbcdEF1234567890aBcdef12345678\n"},
+		{JournalXero, "*Narration,*Date,Description,*AccountCode,*TaxRate,*Amount\n" +
+			"TPL-5,2026-03-05,1.5 ETH to 0x1234567890AbcdEF1234567890aBcdef12345678,Crypto Payments,Tax Exempt,1.5\n" +
+			"TPL-5,2026-03-05,1.5 ETH to 0x1234567890AbcdEF1234567890aBcdef12345678,ETH Wallet,Tax Exempt,-1.5\n"},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := WriteJournal(&buf, tt.format, accounts, planned); err != nil {
+			t.Fatal(err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("%s journal:\n%s\nwant:\n%s", tt.format, buf.String(), tt.want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteJournal(&buf, JournalXero, accounts, testBatches[:1]); err != nil {
+		t.Fatal(err)
+	}
+	if want := "Payroll EXEC-41,2026-03-01,0.1 USDC to 0x1234567890AbcdEF1234567890aBcdef12345678 (tx 0xabc),Crypto Payments,Tax Exempt,0.1\n"; !bytes.Contains(buf.Bytes(), []byte(want)) {
+		t.Errorf("made payments are not narrated with their template and transaction:\n%s", buf.String())
+	}
+}
+
+func TestParseJournalFormat(t *testing.T) {
+	if format, err := ParseJournalFormat("Xero"); err != nil || format != JournalXero {
+		t.Errorf("ParseJournalFormat(Xero) = %q, %v", format, err)
+	}
+	if _, err := ParseJournalFormat("sage"); err == nil {
+		t.Error("ParseJournalFormat(sage) succeeded")
+	}
+}
@@ -0,0 +1,145 @@
+package exports
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+)
+
+// Pain001Namespace is the ISO 20022 customer credit transfer initiation message written by WritePain001
+const Pain001Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.09"
+
+// maxRemittanceLength is the length limit of unstructured remittance information
+const maxRemittanceLength = 140
+
+// notProvided fills mandatory agent identifications, as crypto payments have no bank
+const notProvided = "NOTPROVIDED"
+
+type pain001Document struct {
+	XMLName    xml.Name          `xml:"Document"`
+	Namespace  string            `xml:"xmlns,attr"`
+	Initiation pain001Initiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type pain001Initiation struct {
+	GroupHeader        pain001GroupHeader          `xml:"GrpHdr"`
+	PaymentInformation []pain001PaymentInformation `xml:"PmtInf"`
+}
+
+type pain001GroupHeader struct {
+	MessageID            string       `xml:"MsgId"`
+	CreationDateTime     string       `xml:"CreDtTm"`
+	NumberOfTransactions int          `xml:"NbOfTxs"`
+	ControlSum           string       `xml:"CtrlSum"`
+	InitiatingParty      pain001Party `xml:"InitgPty"`
+}
+
+type pain001Party struct {
+	Name string `xml:"Nm"`
+}
+
+type pain001Date struct {
+	Date string `xml:"Dt"`
+}
+
+type pain001Account struct {
+	ID     string `xml:"Id>Othr>Id"`
+	Scheme string `xml:"Id>Othr>SchmeNm>Prtry"`
+}
+
+type pain001Agent struct {
+	ID string `xml:"FinInstnId>Othr>Id"`
+}
+
+type pain001PaymentInformation struct {
+	ID                     string                  `xml:"PmtInfId"`
+	Method                 string                  `xml:"PmtMtd"`
+	NumberOfTransactions   int                     `xml:"NbOfTxs"`
+	ControlSum             string                  `xml:"CtrlSum"`
+	RequestedExecutionDate pain001Date             `xml:"ReqdExctnDt"`
+	Debtor                 pain001Party            `xml:"Dbtr"`
+	DebtorAccount          pain001Account          `xml:"DbtrAcct"`
+	DebtorAgent            pain001Agent            `xml:"DbtrAgt"`
+	Transactions           []pain001CreditTransfer `xml:"CdtTrfTxInf"`
+}
+
+type pain001Amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type pain001CreditTransfer struct {
+	EndToEndID      string         `xml:"PmtId>EndToEndId"`
+	Amount          pain001Amount  `xml:"Amt>InstdAmt"`
+	Creditor        pain001Party   `xml:"Cdtr"`
+	CreditorAccount pain001Account `xml:"CdtrAcct"`
+	RemittanceInfo  string         `xml:"RmtInf>Ustrd,omitempty"`
+}
+
+// WritePain001 renders batches as a pain.001 message: a payment information
+// block per batch and a credit transfer per payment. Addresses take the place
+// of account numbers, with their chain as the identification scheme, and the
+// transaction hash of made payments is the unstructured remittance information.
+// Currencies are asset symbols, so the message follows the pain.001 layout but
+// not its ISO 4217 currency codes.
+func WritePain001(w io.Writer, messageID string, initiator string, batches []Batch) error {
+	doc := pain001Document{Namespace: Pain001Namespace}
+	var amounts []float64
+
+	for _, b := range batches {
+		info := pain001PaymentInformation{
+			ID:                     b.ID,
+			Method:                 "TRF",
+			NumberOfTransactions:   len(b.Payments),
+			RequestedExecutionDate: pain001Date{Date: b.Date.UTC().Format("2006-01-02")},
+			Debtor:                 pain001Party{Name: b.Debtor},
+			DebtorAccount:          pain001Account{ID: b.Account, Scheme: accountScheme(b.ChainID)},
+			DebtorAgent:            pain001Agent{ID: notProvided},
+		}
+
+		var batchAmounts []float64
+		for _, p := range b.Payments {
+			var remittance []string
+			if p.TxHash != "" {
+				remittance = append(remittance, "tx "+p.TxHash)
+			}
+			if b.Description != "" {
+				remittance = append(remittance, b.Description)
+			}
+			remittanceInfo := strings.Join(remittance, " ")
+			if len(remittanceInfo) > maxRemittanceLength {
+				remittanceInfo = remittanceInfo[:maxRemittanceLength]
+			}
+			info.Transactions = append(info.Transactions, pain001CreditTransfer{
+				EndToEndID:      p.ID,
+				Amount:          pain001Amount{Currency: p.Currency, Value: formatAmount(p.Amount)},
+				Creditor:        pain001Party{Name: p.Destination},
+				CreditorAccount: pain001Account{ID: p.Destination, Scheme: accountScheme(b.ChainID)},
+				RemittanceInfo:  remittanceInfo,
+			})
+			batchAmounts = append(batchAmounts, p.Amount)
+		}
+		info.ControlSum = sumAmounts(batchAmounts)
+
+		doc.Initiation.PaymentInformation = append(doc.Initiation.PaymentInformation, info)
+		doc.Initiation.GroupHeader.NumberOfTransactions += len(b.Payments)
+		amounts = append(amounts, batchAmounts...)
+	}
+
+	doc.Initiation.GroupHeader.MessageID = messageID
+	doc.Initiation.GroupHeader.CreationDateTime = time.Now().UTC().Format("2006-01-02T15:04:05Z")
+	doc.Initiation.GroupHeader.ControlSum = sumAmounts(amounts)
+	doc.Initiation.GroupHeader.InitiatingParty = pain001Party{Name: initiator}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,53 @@
+package exports
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestWritePain001(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WritePain001(&buf, "MSG-1", "GoPayments", testBatches); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), xml.Header) || !strings.Contains(buf.String(), `xmlns="`+Pain001Namespace+`"`) {
+		t.Errorf("message does not start with the XML header and pain.001 namespace:\n%s", buf.String())
+	}
+
+	var doc pain001Document
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	header := doc.Initiation.GroupHeader
+	if header.MessageID != "MSG-1" || header.NumberOfTransactions != 3 || header.ControlSum != "1.8" || header.InitiatingParty.Name != "GoPayments" {
+		t.Errorf("group header = %+v", header)
+	}
+
+	if len(doc.Initiation.PaymentInformation) != 2 {
+		t.Fatalf("got %d payment information blocks, want 2", len(doc.Initiation.PaymentInformation))
+	}
+	made := doc.Initiation.PaymentInformation[0]
+	if made.ID != "EXEC-41" || made.ControlSum != "0.3" || made.NumberOfTransactions != 2 {
+		t.Errorf("payment information = %+v", made)
+	}
+	// Dates are the UTC day of the batch
+	if made.RequestedExecutionDate.Date != "2026-03-01" {
+		t.Errorf("requested execution date = %s, want 2026-03-01", made.RequestedExecutionDate.Date)
+	}
+	if made.DebtorAccount.Scheme != "eip155:8453" || made.DebtorAgent.ID != notProvided {
+		t.Errorf("debtor account = %+v, agent = %+v", made.DebtorAccount, made.DebtorAgent)
+	}
+
+	transfer := made.Transactions[1]
+	if transfer.EndToEndID != "EXEC-41-2" || transfer.Amount != (pain001Amount{Currency: "USDC", Value: "0.2"}) ||
+		transfer.CreditorAccount.ID != "0x1234567890AbcdEF1234567890aBcdef12345678" || transfer.RemittanceInfo != "tx 0xabc Payroll" {
+		t.Errorf("credit transfer = %+v", transfer)
+	}
+
+	planned := doc.Initiation.PaymentInformation[1].Transactions[0]
+	if planned.RemittanceInfo != "" || doc.Initiation.PaymentInformation[1].DebtorAccount.Scheme != "eip155:10" {
+		t.Errorf("planned credit transfer = %+v", planned)
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/jwtLogic"
+	"backend/models"
+
+	"github.com/gorilla/mux"
+)
+
+// newAPIKey returns a new key and the prefix that identifies it
+func newAPIKey() (string, string) {
+	id := make([]byte, apiKeyIDLength/2)
+	secret := make([]byte, 24)
+	rand.Read(id)
+	rand.Read(secret)
+
+	prefix := APIKeyPrefix + hex.EncodeToString(id)
+	return prefix + "_" + hex.EncodeToString(secret), prefix
+}
+
+// GetUserAPIKeys handles GET /api-keys/{userAddress}
+func GetUserAPIKeys(w http.ResponseWriter, r *http.Request) {
+	user, ok := findCookieUser(w, r)
+	if !ok {
+		return
+	}
+
+	var keys []models.APIKey
+	if err := database.DB.Where("user_id = ?", user.ID).Order("id").Find(&keys).Error; err != nil {
+		http.Error(w, "Error fetching api keys", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(keys)
+}
+
+// CreateAPIKey handles POST /api-keys/{userAddress}
+// The key itself is only returned in this response.
+func CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name       string               `json:"name"`
+		Scopes     []models.APIKeyScope `json:"scopes"`
+		ExpiresAt  int64                `json:"expiresAt"`  // Optional expiry in milliseconds
+		AllowedIPs []string             `json:"allowedIps"` // Optional IPs or CIDRs
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := findCookieUser(w, r)
+	if !ok {
+		return
+	}
+
+	if strings.TrimSpace(req.Name) == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Scopes) == 0 {
+		http.Error(w, "At least one scope is required", http.StatusBadRequest)
+		return
+	}
+	scopes := make([]string, len(req.Scopes))
+	for i, scope := range req.Scopes {
+		known := false
+		for _, s := range models.APIKeyScopes {
+			known = known || scope == s
+		}
+		if !known {
+			http.Error(w, "Unknown scope: "+string(scope), http.StatusBadRequest)
+			return
+		}
+		scopes[i] = string(scope)
+	}
+	for _, entry := range req.AllowedIPs {
+		_, _, cidrErr := net.ParseCIDR(entry)
+		if cidrErr != nil && net.ParseIP(entry) == nil {
+			http.Error(w, "Invalid IP or CIDR: "+entry, http.StatusBadRequest)
+			return
+		}
+	}
+
+	rawKey, prefix := newAPIKey()
+	sum := sha256.Sum256([]byte(rawKey))
+	key := models.APIKey{
+		UserID:     user.ID,
+		Name:       req.Name,
+		Prefix:     prefix,
+		KeyHash:    hex.EncodeToString(sum[:]),
+		Scopes:     strings.Join(scopes, ","),
+		AllowedIPs: strings.Join(req.AllowedIPs, ","),
+	}
+	if req.ExpiresAt != 0 {
+		expiresAt := time.UnixMilli(req.ExpiresAt)
+		if !expiresAt.After(time.Now()) {
+			http.Error(w, "Expiry must be in the future", http.StatusBadRequest)
+			return
+		}
+		key.ExpiresAt = &expiresAt
+	}
+
+	if err := database.DB.Create(&key).Error; err != nil {
+		http.Error(w, "Could not save api key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"api_key": key,
+		"key":     rawKey,
+	})
+}
+
+// RevokeAPIKey handles DELETE /api-keys/{keyId}
+// The key row is kept so its prefix stays recognisable in logs.
+func RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userAddress := r.Context().Value(jwtLogic.UserContextKey).(string)
+	keyId := mux.Vars(r)["keyId"]
+
+	var key models.APIKey
+	if err := database.DB.Preload("User").First(&key, "id = ?", keyId).Error; err != nil {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	if !strings.EqualFold(key.User.EthereumAddress, userAddress) {
+		http.Error(w, "API key not found", http.StatusNotFound)
+		return
+	}
+
+	if key.RevokedAt == nil {
+		now := time.Now()
+		if err := database.DB.Model(&key).Update("revoked_at", now).Error; err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "success",
+	})
+}
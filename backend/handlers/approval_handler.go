@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/approvals"
+	"backend/audit"
+	"backend/authorization"
+	"backend/database"
+	"backend/models"
+	"backend/pricing"
+	"backend/scheduler"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ApprovalTierInput struct {
+	AssetID           *uint   `json:"assetId,omitempty"`      // Tier on the amount of an asset
+	FiatCurrency      *string `json:"fiatCurrency,omitempty"` // Or on the amount of fiat-denominated transfers
+	MinAmount         float64 `json:"minAmount"`
+	RequiredApprovals int     `json:"requiredApprovals"`
+}
+
+// GetApprovalPolicy handles GET /organizations/{organizationId}/approval-policy
+// Organizations without a policy need no approvals.
+func GetApprovalPolicy(w http.ResponseWriter, r *http.Request) {
+	_, caller, ok := requireOrganizationPermission(w, r, mux.Vars(r)["organizationId"], models.PermissionViewTemplates)
+	if !ok {
+		return
+	}
+
+	policy := models.ApprovalPolicy{OrganizationID: caller.OrganizationID, Tiers: []models.ApprovalTier{}}
+	err := database.DB.Preload("Tiers").Where("organization_id = ?", caller.OrganizationID).First(&policy).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Error fetching approval policy", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// UpdateApprovalPolicy handles PUT /organizations/{organizationId}/approval-policy
+// Replaces the policy and re-evaluates the organization's active templates.
+func UpdateApprovalPolicy(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RequiredApprovals int                 `json:"requiredApprovals"`
+		Tiers             []ApprovalTierInput `json:"tiers"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	_, caller, ok := requireOrganizationPermission(w, r, mux.Vars(r)["organizationId"], models.PermissionManageMembers)
+	if !ok {
+		return
+	}
+
+	if req.RequiredApprovals < 0 {
+		http.Error(w, "Required approvals cannot be negative", http.StatusBadRequest)
+		return
+	}
+	tiers := make([]models.ApprovalTier, len(req.Tiers))
+	for i, t := range req.Tiers {
+		if (t.AssetID == nil) == (t.FiatCurrency == nil) {
+			http.Error(w, "Each tier needs either an asset or a fiat currency", http.StatusBadRequest)
+			return
+		}
+		if t.FiatCurrency != nil {
+			currency := strings.ToUpper(*t.FiatCurrency)
+			if !pricing.IsSupportedCurrency(currency) {
+				http.Error(w, "Unsupported fiat currency: "+*t.FiatCurrency, http.StatusBadRequest)
+				return
+			}
+			t.FiatCurrency = &currency
+		}
+		if t.MinAmount < 0 || t.RequiredApprovals < 1 {
+			http.Error(w, "Tiers need a non-negative minimum and at least one approval", http.StatusBadRequest)
+			return
+		}
+		tiers[i] = models.ApprovalTier{
+			AssetID:           t.AssetID,
+			FiatCurrency:      t.FiatCurrency,
+			MinAmount:         t.MinAmount,
+			RequiredApprovals: t.RequiredApprovals,
+		}
+	}
+
+	policy := models.ApprovalPolicy{OrganizationID: caller.OrganizationID}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", caller.OrganizationID).FirstOrCreate(&policy).Error; err != nil {
+			return err
+		}
+		policy.RequiredApprovals = req.RequiredApprovals
+		if err := tx.Save(&policy).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("approval_policy_id = ?", policy.ID).Delete(&models.ApprovalTier{}).Error; err != nil {
+			return err
+		}
+		for i := range tiers {
+			tiers[i].ApprovalPolicyID = policy.ID
+		}
+		if len(tiers) > 0 {
+			return tx.Create(&tiers).Error
+		}
+		return nil
+	})
+	if err != nil {
+		http.Error(w, "Could not save approval policy", http.StatusInternalServerError)
+		return
+	}
+	policy.Tiers = tiers
+
+	var templates []models.PaymentTemplate
+	if err := database.DB.Preload("Transfers").Where("organization_id = ? AND is_cancelled = ?", caller.OrganizationID, false).Find(&templates).Error; err != nil {
+		log.Printf("could not load templates of organization %d: %v", caller.OrganizationID, err)
+	}
+	for i := range templates {
+		if _, err := approvals.Refresh(&templates[i]); err != nil {
+			log.Printf("could not re-evaluate approvals of templateId=%d: %v", templates[i].ID, err)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(policy)
+}
+
+// findOrganizationTemplate loads a template with the transfers approvers sign off on
+func findOrganizationTemplate(w http.ResponseWriter, templateId string) (models.PaymentTemplate, bool) {
+	var template models.PaymentTemplate
+	if err := database.DB.Preload("Transfers.Asset").Preload("Authorization").First(&template, "id = ?", templateId).Error; err != nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return template, false
+	}
+	if template.OrganizationID == nil {
+		http.Error(w, "Only organization templates need approval", http.StatusBadRequest)
+		return template, false
+	}
+	return template, true
+}
+
+// GetTemplateApprovals handles GET /templates/{templateId}/approvals
+// Returns the approval state, the decisions on the current transfers and the
+// messages approvers may sign.
+func GetTemplateApprovals(w http.ResponseWriter, r *http.Request) {
+	template, ok := findOrganizationTemplate(w, mux.Vars(r)["templateId"])
+	if !ok {
+		return
+	}
+	if _, _, ok := requireOrganizationPermission(w, r, *template.OrganizationID, models.PermissionViewTemplates); !ok {
+		return
+	}
+
+	state, err := approvals.Refresh(&template)
+	if err != nil {
+		http.Error(w, "Error evaluating approvals", http.StatusInternalServerError)
+		return
+	}
+
+	var decisions []models.TemplateApproval
+	err = database.DB.Preload("User").
+		Where("payment_template_id = ? AND content_digest = ?", template.ID, state.ContentDigest).
+		Order("id").
+		Find(&decisions).Error
+	if err != nil {
+		http.Error(w, "Error fetching approvals", http.StatusInternalServerError)
+		return
+	}
+	for i := range decisions {
+		decisions[i].User.Email = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"state":     state,
+		"approvals": decisions,
+		"messages": map[models.ApprovalDecision]string{
+			models.ApprovalDecisionApprove: approvals.Message(template.ID, models.ApprovalDecisionApprove, state.ContentDigest),
+			models.ApprovalDecisionReject:  approvals.Message(template.ID, models.ApprovalDecisionReject, state.ContentDigest),
+		},
+	})
+}
+
+// requeueApproved queues the next run of a scheduled template that was just
+// approved, as the scheduler skipped its runs while it waited. The schedule
+// revision moves on so runs that are still queued are dropped.
+func requeueApproved(template models.PaymentTemplate) {
+	now := time.Now()
+	if template.ScheduledAt == nil || (template.EndsAt != nil && now.After(*template.EndsAt)) {
+		return
+	}
+
+	// One-off templates run once
+	if template.RecurringInterval == nil || *template.RecurringInterval <= 0 {
+		var executed int64
+		err := database.DB.Model(&models.Execution{}).
+			Where("payment_template_id = ? AND status IN ?", template.ID,
+				[]models.ExecutionStatus{models.ExecutionStatusSubmitted, models.ExecutionStatusConfirmed, models.ExecutionStatusUnknown}).
+			Count(&executed).Error
+		if err != nil || executed > 0 {
+			return
+		}
+	}
+
+	revision := template.ScheduleRevision + 1
+	err := database.DB.Model(&models.PaymentTemplate{ID: template.ID}).Update("schedule_revision", revision).Error
+	if err != nil {
+		log.Printf("could not requeue templateId=%d: %v", template.ID, err)
+		return
+	}
+	scheduler.Schedule(template.UserID, template.ID, revision, nextRun(*template.ScheduledAt, template.RecurringInterval, now))
+}
+
+// DecideTemplate handles POST /templates/{templateId}/approvals
+// Records the caller's approval or rejection of the template's current
+// transfers, optionally backed by a signature of the approval message.
+func DecideTemplate(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Decision  models.ApprovalDecision `json:"decision"`
+		Comment   string                  `json:"comment"`
+		Signature string                  `json:"signature"` // Optional personal_sign signature of the approval message
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Decision != models.ApprovalDecisionApprove && req.Decision != models.ApprovalDecisionReject {
+		http.Error(w, "Decision must be approve or reject", http.StatusBadRequest)
+		return
+	}
+
+	template, ok := findOrganizationTemplate(w, mux.Vars(r)["templateId"])
+	if !ok {
+		return
+	}
+	user, _, ok := requireOrganizationPermission(w, r, *template.OrganizationID, models.PermissionApproveTemplates)
+	if !ok {
+		return
+	}
+	if user.ID == template.UserID {
+		http.Error(w, "You cannot approve your own template", http.StatusForbidden)
+		return
+	}
+	if template.IsCancelled {
+		http.Error(w, "Template is cancelled", http.StatusBadRequest)
+		return
+	}
+
+	digest := approvals.ContentDigest(template)
+	if req.Signature != "" {
+		message := approvals.Message(template.ID, req.Decision, digest)
+		hash := common.BytesToHash(accounts.TextHash([]byte(message)))
+		var chainID uint64
+		if len(template.Transfers) > 0 {
+			chainID = template.Transfers[0].Asset.ChainID
+		}
+		if err := authorization.VerifyAccountSignature(hash, req.Signature, user.EthereumAddress, chainID); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+
+	decision := models.TemplateApproval{
+		PaymentTemplateID: template.ID,
+		UserID:            user.ID,
+		ContentDigest:     digest,
+		Decision:          req.Decision,
+		Comment:           req.Comment,
+		Signature:         req.Signature,
+	}
+	err := database.DB.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"decision", "comment", "signature", "created_at"}),
+	}).Create(&decision).Error
+	if err != nil {
+		http.Error(w, "Could not save decision", http.StatusInternalServerError)
+		return
+	}
+
+	before := template.ApprovalStatus
+	state, err := approvals.Refresh(&template)
+	if err != nil {
+		http.Error(w, "Error evaluating approvals", http.StatusInternalServerError)
+		return
+	}
+	if before != models.ApprovalStatusApproved && state.Status == models.ApprovalStatusApproved {
+		requeueApproved(template)
+	}
+
+	action := audit.ActionTemplateApprove
+	if req.Decision == models.ApprovalDecisionReject {
+		action = audit.ActionTemplateReject
+	}
+	audit.Record(r, audit.Entry{
+		UserID:     template.UserID,
+		Actor:      user.EthereumAddress,
+		Action:     action,
+		TargetType: audit.TargetPaymentTemplate,
+		TargetID:   template.ID,
+		Before:     map[string]interface{}{"approval_status": before},
+		After:      map[string]interface{}{"approval_status": state.Status, "content_digest": digest, "comment": req.Comment},
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
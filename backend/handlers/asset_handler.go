@@ -1,17 +1,36 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
+	"backend/audit"
+	"backend/chain"
 	"backend/database"
 	"backend/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
 )
 
+// tokenMetadataTimeout bounds the on-chain calls validating a new asset
+const tokenMetadataTimeout = 10 * time.Second
+
 // GetAllAssets handles GET /assets
+// Disabled assets are left out unless include_disabled=true.
 func GetAllAssets(w http.ResponseWriter, r *http.Request) {
 	var assets []models.Asset
-	result := database.DB.Find(&assets)
+	query := database.DB
+	if r.URL.Query().Get("include_disabled") != "true" {
+		query = query.Where("enabled = ?", true)
+	}
+	result := query.Find(&assets)
 
 	if result.Error != nil {
 		http.Error(w, "Error fetching assets", http.StatusInternalServerError)
@@ -21,3 +40,225 @@ func GetAllAssets(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(assets)
 }
+
+// requireAdmin loads the authenticated user and checks they are an admin,
+// writing the error response on failure
+func requireAdmin(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	user, ok := findContextUser(w, r)
+	if !ok {
+		return user, false
+	}
+	if !user.IsAdmin {
+		http.Error(w, "Admin role required", http.StatusForbidden)
+		return user, false
+	}
+	return user, true
+}
+
+type CreateAssetRequest struct {
+	Symbol          string `json:"symbol"`
+	Name            string `json:"name"`
+	Decimals        uint8  `json:"decimals"`
+	ContractAddress string `json:"contractAddress"`
+	ChainID         uint64 `json:"chainId"`
+}
+
+// CreateAsset handles POST /assets
+// ERC-20 assets are checked against the contract's name(), symbol() and decimals().
+func CreateAsset(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateAssetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	req.Symbol = strings.TrimSpace(req.Symbol)
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Symbol == "" || len(req.Symbol) > 10 || req.Name == "" {
+		http.Error(w, "Symbol (up to 10 characters) and name are required", http.StatusBadRequest)
+		return
+	}
+	if !common.IsHexAddress(req.ContractAddress) {
+		http.Error(w, "Invalid contract address", http.StatusBadRequest)
+		return
+	}
+	if _, ok := chain.Networks[req.ChainID]; !ok {
+		http.Error(w, fmt.Sprintf("Unsupported chain id: %d", req.ChainID), http.StatusBadRequest)
+		return
+	}
+
+	if !strings.EqualFold(req.ContractAddress, chain.NativeAssetAddress) {
+		client, err := chain.GetClient(req.ChainID)
+		if err != nil {
+			http.Error(w, "Could not connect to chain", http.StatusBadGateway)
+			return
+		}
+		defer client.Close()
+
+		ctx, cancel := context.WithTimeout(r.Context(), tokenMetadataTimeout)
+		defer cancel()
+		metadata, err := chain.ReadTokenMetadata(ctx, client, common.HexToAddress(req.ContractAddress))
+		if err != nil {
+			http.Error(w, "Not an ERC-20 token: "+err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+
+		var mismatches []string
+		if metadata.Symbol != req.Symbol {
+			mismatches = append(mismatches, fmt.Sprintf("symbol is %q", metadata.Symbol))
+		}
+		if metadata.Name != req.Name {
+			mismatches = append(mismatches, fmt.Sprintf("name is %q", metadata.Name))
+		}
+		if metadata.Decimals != req.Decimals {
+			mismatches = append(mismatches, fmt.Sprintf("decimals is %d", metadata.Decimals))
+		}
+		if len(mismatches) > 0 {
+			http.Error(w, "Token does not match: "+strings.Join(mismatches, ", "), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	asset := models.Asset{
+		Symbol:          req.Symbol,
+		Name:            req.Name,
+		Decimals:        req.Decimals,
+		ContractAddress: strings.ToLower(req.ContractAddress),
+		ChainID:         req.ChainID,
+		Enabled:         true,
+	}
+	if err := database.DB.Create(&asset).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(w, "An asset with this contract address already exists on this chain", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	audit.Record(r, audit.Entry{
+		UserID:     user.ID,
+		Actor:      user.EthereumAddress,
+		Action:     audit.ActionAssetCreate,
+		TargetType: audit.TargetAsset,
+		TargetID:   asset.ID,
+		After:      asset,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(asset)
+}
+
+type UpdateAssetRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// UpdateAsset handles PUT /assets/{assetId}
+// Only the enabled flag can change, the token itself is fixed once added.
+func UpdateAsset(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var req UpdateAssetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Enabled == nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var asset models.Asset
+	if err := database.DB.First(&asset, mux.Vars(r)["assetId"]).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Asset not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	before := asset
+
+	if err := database.DB.Model(&asset).Update("enabled", *req.Enabled).Error; err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	audit.Record(r, audit.Entry{
+		UserID:     user.ID,
+		Actor:      user.EthereumAddress,
+		Action:     audit.ActionAssetUpdate,
+		TargetType: audit.TargetAsset,
+		TargetID:   asset.ID,
+		Before:     before,
+		After:      asset,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(asset)
+}
+
+// DeleteAsset handles DELETE /assets/{assetId}
+// Assets that templates or executions refer to can only be disabled.
+func DeleteAsset(w http.ResponseWriter, r *http.Request) {
+	user, ok := requireAdmin(w, r)
+	if !ok {
+		return
+	}
+
+	var asset models.Asset
+	if err := database.DB.First(&asset, mux.Vars(r)["assetId"]).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Asset not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	for _, model := range []interface{}{
+		&models.Transfer{},
+		&models.ExecutionTransfer{},
+		&models.AuthorizationMaxTotal{},
+		&models.PaymentCondition{},
+		&models.TokenPermit{},
+		&models.ApprovalTier{},
+	} {
+		var count int64
+		if err := database.DB.Model(model).Where("asset_id = ?", asset.ID).Count(&count).Error; err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if count > 0 {
+			http.Error(w, "Asset is in use, disable it instead", http.StatusConflict)
+			return
+		}
+	}
+
+	// Contacts only prefer the asset, they do not keep it in use
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Contact{}).Where("default_asset_id = ?", asset.ID).Update("default_asset_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&asset).Error
+	})
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	audit.Record(r, audit.Entry{
+		UserID:     user.ID,
+		Actor:      user.EthereumAddress,
+		Action:     audit.ActionAssetDelete,
+		TargetType: audit.TargetAsset,
+		TargetID:   asset.ID,
+		Before:     asset,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/jwtLogic"
+	"backend/testdb"
+
+	"github.com/gorilla/mux"
+)
+
+// useAssetAdmin serves the caller, an admin when admin is set, and counts
+// every asset as used by inUse rows
+func useAssetAdmin(t *testing.T, admin bool, inUse int64) *testdb.DB {
+	t.Helper()
+	return testdb.Use(t, func(query string, args []driver.Value) (testdb.Rows, error) {
+		switch {
+		case strings.Contains(query, "FROM `users`"):
+			return testdb.Rows{Columns: []string{"id", "ethereum_address", "is_admin"}, Values: [][]driver.Value{{int64(7), csvTestUser.EthereumAddress, admin}}}, nil
+		case strings.Contains(query, "count(*)"):
+			return testdb.Rows{Columns: []string{"count(*)"}, Values: [][]driver.Value{{inUse}}}, nil
+		case strings.Contains(query, "FROM `assets`"):
+			return queryAssets(query, args)
+		}
+		return testdb.Rows{}, errors.New("unexpected query: " + query)
+	})
+}
+
+func assetRequest(method, path, body string, vars map[string]string) *http.Request {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r = mux.SetURLVars(r, vars)
+	return r.WithContext(context.WithValue(r.Context(), jwtLogic.UserContextKey, csvTestUser.EthereumAddress))
+}
+
+func TestCreateAsset(t *testing.T) {
+	native := `{"symbol":"ETH","name":"Ether","decimals":18,"contractAddress":"0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE","chainId":10}`
+
+	tests := []struct {
+		name  string
+		admin bool
+		body  string
+		want  int
+	}{
+		{"not an admin", false, native, http.StatusForbidden},
+		{"native coin", true, native, http.StatusCreated},
+		{"unsupported chain", true, strings.Replace(native, `"chainId":10`, `"chainId":1337`, 1), http.StatusBadRequest},
+		{"invalid address", true, strings.Replace(native, "0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE", "0x1234", 1), http.StatusBadRequest},
+		{"symbol too long", true, strings.Replace(native, `"ETH"`, `"ETHEREUMCOIN"`, 1), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := useAssetAdmin(t, tt.admin, 0)
+			w := httptest.NewRecorder()
+			CreateAsset(w, assetRequest(http.MethodPost, "/assets", tt.body, nil))
+
+			if w.Code != tt.want {
+				t.Fatalf("status = %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), tt.want)
+			}
+			inserts := db.Execs("INSERT INTO `assets`")
+			if created := tt.want == http.StatusCreated; created != (len(inserts) == 1) {
+				t.Errorf("inserted %d assets", len(inserts))
+			}
+		})
+	}
+}
+
+func TestDeleteAsset(t *testing.T) {
+	t.Run("in use", func(t *testing.T) {
+		db := useAssetAdmin(t, true, 1)
+		w := httptest.NewRecorder()
+		DeleteAsset(w, assetRequest(http.MethodDelete, "/assets/1", "", map[string]string{"assetId": "1"}))
+
+		if w.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", w.Code, http.StatusConflict)
+		}
+		if len(db.Execs("DELETE FROM `assets`")) != 0 {
+			t.Error("asset in use was deleted")
+		}
+	})
+
+	t.Run("unused", func(t *testing.T) {
+		db := useAssetAdmin(t, true, 0)
+		w := httptest.NewRecorder()
+		DeleteAsset(w, assetRequest(http.MethodDelete, "/assets/1", "", map[string]string{"assetId": "1"}))
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("status = %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), http.StatusNoContent)
+		}
+		if len(db.Execs("UPDATE `contacts` SET `default_asset_id`")) != 1 || len(db.Execs("DELETE FROM `assets`")) != 1 {
+			t.Error("unused asset was not deleted with the contacts preferring it cleared")
+		}
+	})
+}
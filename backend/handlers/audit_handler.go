@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"backend/database"
+	"backend/jwtLogic"
+	"backend/models"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 500
+)
+
+// GetAuditEvents handles GET /audit
+// Lists the caller's audit events, newest first. Optional filters: action,
+// actor, target_type, target_id, since and until (RFC 3339) and limit.
+func GetAuditEvents(w http.ResponseWriter, r *http.Request) {
+	userAddress := r.Context().Value(jwtLogic.UserContextKey).(string)
+
+	var user models.User
+	if err := database.DB.Where("ethereum_address = ?", userAddress).First(&user).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	query := database.DB.Where("user_id = ?", user.ID)
+	params := r.URL.Query()
+
+	if action := params.Get("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if actor := params.Get("actor"); actor != "" {
+		query = query.Where("actor = ?", actor)
+	}
+	if targetType := params.Get("target_type"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if targetID := params.Get("target_id"); targetID != "" {
+		id, err := strconv.ParseUint(targetID, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid target_id", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("target_id = ?", id)
+	}
+	if since := params.Get("since"); since != "" {
+		t, err := time.Parse(time.RFC3339, since)
+		if err != nil {
+			http.Error(w, "Invalid since, expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("created_at >= ?", t)
+	}
+	if until := params.Get("until"); until != "" {
+		t, err := time.Parse(time.RFC3339, until)
+		if err != nil {
+			http.Error(w, "Invalid until, expected RFC 3339", http.StatusBadRequest)
+			return
+		}
+		query = query.Where("created_at < ?", t)
+	}
+
+	limit := defaultAuditLimit
+	if l := params.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxAuditLimit)
+	}
+
+	var auditEvents []models.AuditEvent
+	if err := query.Order("id DESC").Limit(limit).Find(&auditEvents).Error; err != nil {
+		http.Error(w, "Error fetching audit events", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(auditEvents)
+}
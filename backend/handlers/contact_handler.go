@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"backend/chain"
+	"backend/database"
+	"backend/jwtLogic"
+	"backend/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// ContactRequest is the body of contact creations and updates
+type ContactRequest struct {
+	Name           string   `json:"name"`
+	Address        string   `json:"address"`
+	DefaultAssetID *uint    `json:"defaultAssetId"` // Optional asset paid when transfers name none
+	DefaultChainID *uint64  `json:"defaultChainId"` // Optional, the chain of DefaultAssetID when both are set
+	Tags           []string `json:"tags"`
+	Notes          string   `json:"notes"`
+}
+
+// contactFromRequest checks req and applies it to contact, returning the
+// message of the first invalid field
+func contactFromRequest(req ContactRequest, contact *models.Contact) error {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return errors.New("Name is required")
+	}
+	if len(name) > 100 {
+		return errors.New("Name must be at most 100 characters")
+	}
+	if !common.IsHexAddress(req.Address) {
+		return errors.New("Address is not a valid address")
+	}
+	if !validAddressChecksum(req.Address) {
+		return errors.New("Address has an invalid EIP-55 checksum")
+	}
+
+	if req.DefaultChainID != nil {
+		if _, ok := chain.Networks[*req.DefaultChainID]; !ok {
+			return fmt.Errorf("Unsupported chain id: %d", *req.DefaultChainID)
+		}
+	}
+	if req.DefaultAssetID != nil {
+		var asset models.Asset
+		if err := database.DB.First(&asset, *req.DefaultAssetID).Error; err != nil {
+			return errors.New("Default asset not found")
+		}
+		if req.DefaultChainID != nil && asset.ChainID != *req.DefaultChainID {
+			return fmt.Errorf("Asset %s is on chain %d, not %d", asset.Symbol, asset.ChainID, *req.DefaultChainID)
+		}
+	}
+
+	var tags []string
+	for _, tag := range req.Tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			continue
+		}
+		if strings.Contains(tag, ",") {
+			return errors.New("Tags cannot contain commas")
+		}
+		tags = append(tags, tag)
+	}
+
+	contact.Name = name
+	contact.Address = common.HexToAddress(req.Address).Hex()
+	contact.DefaultAssetID = req.DefaultAssetID
+	contact.DefaultChainID = req.DefaultChainID
+	contact.Tags = strings.Join(tags, ",")
+	contact.Notes = req.Notes
+	return nil
+}
+
+// GetUserContacts handles GET /contacts/{userAddress}
+// Lists the user's address book by name; tag and q (in name or address) filter it.
+func GetUserContacts(w http.ResponseWriter, r *http.Request) {
+	user, ok := findCookieUser(w, r)
+	if !ok {
+		return
+	}
+
+	query := database.DB.Preload("DefaultAsset").Where("user_id = ?", user.ID)
+	if q := r.URL.Query().Get("q"); q != "" {
+		like := "%" + strings.ToLower(q) + "%"
+		query = query.Where("LOWER(name) LIKE ? OR LOWER(address) LIKE ?", like, like)
+	}
+
+	var contacts []models.Contact
+	if err := query.Order("name, id").Find(&contacts).Error; err != nil {
+		http.Error(w, "Error fetching contacts", http.StatusInternalServerError)
+		return
+	}
+
+	if tag := r.URL.Query().Get("tag"); tag != "" {
+		tagged := []models.Contact{}
+		for _, c := range contacts {
+			if c.HasTag(tag) {
+				tagged = append(tagged, c)
+			}
+		}
+		contacts = tagged
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contacts)
+}
+
+// CreateContact handles POST /contacts/{userAddress}
+func CreateContact(w http.ResponseWriter, r *http.Request) {
+	var req ContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := findCookieUser(w, r)
+	if !ok {
+		return
+	}
+
+	contact := models.Contact{UserID: user.ID}
+	if err := contactFromRequest(req, &contact); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := database.DB.Create(&contact).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(w, "Address is already in the address book", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not save contact", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contact)
+}
+
+// findUserContact loads a contact of the authenticated user, writing the error response on failure
+func findUserContact(w http.ResponseWriter, r *http.Request) (models.Contact, bool) {
+	userAddress := r.Context().Value(jwtLogic.UserContextKey).(string)
+
+	var contact models.Contact
+	if err := database.DB.Preload("User").First(&contact, "id = ?", mux.Vars(r)["contactId"]).Error; err != nil {
+		http.Error(w, "Contact not found", http.StatusNotFound)
+		return contact, false
+	}
+
+	if !strings.EqualFold(contact.User.EthereumAddress, userAddress) {
+		http.Error(w, "Contact not found", http.StatusNotFound)
+		return contact, false
+	}
+
+	return contact, true
+}
+
+// UpdateContact handles PUT /contacts/{contactId}
+// Replaces every field of the contact with the request.
+func UpdateContact(w http.ResponseWriter, r *http.Request) {
+	var req ContactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	contact, ok := findUserContact(w, r)
+	if !ok {
+		return
+	}
+
+	if err := contactFromRequest(req, &contact); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err := database.DB.Model(&contact).Select("name", "address", "default_asset_id", "default_chain_id", "tags", "notes").Updates(&contact).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(w, "Address is already in the address book", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not save contact", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(contact)
+}
+
+// DeleteContact handles DELETE /contacts/{contactId}
+// Templates keep paying the address; they only lose its name.
+func DeleteContact(w http.ResponseWriter, r *http.Request) {
+	contact, ok := findUserContact(w, r)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(&contact).Error; err != nil {
+		http.Error(w, "Could not delete contact", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "success",
+	})
+}
+
+// findTransferContact loads the contact a transfer names, which must be in the address book of user
+func findTransferContact(contactID uint, user models.User) (models.Contact, error) {
+	var contact models.Contact
+	if err := database.DB.Where("id = ? AND user_id = ?", contactID, user.ID).First(&contact).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return contact, errors.New("Contact not found")
+		}
+		return contact, err
+	}
+	return contact, nil
+}
+
+// addressBookWarnings lists the transfers that pay an address missing from the address book of user
+func addressBookWarnings(user models.User, transfers []models.Transfer) []RowError {
+	var contacts []models.Contact
+	database.DB.Select("address").Where("user_id = ?", user.ID).Find(&contacts)
+
+	known := map[string]bool{}
+	for _, c := range contacts {
+		known[strings.ToLower(c.Address)] = true
+	}
+
+	warnings := []RowError{}
+	for i, t := range transfers {
+		if !known[strings.ToLower(t.DestinationUserAddress)] {
+			warnings = append(warnings, RowError{
+				Row:     i,
+				Field:   "destination",
+				Message: fmt.Sprintf("%s is not in the address book", t.DestinationUserAddress),
+			})
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"backend/models"
+	"backend/testdb"
+)
+
+// testContact is the only contact in the address book of csvTestUser
+var testContact = models.Contact{ID: 4, UserID: 7, Name: "Landlord", Address: "0x1234567890AbcdEF1234567890aBcdef12345678"}
+
+// useAddressBook serves testAssets and testContact, whose default asset is defaultAsset
+func useAddressBook(t *testing.T, defaultAsset uint) {
+	t.Helper()
+	testdb.Use(t, func(query string, args []driver.Value) (testdb.Rows, error) {
+		switch {
+		case strings.Contains(query, "FROM `assets`"):
+			return queryAssets(query, args)
+		case strings.Contains(query, "FROM `contacts`"):
+			rows := testdb.Rows{Columns: []string{"id", "user_id", "name", "address", "default_asset_id"}}
+			// Transfers look contacts up by id and owner, warnings list the owner's addresses
+			owner := args[len(args)-1]
+			if len(args) > 1 {
+				owner = args[1]
+			}
+			if len(args) > 1 && args[0] != int64(testContact.ID) {
+				return rows, nil
+			}
+			if owner == int64(testContact.UserID) {
+				rows.Values = append(rows.Values, []driver.Value{int64(testContact.ID), int64(testContact.UserID), testContact.Name, testContact.Address, int64(defaultAsset)})
+			}
+			return rows, nil
+		}
+		return testdb.Rows{}, errors.New("unexpected query: " + query)
+	})
+}
+
+func TestContactFromRequest(t *testing.T) {
+	useTestDB(t)
+	base, other := uint64(8453), uint64(10)
+	usdc := uint(1)
+
+	var contact models.Contact
+	err := contactFromRequest(ContactRequest{
+		Name:           "  Landlord ",
+		Address:        "0x1234567890abcdef1234567890abcdef12345678",
+		DefaultAssetID: &usdc,
+		DefaultChainID: &base,
+		Tags:           []string{" rent ", "", "home"},
+	}, &contact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contact.Name != "Landlord" || contact.Address != testContact.Address || contact.Tags != "rent,home" {
+		t.Errorf("contact = %+v, want a trimmed name, checksummed address and joined tags", contact)
+	}
+	if !contact.HasTag("RENT") || contact.HasTag("ren") {
+		t.Errorf("HasTag does not match whole tags ignoring case: %q", contact.Tags)
+	}
+
+	tests := []struct {
+		name string
+		req  ContactRequest
+	}{
+		{"no name", ContactRequest{Name: " ", Address: testContact.Address}},
+		{"long name", ContactRequest{Name: strings.Repeat("a", 101), Address: testContact.Address}},
+		{"invalid address", ContactRequest{Name: "a", Address: "0x1234"}},
+		{"bad checksum", ContactRequest{Name: "a", Address: "0x1234567890ABcdEF1234567890aBcdef12345678"}},
+		{"unsupported chain", ContactRequest{Name: "a", Address: testContact.Address, DefaultChainID: &[]uint64{1337}[0]}},
+		{"unknown asset", ContactRequest{Name: "a", Address: testContact.Address, DefaultAssetID: &[]uint{99}[0]}},
+		{"asset of another chain", ContactRequest{Name: "a", Address: testContact.Address, DefaultAssetID: &usdc, DefaultChainID: &other}},
+		{"comma in a tag", ContactRequest{Name: "a", Address: testContact.Address, Tags: []string{"rent,home"}}},
+	}
+	for _, tt := range tests {
+		if err := contactFromRequest(tt.req, &models.Contact{}); err == nil {
+			t.Errorf("%s: accepted", tt.name)
+		}
+	}
+}
+
+func TestTransfersToContacts(t *testing.T) {
+	useAddressBook(t, 3)
+
+	req := CreateTemplateRequest{
+		ChainID:   8453,
+		Type:      TypeSchedule,
+		Transfers: []TransferInput{{ContactID: testContact.ID, Amount: 5}},
+	}
+	transfers, err := transfersFromRequest(req, csvTestUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfers[0].DestinationUserAddress != testContact.Address || transfers[0].Asset.Symbol != "EURC" {
+		t.Errorf("transfer pays %s in %s, want the contact's address and default asset", transfers[0].DestinationUserAddress, transfers[0].Asset.Symbol)
+	}
+
+	tests := []struct {
+		name     string
+		transfer TransferInput
+		user     models.User
+		field    string
+	}{
+		{"contact of another user", TransferInput{ContactID: testContact.ID, Amount: 5}, models.User{ID: 8}, "contactId"},
+		{"unknown contact", TransferInput{ContactID: 99, Amount: 5}, csvTestUser, "contactId"},
+		{"other destination", TransferInput{ContactID: testContact.ID, Destination: csvTestUser.EthereumAddress, Amount: 5}, csvTestUser, "destination"},
+	}
+	for _, tt := range tests {
+		req.Transfers = []TransferInput{tt.transfer}
+		_, err := transfersFromRequest(req, tt.user)
+		var invalid *ValidationError
+		if !errors.As(err, &invalid) || invalid.Rows[0].Field != tt.field {
+			t.Errorf("%s: err = %v, want a %s error", tt.name, err, tt.field)
+		}
+	}
+}
+
+func TestAddressBookWarnings(t *testing.T) {
+	useAddressBook(t, 1)
+
+	warnings := addressBookWarnings(csvTestUser, []models.Transfer{
+		{DestinationUserAddress: strings.ToLower(testContact.Address)},
+		{DestinationUserAddress: csvTestUser.EthereumAddress},
+	})
+	if len(warnings) != 1 || warnings[0].Row != 1 || warnings[0].Field != "destination" {
+		t.Errorf("warnings = %+v, want one for the address missing from the address book", warnings)
+	}
+}
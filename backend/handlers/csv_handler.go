@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/jwtLogic"
+	"backend/models"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// The CSV format of a template: a header and a row for the template, then a
+// header and a row per transfer
+var (
+	csvTemplateHeader = []string{"Name", "Chain id", "User address", "Scheduled at", "Interval in seconds", "Number of transfers"}
+	csvTransferHeader = []string{"Amount", "Destination", "Asset id", "Asset symbol", "Asset decimals", "Asset address", "Asset chain id"}
+)
+
+// maxImportSize bounds the CSV body of an import
+const maxImportSize = 1 << 20
+
+// csvFields maps the fields of template validation errors to CSV columns
+var csvFields = map[string]string{
+	"asset":        "Asset id",
+	"destination":  "Destination",
+	"amount":       "Amount",
+	"fiatAmount":   "Amount",
+	"fiatCurrency": "Amount",
+}
+
+// ExportTemplateCSV handles GET /templates/{templateId}/export.csv
+func ExportTemplateCSV(w http.ResponseWriter, r *http.Request) {
+	template, err := loadVersionedTemplate(mux.Vars(r)["templateId"])
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+
+	if !authorizeTemplate(w, r, template, models.PermissionViewTemplates) {
+		return
+	}
+
+	// The format has no columns for conditions and fiat amounts
+	if template.Condition != nil {
+		http.Error(w, "Conditional templates cannot be exported to CSV", http.StatusConflict)
+		return
+	}
+	for _, t := range template.Transfers {
+		if t.FiatCurrency != nil {
+			http.Error(w, "Fiat-denominated transfers cannot be exported to CSV", http.StatusConflict)
+			return
+		}
+	}
+
+	var chainID, scheduledAt, interval string
+	if len(template.Transfers) > 0 {
+		chainID = strconv.FormatUint(template.Transfers[0].Asset.ChainID, 10)
+	}
+	if template.ScheduledAt != nil {
+		scheduledAt = template.ScheduledAt.Format(time.RFC3339Nano)
+	}
+	if template.RecurringInterval != nil {
+		interval = strconv.FormatInt(*template.RecurringInterval, 10)
+	}
+
+	records := [][]string{
+		csvTemplateHeader,
+		{template.Name, chainID, template.User.EthereumAddress, scheduledAt, interval, strconv.Itoa(len(template.Transfers))},
+		csvTransferHeader,
+	}
+	for _, t := range template.Transfers {
+		records = append(records, []string{
+			strconv.FormatFloat(t.Amount, 'f', -1, 64),
+			t.DestinationUserAddress,
+			strconv.FormatUint(uint64(t.AssetID), 10),
+			t.Asset.Symbol,
+			strconv.Itoa(int(t.Asset.Decimals)),
+			t.Asset.ContractAddress,
+			strconv.FormatUint(t.Asset.ChainID, 10),
+		})
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%d.csv", template.Name, template.ID)))
+	if err := csv.NewWriter(w).WriteAll(records); err != nil {
+		http.Error(w, "Could not write CSV", http.StatusInternalServerError)
+	}
+}
+
+// emptyCSVValue reports whether a CSV value stands for an unset field; exports
+// of the frontend write null or undefined
+func emptyCSVValue(value string) bool {
+	return value == "" || value == "null" || value == "undefined"
+}
+
+// importedAsset is the asset a CSV transfer row names, checked against the stored asset
+type importedAsset struct {
+	line     int
+	symbol   string
+	decimals string
+	address  string
+	chainID  string
+}
+
+// templateRequestFromCSV parses and checks a template in the CSV format. Every
+// problem is reported with its line, in a ValidationError.
+func templateRequestFromCSV(body io.Reader, user models.User) (CreateTemplateRequest, error) {
+	var req CreateTemplateRequest
+	invalid := &ValidationError{}
+
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	records, err := reader.ReadAll()
+	if err != nil {
+		return req, fmt.Errorf("Invalid CSV: %v", err)
+	}
+
+	checkHeader := func(line int, header []string) bool {
+		if len(records) < line || strings.Join(records[line-1], ",") != strings.Join(header, ",") {
+			invalid.add(line, "", "Expected header %q", strings.Join(header, ","))
+			return false
+		}
+		return true
+	}
+	if !checkHeader(1, csvTemplateHeader) {
+		return req, invalid
+	}
+	if len(records) < 2 || len(records[1]) != len(csvTemplateHeader) {
+		invalid.add(2, "", "Expected a template row with %d columns", len(csvTemplateHeader))
+		return req, invalid
+	}
+	if !checkHeader(3, csvTransferHeader) {
+		return req, invalid
+	}
+
+	row := records[1]
+	req.Name = strings.TrimSpace(row[0])
+	if req.Name == "" {
+		invalid.add(2, "Name", "Name is required")
+	}
+	if req.ChainID, err = strconv.ParseUint(row[1], 10, 64); err != nil {
+		invalid.add(2, "Chain id", "Chain id must be a number")
+	}
+	req.UserAddress = row[2]
+	if !strings.EqualFold(row[2], user.EthereumAddress) {
+		invalid.add(2, "User address", "Templates can only be imported for the signed in user")
+	}
+
+	req.Type = TypeNow
+	var scheduledAt time.Time
+	if !emptyCSVValue(row[3]) {
+		if scheduledAt, err = time.Parse(time.RFC3339Nano, row[3]); err != nil {
+			invalid.add(2, "Scheduled at", "Scheduled at must be an RFC 3339 time")
+		}
+		req.ScheduledAt = scheduledAt.UnixMilli()
+		req.Type = TypeSchedule
+	}
+	if !emptyCSVValue(row[4]) {
+		interval, err := strconv.ParseInt(row[4], 10, 64)
+		switch {
+		case err != nil || interval < 0:
+			invalid.add(2, "Interval in seconds", "Interval must be a whole number of seconds")
+		case interval > 0 && req.Type == TypeNow:
+			invalid.add(2, "Interval in seconds", "Recurring payments need a scheduled time")
+		case interval > 0:
+			req.RecurringInterval = interval * 1000
+			req.Type = TypeRecurring
+		}
+	}
+	// Recurring templates keep their schedule and first run at its next
+	// occurrence, so exports of running templates import again
+	if req.Type == TypeSchedule && !scheduledAt.IsZero() && !scheduledAt.After(time.Now()) {
+		invalid.add(2, "Scheduled at", "Scheduled at must be in the future")
+	}
+
+	transferRows := records[3:]
+	count, err := strconv.Atoi(row[5])
+	if err != nil || count <= 0 {
+		invalid.add(2, "Number of transfers", "Number of transfers must be a positive number")
+	} else if count != len(transferRows) {
+		invalid.add(2, "Number of transfers", "Number of transfers is %d but the file has %d transfer rows", count, len(transferRows))
+	}
+
+	// Lines of the rows that parsed, by their index in req.Transfers
+	var lines []int
+	var assets []importedAsset
+	for i, row := range transferRows {
+		line := i + 4
+		if len(row) != len(csvTransferHeader) {
+			invalid.add(line, "", "Expected %d columns, found %d", len(csvTransferHeader), len(row))
+			continue
+		}
+
+		rowErrors := len(invalid.Rows)
+		amount, err := strconv.ParseFloat(row[0], 64)
+		if err != nil {
+			invalid.add(line, "Amount", "Amount must be a number")
+		}
+		assetID, err := strconv.ParseUint(row[2], 10, 64)
+		if err != nil {
+			invalid.add(line, "Asset id", "Asset id must be a number")
+		}
+		if len(invalid.Rows) > rowErrors {
+			continue
+		}
+
+		lines = append(lines, line)
+		assets = append(assets, importedAsset{line: line, symbol: row[3], decimals: row[4], address: row[5], chainID: row[6]})
+		req.Transfers = append(req.Transfers, TransferInput{
+			Amount:      amount,
+			Destination: row[1],
+			Asset:       AssetInput{ID: uint(assetID)},
+		})
+	}
+
+	// The asset columns must describe the stored asset
+	for i, a := range assets {
+		var asset models.Asset
+		if err := database.DB.First(&asset, req.Transfers[i].Asset.ID).Error; err != nil {
+			continue // Reported by transfersFromRequest
+		}
+		if a.symbol != asset.Symbol {
+			invalid.add(a.line, "Asset symbol", "Asset %d is %s", asset.ID, asset.Symbol)
+		}
+		if a.decimals != strconv.Itoa(int(asset.Decimals)) {
+			invalid.add(a.line, "Asset decimals", "%s has %d decimals", asset.Symbol, asset.Decimals)
+		}
+		if !strings.EqualFold(a.address, asset.ContractAddress) {
+			invalid.add(a.line, "Asset address", "%s has address %s", asset.Symbol, asset.ContractAddress)
+		}
+		if a.chainID != strconv.FormatUint(asset.ChainID, 10) {
+			invalid.add(a.line, "Asset chain id", "%s is on chain %d", asset.Symbol, asset.ChainID)
+		}
+	}
+
+	// Same checks as templates created from JSON, reported on CSV lines
+	if _, err := transfersFromRequest(req, user); err != nil {
+		var rowsErr *ValidationError
+		if !errors.As(err, &rowsErr) {
+			return req, err
+		}
+		for _, e := range rowsErr.Rows {
+			invalid.Rows = append(invalid.Rows, RowError{Row: lines[e.Row], Field: csvFields[e.Field], Message: e.Message})
+		}
+	}
+
+	if len(invalid.Rows) > 0 {
+		sort.SliceStable(invalid.Rows, func(i, j int) bool { return invalid.Rows[i].Row < invalid.Rows[j].Row })
+		return req, invalid
+	}
+	return req, nil
+}
+
+// parseMaxTotals reads max_total query parameters of the form <asset id>:<amount>
+func parseMaxTotals(values []string) ([]MaxTotalInput, error) {
+	var maxTotals []MaxTotalInput
+	for _, value := range values {
+		assetID, amount, found := strings.Cut(value, ":")
+		id, err := strconv.ParseUint(assetID, 10, 64)
+		if !found || err != nil {
+			return nil, fmt.Errorf("Invalid max_total %q, expected <asset id>:<amount>", value)
+		}
+		total, err := strconv.ParseFloat(amount, 64)
+		if err != nil || total <= 0 {
+			return nil, fmt.Errorf("Invalid max_total %q, expected <asset id>:<amount>", value)
+		}
+		maxTotals = append(maxTotals, MaxTotalInput{AssetID: uint(id), Amount: total})
+	}
+	return maxTotals, nil
+}
+
+// ImportTemplateCSV handles POST /templates/import
+// Creates a template for the caller from a CSV file in the export format.
+// Scheduled and recurring templates need the signature of their EIP-712
+// message and its nonce in the signature and nonce query parameters, and
+// recurring ones a max_total (<asset id>:<amount>) per asset. With dry_run=true the file is only checked
+// and the message to sign is returned.
+func ImportTemplateCSV(w http.ResponseWriter, r *http.Request) {
+	userAddress := r.Context().Value(jwtLogic.UserContextKey).(string)
+
+	var user models.User
+	if err := database.DB.Where("ethereum_address = ?", userAddress).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	req, err := templateRequestFromCSV(http.MaxBytesReader(w, r.Body, maxImportSize), user)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	params := r.URL.Query()
+	maxTotals, err := parseMaxTotals(params["max_total"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Type != TypeNow {
+		req.Authorization = &AuthorizationInput{MaxTotals: maxTotals, Signature: params.Get("signature"), Nonce: params.Get("nonce")}
+	}
+
+	if params.Get("dry_run") == "true" {
+		writeTemplateTypedData(w, req, user)
+		return
+	}
+	createTemplate(w, r, req, user)
+}
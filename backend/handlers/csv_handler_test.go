@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/models"
+)
+
+var csvTestUser = models.User{ID: 7, EthereumAddress: "0x6969174FD72466430a46e18234D0b530c9FD5f49"}
+
+func TestTemplateRequestFromCSVSampleFiles(t *testing.T) {
+	useTestDB(t)
+
+	tests := []struct {
+		file        string
+		name        string
+		scheduledAt string
+		interval    int64
+		assetIDs    []uint
+	}{
+		{
+			file:        "Monthly Recurring Payment-1.csv",
+			name:        "Monthly Recurring Payment",
+			scheduledAt: "2026-02-09T15:09:39.686+02:00",
+			interval:    86400,
+			assetIDs:    []uint{1, 2, 3},
+		},
+		{
+			file:        "Recurring Payment-4.csv",
+			name:        "Recurring Payment",
+			scheduledAt: "2026-02-08T15:22:29+02:00",
+			interval:    18,
+			assetIDs:    []uint{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("..", "..", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			req, err := templateRequestFromCSV(f, csvTestUser)
+			if err != nil {
+				t.Fatalf("templateRequestFromCSV: %v", err)
+			}
+
+			scheduledAt, _ := time.Parse(time.RFC3339Nano, tt.scheduledAt)
+			if req.Name != tt.name || req.ChainID != 8453 || req.Type != TypeRecurring {
+				t.Errorf("got name %q, chain %d, type %s", req.Name, req.ChainID, req.Type)
+			}
+			// Past schedules are kept as signed; their first run is rolled forward on creation
+			if req.ScheduledAt != scheduledAt.UnixMilli() {
+				t.Errorf("ScheduledAt = %d, want %d", req.ScheduledAt, scheduledAt.UnixMilli())
+			}
+			if req.RecurringInterval != tt.interval*1000 {
+				t.Errorf("RecurringInterval = %d, want %d", req.RecurringInterval, tt.interval*1000)
+			}
+			if len(req.Transfers) != len(tt.assetIDs) {
+				t.Fatalf("got %d transfers, want %d", len(req.Transfers), len(tt.assetIDs))
+			}
+			for i, id := range tt.assetIDs {
+				if req.Transfers[i].Asset.ID != id {
+					t.Errorf("transfer %d has asset %d, want %d", i, req.Transfers[i].Asset.ID, id)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplateRequestFromCSVErrors(t *testing.T) {
+	useTestDB(t)
+
+	const (
+		templateHeader = "Name,Chain id,User address,Scheduled at,Interval in seconds,Number of transfers\n"
+		transferHeader = "Amount,Destination,Asset id,Asset symbol,Asset decimals,Asset address,Asset chain id\n"
+		transfer       = "1.5,0x1234567890abcdef1234567890abcdef12345678,1,USDC,6,0x833589fcd6edb6e08f4c7c32d4f71b54bda02913,8453\n"
+	)
+	future := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name  string
+		csv   string
+		row   int
+		field string
+	}{
+		{
+			name:  "past one-off schedule",
+			csv:   templateHeader + "Payment,8453," + csvTestUser.EthereumAddress + ",2026-02-08T15:22:29+02:00,,1\n" + transferHeader + transfer,
+			row:   2,
+			field: "Scheduled at",
+		},
+		{
+			name:  "another user",
+			csv:   templateHeader + "Payment,8453,0x1234567890abcdef1234567890abcdef12345678," + future + ",,1\n" + transferHeader + transfer,
+			row:   2,
+			field: "User address",
+		},
+		{
+			name:  "interval without schedule",
+			csv:   templateHeader + "Payment,8453," + csvTestUser.EthereumAddress + ",,60,1\n" + transferHeader + transfer,
+			row:   2,
+			field: "Interval in seconds",
+		},
+		{
+			name:  "wrong transfer count",
+			csv:   templateHeader + "Payment,8453," + csvTestUser.EthereumAddress + "," + future + ",,2\n" + transferHeader + transfer,
+			row:   2,
+			field: "Number of transfers",
+		},
+		{
+			name:  "invalid amount",
+			csv:   templateHeader + "Payment,8453," + csvTestUser.EthereumAddress + "," + future + ",,1\n" + transferHeader + strings.Replace(transfer, "1.5", "abc", 1),
+			row:   4,
+			field: "Amount",
+		},
+		{
+			name:  "symbol of another asset",
+			csv:   templateHeader + "Payment,8453," + csvTestUser.EthereumAddress + "," + future + ",,1\n" + transferHeader + strings.Replace(transfer, "USDC", "EURC", 1),
+			row:   4,
+			field: "Asset symbol",
+		},
+		{
+			name:  "unknown asset",
+			csv:   templateHeader + "Payment,8453," + csvTestUser.EthereumAddress + "," + future + ",,1\n" + transferHeader + strings.Replace(transfer, ",1,USDC", ",9,USDC", 1),
+			row:   4,
+			field: "Asset id",
+		},
+		{
+			name:  "bad checksum",
+			csv:   templateHeader + "Payment,8453," + csvTestUser.EthereumAddress + "," + future + ",,1\n" + transferHeader + strings.Replace(transfer, "0x1234567890abcdef", "0x1234567890ABcdef", 1),
+			row:   4,
+			field: "Destination",
+		},
+		{
+			name: "missing transfer header",
+			csv:  templateHeader + "Payment,8453," + csvTestUser.EthereumAddress + "," + future + ",,1\n" + transfer,
+			row:  3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := templateRequestFromCSV(strings.NewReader(tt.csv), csvTestUser)
+			var invalid *ValidationError
+			if !errors.As(err, &invalid) {
+				t.Fatalf("got error %v, want a ValidationError", err)
+			}
+			for _, e := range invalid.Rows {
+				if e.Row == tt.row && e.Field == tt.field {
+					return
+				}
+			}
+			t.Errorf("no error on row %d, field %q in %+v", tt.row, tt.field, invalid.Rows)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"backend/database"
+	"backend/models"
+
+	"github.com/gorilla/mux"
+)
+
+// GetTemplateExecutions handles GET /templates/{templateId}/executions
+// Lists every scheduler run of the template, newest first.
+func GetTemplateExecutions(w http.ResponseWriter, r *http.Request) {
+	templateId := mux.Vars(r)["templateId"]
+
+	var template models.PaymentTemplate
+	if err := database.DB.Preload("User").First(&template, "id = ?", templateId).Error; err != nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+
+	if !authorizeTemplate(w, r, template, models.PermissionViewTemplates) {
+		return
+	}
+
+	var executions []models.Execution
+	err := database.DB.
+		Preload("Transfers").
+		Where("payment_template_id = ?", template.ID).
+		Order("id DESC").
+		Find(&executions).Error
+	if err != nil {
+		http.Error(w, "Error fetching executions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(executions)
+}
@@ -0,0 +1,302 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"backend/database"
+	"backend/exports"
+	"backend/models"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// Ledger accounts of journal exports when the request names none
+const (
+	defaultDebitAccount  = "Crypto Payments"
+	defaultCreditAccount = exports.SymbolPlaceholder + " Wallet"
+	defaultTaxRate       = "Tax Exempt"
+)
+
+// payerName is how exports name user as the paying party
+func payerName(user models.User) string {
+	if user.Username != nil && *user.Username != "" {
+		return *user.Username
+	}
+	return user.EthereumAddress
+}
+
+// payingAccount is the address funds of user leave from on the chain: the
+// registered smart account, or the user's own address
+func payingAccount(user models.User, chainID uint64) string {
+	var account models.SmartAccount
+	if err := database.DB.Where("user_id = ? AND chain_id = ?", user.ID, chainID).First(&account).Error; err != nil {
+		return user.EthereumAddress
+	}
+	return account.Address
+}
+
+// templateBatch lists the payments template plans; fiat-denominated transfers
+// are in their fiat currency. Transfers need their Asset loaded.
+func templateBatch(template models.PaymentTemplate) exports.Batch {
+	batch := exports.Batch{
+		ID:          fmt.Sprintf("T%d-V%d", template.ID, template.Version),
+		Date:        time.Now(),
+		Description: template.Name,
+		Debtor:      payerName(template.User),
+	}
+	if template.ScheduledAt != nil {
+		batch.Date = *template.ScheduledAt
+	}
+	if len(template.Transfers) > 0 {
+		batch.ChainID = template.Transfers[0].Asset.ChainID
+	}
+	batch.Account = payingAccount(template.User, batch.ChainID)
+
+	for _, t := range template.Transfers {
+		payment := exports.Payment{
+			ID:          fmt.Sprintf("T%d-%d", template.ID, t.ID),
+			Amount:      t.Amount,
+			Currency:    t.Asset.Symbol,
+			Destination: t.DestinationUserAddress,
+		}
+		if t.FiatCurrency != nil && t.FiatAmount != nil {
+			payment.Amount = *t.FiatAmount
+			payment.Currency = *t.FiatCurrency
+		}
+		batch.Payments = append(batch.Payments, payment)
+	}
+	return batch
+}
+
+// executionDestinations finds the address each transfer of executions paid.
+// Transfers replaced by template edits are looked up in the version that ran.
+func executionDestinations(executions []models.Execution) map[uint]string {
+	destinations := map[uint]string{}
+
+	var ids []uint
+	for _, e := range executions {
+		for _, t := range e.Transfers {
+			ids = append(ids, t.TransferID)
+		}
+	}
+	if len(ids) == 0 {
+		return destinations
+	}
+
+	var transfers []models.Transfer
+	database.DB.Select("id", "destination_user_address").Where("id IN ?", ids).Find(&transfers)
+	for _, t := range transfers {
+		destinations[t.ID] = t.DestinationUserAddress
+	}
+
+	for _, e := range executions {
+		missing := false
+		for _, t := range e.Transfers {
+			if _, ok := destinations[t.TransferID]; !ok {
+				missing = true
+			}
+		}
+		if !missing {
+			continue
+		}
+
+		var version models.TemplateVersion
+		err := database.DB.
+			Where("payment_template_id = ? AND version = ?", e.PaymentTemplateID, e.TemplateVersion).
+			First(&version).Error
+		if err != nil {
+			continue
+		}
+		var snapshot TemplateSnapshot
+		if err := json.Unmarshal(version.Content, &snapshot); err != nil {
+			continue
+		}
+		for _, t := range snapshot.Transfers {
+			if _, ok := destinations[t.TransferID]; !ok {
+				destinations[t.TransferID] = t.Destination
+			}
+		}
+	}
+	return destinations
+}
+
+// executionBatches lists what each execution paid, in asset units
+func executionBatches(executions []models.Execution, user models.User) []exports.Batch {
+	destinations := executionDestinations(executions)
+
+	var batches []exports.Batch
+	for _, e := range executions {
+		batch := exports.Batch{
+			ID:      fmt.Sprintf("E%d", e.ID),
+			Date:    e.CreatedAt,
+			Debtor:  payerName(user),
+			Account: user.EthereumAddress,
+			ChainID: e.ChainID,
+		}
+		if e.ConfirmedAt != nil {
+			batch.Date = *e.ConfirmedAt
+		}
+		if e.PaymentTemplate != nil {
+			batch.Description = e.PaymentTemplate.Name
+		}
+		if e.SmartAccount != nil {
+			batch.Account = e.SmartAccount.Address
+		}
+
+		for _, t := range e.Transfers {
+			batch.Payments = append(batch.Payments, exports.Payment{
+				ID:          fmt.Sprintf("E%d-%d", e.ID, t.TransferID),
+				Amount:      t.Amount,
+				Currency:    t.Asset.Symbol,
+				Destination: destinations[t.TransferID],
+				TxHash:      e.TxHash,
+			})
+		}
+		batches = append(batches, batch)
+	}
+	return batches
+}
+
+// parseExportDate reads a date (2006-01-02) or an RFC 3339 time; a date given
+// as the end of a range includes the whole day
+func parseExportDate(value string, end bool) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return t, err
+	}
+	if end {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+// findExportExecutions loads the caller and the executions of the caller's
+// templates that made a transaction between the from and to query parameters,
+// writing the error response on failure
+func findExportExecutions(w http.ResponseWriter, r *http.Request) (models.User, []models.Execution, bool) {
+	var executions []models.Execution
+
+	from, err := parseExportDate(r.URL.Query().Get("from"), false)
+	if err != nil {
+		http.Error(w, "from must be a date (YYYY-MM-DD) or an RFC 3339 time", http.StatusBadRequest)
+		return models.User{}, nil, false
+	}
+	to, err := parseExportDate(r.URL.Query().Get("to"), true)
+	if err != nil {
+		http.Error(w, "to must be a date (YYYY-MM-DD) or an RFC 3339 time", http.StatusBadRequest)
+		return models.User{}, nil, false
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return models.User{}, nil, false
+	}
+
+	user, ok := findContextUser(w, r)
+	if !ok {
+		return user, nil, false
+	}
+
+	err = database.DB.
+		Preload("Transfers", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Transfers.Asset").
+		Preload("PaymentTemplate", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("SmartAccount").
+		Joins("JOIN payment_templates ON payment_templates.id = executions.payment_template_id").
+		Where("payment_templates.user_id = ?", user.ID).
+		Where("executions.status IN ? AND executions.tx_hash <> ''", []models.ExecutionStatus{models.ExecutionStatusSubmitted, models.ExecutionStatusConfirmed, models.ExecutionStatusUnknown}).
+		Where("executions.created_at >= ? AND executions.created_at < ?", from, to).
+		Order("executions.created_at, executions.id").
+		Find(&executions).Error
+	if err != nil {
+		http.Error(w, "Error fetching executions", http.StatusInternalServerError)
+		return user, nil, false
+	}
+	return user, executions, true
+}
+
+// ExportTemplatePain001 handles GET /templates/{templateId}/export.xml
+// Renders the payments the template plans as a pain.001 message.
+func ExportTemplatePain001(w http.ResponseWriter, r *http.Request) {
+	template, err := loadVersionedTemplate(mux.Vars(r)["templateId"])
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+
+	if !authorizeTemplate(w, r, template, models.PermissionViewTemplates) {
+		return
+	}
+
+	batch := templateBatch(template)
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("%s-%d.xml", template.Name, template.ID)))
+	if err := exports.WritePain001(w, fmt.Sprintf("%s-%d", batch.ID, time.Now().Unix()), batch.Debtor, []exports.Batch{batch}); err != nil {
+		http.Error(w, "Could not write XML", http.StatusInternalServerError)
+	}
+}
+
+// ExportExecutionsPain001 handles GET /executions/export.xml
+// Renders the caller's executions between from and to as a pain.001 message,
+// a payment information block per execution.
+func ExportExecutionsPain001(w http.ResponseWriter, r *http.Request) {
+	user, executions, ok := findExportExecutions(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/xml")
+	w.Header().Set("Content-Disposition", `attachment; filename="executions.xml"`)
+	if err := exports.WritePain001(w, fmt.Sprintf("U%d-%d", user.ID, time.Now().Unix()), payerName(user), executionBatches(executions, user)); err != nil {
+		http.Error(w, "Could not write XML", http.StatusInternalServerError)
+	}
+}
+
+// ExportExecutionsJournal handles GET /executions/export.csv
+// Renders the caller's executions between from and to as a QuickBooks or Xero
+// journal import (format), booking each transfer from credit_account to
+// debit_account; "{symbol}" in an account name is replaced by the asset symbol.
+func ExportExecutionsJournal(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	format := exports.JournalQuickBooks
+	if params.Get("format") != "" {
+		var err error
+		if format, err = exports.ParseJournalFormat(params.Get("format")); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	accounts := exports.JournalAccounts{
+		Debit:   defaultDebitAccount,
+		Credit:  defaultCreditAccount,
+		TaxRate: defaultTaxRate,
+	}
+	if v := params.Get("debit_account"); v != "" {
+		accounts.Debit = v
+	}
+	if v := params.Get("credit_account"); v != "" {
+		accounts.Credit = v
+	}
+	if v := params.Get("tax_rate"); v != "" {
+		accounts.TaxRate = v
+	}
+
+	user, executions, ok := findExportExecutions(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprintf("journal-%s.csv", format)))
+	if err := exports.WriteJournal(w, format, accounts, executionBatches(executions, user)); err != nil {
+		http.Error(w, "Could not write CSV", http.StatusInternalServerError)
+	}
+}
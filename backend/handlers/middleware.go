@@ -2,14 +2,34 @@ package handlers
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 
-	"github.com/golang-jwt/jwt/v5"
-
+	"backend/database"
 	"backend/jwtLogic"
+	"backend/models"
+	"backend/proxy"
+
+	"gorm.io/gorm"
 )
 
+// APIKeyPrefix starts every API key, so leaked keys are easy to recognise
+const APIKeyPrefix = "gpk_"
+
+// apiKeyIDLength is the length of the public part of a key after APIKeyPrefix
+const apiKeyIDLength = 8
+
+const APIKeyContextKey string = "apiKeyID"
+
 // JWTAuth reads the token from the HTTP-only cookie
+// State-changing requests must also send the session's CSRF token in X-CSRF-Token.
 func JWTAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		cookie, err := r.Cookie("token")
@@ -18,26 +38,106 @@ func JWTAuth(next http.Handler) http.Handler {
 			return
 		}
 
-		token, err := jwt.Parse(cookie.Value, func(t *jwt.Token) (interface{}, error) {
-			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return jwtLogic.JwtKey, nil
-		})
-
-		if err != nil || !token.Valid {
+		address, sessionID, err := jwtLogic.ParseToken(cookie.Value)
+		if err != nil {
 			http.Error(w, "invalid token", http.StatusUnauthorized)
 			return
 		}
 
-		claims := token.Claims.(jwt.MapClaims)
-		address, ok := claims["userAddress"].(string)
-		if !ok {
-			http.Error(w, "invalid token claims", http.StatusUnauthorized)
+		// Tokens of logged out or revoked sessions stop working before they expire
+		session, err := jwtLogic.TouchSession(sessionID)
+		if err != nil {
+			http.Error(w, "session revoked", http.StatusUnauthorized)
 			return
 		}
 
+		// Browsers attach the cookie to cross-site requests too; only our frontend knows the CSRF token
+		if jwtLogic.CSRFRequired(r) {
+			if err := jwtLogic.VerifyCSRF(r, session); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+
 		ctx := context.WithValue(r.Context(), jwtLogic.UserContextKey, address)
+		ctx = context.WithValue(ctx, jwtLogic.SessionContextKey, sessionID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// ErrInvalidAPIKey is returned for keys that were never issued
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+// VerifyAPIKey looks up the key by its prefix and checks the rest of it
+// against the stored hash. Whether the key is active is left to the caller.
+func VerifyAPIKey(rawKey string) (models.APIKey, error) {
+	var key models.APIKey
+	if !strings.HasPrefix(rawKey, APIKeyPrefix) || len(rawKey) <= len(APIKeyPrefix)+apiKeyIDLength {
+		return key, ErrInvalidAPIKey
+	}
+
+	err := database.DB.Where("prefix = ?", rawKey[:len(APIKeyPrefix)+apiKeyIDLength]).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return key, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return key, err
+	}
+
+	sum := sha256.Sum256([]byte(rawKey))
+	if subtle.ConstantTimeCompare([]byte(hex.EncodeToString(sum[:])), []byte(key.KeyHash)) != 1 {
+		return key, ErrInvalidAPIKey
+	}
+	return key, nil
+}
+
+// ScopedAuth accepts an API key granted scope in the Authorization header
+// ("Bearer gpk_...") and otherwise falls back to JWTAuth
+func ScopedAuth(scope models.APIKeyScope, next http.Handler) http.Handler {
+	jwtAuth := JWTAuth(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawKey, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || !strings.HasPrefix(rawKey, APIKeyPrefix) {
+			jwtAuth.ServeHTTP(w, r)
+			return
+		}
+
+		key, err := VerifyAPIKey(rawKey)
+		if err != nil {
+			http.Error(w, "invalid api key", http.StatusUnauthorized)
+			return
+		}
+		if err := database.DB.First(&key.User, key.UserID).Error; err != nil {
+			http.Error(w, "invalid api key", http.StatusUnauthorized)
+			return
+		}
+
+		now := time.Now()
+		if !key.Active(now) {
+			http.Error(w, "api key revoked or expired", http.StatusUnauthorized)
+			return
+		}
+
+		// The allow-list is checked against the client address, taken from
+		// X-Forwarded-For only behind a trusted proxy
+		if !key.AllowsIP(net.ParseIP(proxy.ClientIP(r))) {
+			http.Error(w, "api key not allowed from this address", http.StatusForbidden)
+			return
+		}
+
+		if !key.HasScope(scope) {
+			http.Error(w, "api key lacks scope "+string(scope), http.StatusForbidden)
+			return
+		}
+
+		if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute {
+			if err := database.DB.Model(&key).Update("last_used_at", now).Error; err != nil {
+				log.Printf("could not update api key %d: %v", key.ID, err)
+			}
+		}
+
+		ctx := context.WithValue(r.Context(), jwtLogic.UserContextKey, key.User.EthereumAddress)
+		ctx = context.WithValue(ctx, APIKeyContextKey, key.ID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/jwtLogic"
+	"backend/models"
+	"backend/testdb"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestScopedAuthAllowList(t *testing.T) {
+	const rawKey = APIKeyPrefix + "abcd1234" + "secretpart"
+	sum := sha256.Sum256([]byte(rawKey))
+	testdb.Use(t, func(query string, _ []driver.Value) (testdb.Rows, error) {
+		switch {
+		case strings.Contains(query, "FROM `api_keys`"):
+			return testdb.Rows{
+				Columns: []string{"id", "user_id", "prefix", "key_hash", "scopes", "allowed_ips"},
+				Values:  [][]driver.Value{{int64(1), int64(7), rawKey[:12], hex.EncodeToString(sum[:]), string(models.ScopeTemplatesRead), "203.0.113.0/24"}},
+			}, nil
+		case strings.Contains(query, "FROM `users`"):
+			return testdb.Rows{
+				Columns: []string{"id", "ethereum_address"},
+				Values:  [][]driver.Value{{int64(7), csvTestUser.EthereumAddress}},
+			}, nil
+		}
+		return testdb.Rows{}, errors.New("unexpected query: " + query)
+	})
+
+	tests := []struct {
+		name         string
+		trustProxy   string
+		remoteAddr   string
+		forwardedFor string
+		want         int
+	}{
+		{name: "allowed connection", remoteAddr: "203.0.113.9:4000", want: http.StatusOK},
+		{name: "other connection", remoteAddr: "198.51.100.1:4000", want: http.StatusForbidden},
+		{name: "forwarded header ignored without a trusted proxy", remoteAddr: "198.51.100.1:4000", forwardedFor: "203.0.113.9", want: http.StatusForbidden},
+		{name: "allowed client behind the proxy", trustProxy: "true", remoteAddr: "10.0.0.2:4000", forwardedFor: "203.0.113.9", want: http.StatusOK},
+		{name: "other client behind the proxy", trustProxy: "true", remoteAddr: "10.0.0.2:4000", forwardedFor: "198.51.100.1", want: http.StatusForbidden},
+		{name: "forged hop behind the proxy", trustProxy: "true", remoteAddr: "10.0.0.2:4000", forwardedFor: "203.0.113.9, 198.51.100.1", want: http.StatusForbidden},
+	}
+
+	handler := ScopedAuth(models.ScopeTemplatesRead, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUST_PROXY", tt.trustProxy)
+
+			r := httptest.NewRequest(http.MethodGet, "/templates", nil)
+			r.RemoteAddr = tt.remoteAddr
+			r.Header.Set("Authorization", "Bearer "+rawKey)
+			if tt.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), tt.want)
+			}
+		})
+	}
+}
+
+func TestJWTAuthRequiresCSRFToken(t *testing.T) {
+	keys, err := jwtLogic.NewKeySet(jwtLogic.KeysConfig{Keys: []jwtLogic.KeyConfig{{ID: "test", Algorithm: jwtLogic.AlgorithmHS256, Secret: strings.Repeat("s", 32)}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := jwtLogic.Keys
+	jwtLogic.Keys = keys
+	t.Cleanup(func() { jwtLogic.Keys = previous })
+
+	token, err := keys.Sign(jwt.MapClaims{"userAddress": csvTestUser.EthereumAddress, "sid": 3, "exp": time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testdb.Use(t, func(query string, _ []driver.Value) (testdb.Rows, error) {
+		if !strings.Contains(query, "FROM `sessions`") {
+			return testdb.Rows{}, errors.New("unexpected query: " + query)
+		}
+		return testdb.Rows{
+			Columns: []string{"id", "user_id", "expires_at", "last_used_at", "csrf_token"},
+			Values:  [][]driver.Value{{int64(3), int64(7), time.Now().Add(time.Hour), time.Now(), "session-csrf"}},
+		}, nil
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		csrf   string
+		want   int
+	}{
+		{"read without token", http.MethodGet, "", http.StatusOK},
+		{"change without token", http.MethodPost, "", http.StatusForbidden},
+		{"change with another token", http.MethodDelete, "other-csrf", http.StatusForbidden},
+		{"change with the session's token", http.MethodPut, "session-csrf", http.StatusOK},
+	}
+
+	handler := JWTAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(tt.method, "/templates/5", nil)
+			r.AddCookie(&http.Cookie{Name: "token", Value: token})
+			if tt.csrf != "" {
+				r.Header.Set(jwtLogic.CSRFHeader, tt.csrf)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.want {
+				t.Errorf("status = %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), tt.want)
+			}
+		})
+	}
+}
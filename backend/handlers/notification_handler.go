@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"backend/database"
+	"backend/jwtLogic"
+	"backend/models"
+	"backend/notifications"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// findCookieUser loads the user in the route if it matches the cookie, writing the error response on failure
+func findCookieUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	userAddressFromCookie := r.Context().Value(jwtLogic.UserContextKey).(string)
+	userAddress := mux.Vars(r)["userAddress"]
+
+	var user models.User
+	if !strings.EqualFold(userAddressFromCookie, userAddress) {
+		http.Error(w, "wrong cookie", http.StatusUnauthorized)
+		return user, false
+	}
+
+	if err := database.DB.Where("ethereum_address = ?", userAddress).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return user, false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return user, false
+	}
+
+	return user, true
+}
+
+// RequestEmailVerification handles PUT /users/{userAddress}/email
+// The address only becomes the user's email once the emailed link is opened.
+func RequestEmailVerification(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := findCookieUser(w, r)
+	if !ok {
+		return
+	}
+
+	address, err := mail.ParseAddress(req.Email)
+	if err != nil || address.Address != req.Email || len(req.Email) > 100 {
+		http.Error(w, "Invalid email", http.StatusBadRequest)
+		return
+	}
+
+	if err := notifications.RequestVerification(user, req.Email); err != nil {
+		if errors.Is(err, notifications.ErrNotConfigured) {
+			http.Error(w, "Email is not available", http.StatusServiceUnavailable)
+			return
+		}
+		http.Error(w, "Could not send verification email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "verification sent",
+	})
+}
+
+// VerifyEmail handles GET /verify-email?token=...
+func VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Token is required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := notifications.ConfirmVerification(token); err != nil {
+		if errors.Is(err, notifications.ErrInvalidToken) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(w, "Email is already in use", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Could not verify email", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte("Your email address is verified. You can close this page."))
+}
+
+// GetNotificationPreferences handles GET /users/{userAddress}/notifications
+func GetNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	user, ok := findCookieUser(w, r)
+	if !ok {
+		return
+	}
+
+	prefs, err := notifications.PreferencesFor(user.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// UpdateNotificationPreferences handles PUT /users/{userAddress}/notifications
+func UpdateNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PaymentDue      *bool `json:"payment_due"`
+		PaymentExecuted *bool `json:"payment_executed"`
+		PaymentFailed   *bool `json:"payment_failed"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := findCookieUser(w, r)
+	if !ok {
+		return
+	}
+
+	prefs, err := notifications.PreferencesFor(user.ID)
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	if req.PaymentDue != nil {
+		prefs.PaymentDue = *req.PaymentDue
+	}
+	if req.PaymentExecuted != nil {
+		prefs.PaymentExecuted = *req.PaymentExecuted
+	}
+	if req.PaymentFailed != nil {
+		prefs.PaymentFailed = *req.PaymentFailed
+	}
+
+	if err := database.DB.Save(&prefs).Error; err != nil {
+		http.Error(w, "Could not save preferences", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
@@ -0,0 +1,453 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/jwtLogic"
+	"backend/models"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// findContextUser loads the authenticated user, writing the error response on failure
+func findContextUser(w http.ResponseWriter, r *http.Request) (models.User, bool) {
+	userAddress := r.Context().Value(jwtLogic.UserContextKey).(string)
+
+	var user models.User
+	if err := database.DB.Where("ethereum_address = ?", userAddress).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return user, false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return user, false
+	}
+	return user, true
+}
+
+// requireOrganizationPermission loads the caller's membership in the organization
+// and checks its role grants permission, writing the error response on failure
+func requireOrganizationPermission(w http.ResponseWriter, r *http.Request, organizationId interface{}, permission models.OrganizationPermission) (models.User, models.OrganizationMember, bool) {
+	var member models.OrganizationMember
+
+	user, ok := findContextUser(w, r)
+	if !ok {
+		return user, member, false
+	}
+
+	err := database.DB.Where("organization_id = ? AND user_id = ?", organizationId, user.ID).First(&member).Error
+	if err != nil {
+		// Non-members cannot tell whether the organization exists
+		http.Error(w, "Organization not found", http.StatusNotFound)
+		return user, member, false
+	}
+
+	if !member.Role.Can(permission) {
+		http.Error(w, "Your role does not allow this", http.StatusForbidden)
+		return user, member, false
+	}
+	return user, member, true
+}
+
+// authorizeTemplate checks the caller may act on the template: personal
+// templates only by their user, organization templates by role
+func authorizeTemplate(w http.ResponseWriter, r *http.Request, template models.PaymentTemplate, permission models.OrganizationPermission) bool {
+	if template.OrganizationID != nil {
+		_, _, ok := requireOrganizationPermission(w, r, *template.OrganizationID, permission)
+		return ok
+	}
+
+	user, ok := findContextUser(w, r)
+	if !ok {
+		return false
+	}
+	if user.ID != template.UserID {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// CreateOrganization handles POST /organizations
+// The caller becomes its first owner.
+func CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		http.Error(w, "Name is required", http.StatusBadRequest)
+		return
+	}
+
+	user, ok := findContextUser(w, r)
+	if !ok {
+		return
+	}
+
+	organization := models.Organization{
+		Name:    req.Name,
+		Members: []models.OrganizationMember{{UserID: user.ID, Role: models.OrganizationRoleOwner}},
+	}
+	if err := database.DB.Create(&organization).Error; err != nil {
+		http.Error(w, "Could not create organization", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(organization)
+}
+
+// GetOrganizations handles GET /organizations
+// Lists the caller's memberships with their organizations.
+func GetOrganizations(w http.ResponseWriter, r *http.Request) {
+	user, ok := findContextUser(w, r)
+	if !ok {
+		return
+	}
+
+	var memberships []models.OrganizationMember
+	if err := database.DB.Preload("Organization").Where("user_id = ?", user.ID).Order("id").Find(&memberships).Error; err != nil {
+		http.Error(w, "Error fetching organizations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(memberships)
+}
+
+// GetOrganizationMembers handles GET /organizations/{organizationId}/members
+func GetOrganizationMembers(w http.ResponseWriter, r *http.Request) {
+	organizationId := mux.Vars(r)["organizationId"]
+	if _, _, ok := requireOrganizationPermission(w, r, organizationId, models.PermissionViewTemplates); !ok {
+		return
+	}
+
+	var members []models.OrganizationMember
+	if err := database.DB.Preload("User").Where("organization_id = ?", organizationId).Order("id").Find(&members).Error; err != nil {
+		http.Error(w, "Error fetching members", http.StatusInternalServerError)
+		return
+	}
+	for i := range members {
+		members[i].User.Email = nil
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(members)
+}
+
+// countOwners returns how many owners the organization has
+func countOwners(organizationID uint) (int64, error) {
+	var owners int64
+	err := database.DB.Model(&models.OrganizationMember{}).
+		Where("organization_id = ? AND role = ?", organizationID, models.OrganizationRoleOwner).
+		Count(&owners).Error
+	return owners, err
+}
+
+// findOrganizationMember loads a member of the organization, writing the error response on failure
+func findOrganizationMember(w http.ResponseWriter, organizationID uint, memberId string) (models.OrganizationMember, bool) {
+	var member models.OrganizationMember
+	if err := database.DB.Where("organization_id = ?", organizationID).First(&member, "id = ?", memberId).Error; err != nil {
+		http.Error(w, "Member not found", http.StatusNotFound)
+		return member, false
+	}
+	return member, true
+}
+
+// UpdateOrganizationMember handles PUT /organizations/{organizationId}/members/{memberId}
+// Only owners grant or take away the owner role, and the last owner stays.
+func UpdateOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var req struct {
+		Role models.OrganizationRole `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !req.Role.Valid() {
+		http.Error(w, "Unknown role: "+string(req.Role), http.StatusBadRequest)
+		return
+	}
+
+	_, caller, ok := requireOrganizationPermission(w, r, vars["organizationId"], models.PermissionManageMembers)
+	if !ok {
+		return
+	}
+
+	member, ok := findOrganizationMember(w, caller.OrganizationID, vars["memberId"])
+	if !ok {
+		return
+	}
+
+	involvesOwner := member.Role == models.OrganizationRoleOwner || req.Role == models.OrganizationRoleOwner
+	if involvesOwner && !caller.Role.Can(models.PermissionManageOwners) {
+		http.Error(w, "Only owners can change owners", http.StatusForbidden)
+		return
+	}
+
+	if member.Role == models.OrganizationRoleOwner && req.Role != models.OrganizationRoleOwner {
+		owners, err := countOwners(member.OrganizationID)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if owners <= 1 {
+			http.Error(w, "An organization needs at least one owner", http.StatusBadRequest)
+			return
+		}
+	}
+
+	member.Role = req.Role
+	if err := database.DB.Model(&member).Update("role", member.Role).Error; err != nil {
+		http.Error(w, "Could not update member", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(member)
+}
+
+// RemoveOrganizationMember handles DELETE /organizations/{organizationId}/members/{memberId}
+// Members may always remove themselves, except the last owner.
+func RemoveOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	_, caller, ok := requireOrganizationPermission(w, r, vars["organizationId"], models.PermissionViewTemplates)
+	if !ok {
+		return
+	}
+
+	member, ok := findOrganizationMember(w, caller.OrganizationID, vars["memberId"])
+	if !ok {
+		return
+	}
+
+	if member.ID != caller.ID {
+		if !caller.Role.Can(models.PermissionManageMembers) {
+			http.Error(w, "Your role does not allow this", http.StatusForbidden)
+			return
+		}
+		if member.Role == models.OrganizationRoleOwner && !caller.Role.Can(models.PermissionManageOwners) {
+			http.Error(w, "Only owners can remove owners", http.StatusForbidden)
+			return
+		}
+	}
+
+	if member.Role == models.OrganizationRoleOwner {
+		owners, err := countOwners(member.OrganizationID)
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		if owners <= 1 {
+			http.Error(w, "An organization needs at least one owner", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := database.DB.Delete(&member).Error; err != nil {
+		http.Error(w, "Could not remove member", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "success",
+	})
+}
+
+// CreateOrganizationInvitation handles POST /organizations/{organizationId}/invitations
+func CreateOrganizationInvitation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Address string                  `json:"address"`
+		Role    models.OrganizationRole `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if !common.IsHexAddress(req.Address) {
+		http.Error(w, "Invalid address", http.StatusBadRequest)
+		return
+	}
+	if !req.Role.Valid() {
+		http.Error(w, "Unknown role: "+string(req.Role), http.StatusBadRequest)
+		return
+	}
+
+	user, caller, ok := requireOrganizationPermission(w, r, mux.Vars(r)["organizationId"], models.PermissionManageMembers)
+	if !ok {
+		return
+	}
+	if req.Role == models.OrganizationRoleOwner && !caller.Role.Can(models.PermissionManageOwners) {
+		http.Error(w, "Only owners can invite owners", http.StatusForbidden)
+		return
+	}
+
+	var existing int64
+	err := database.DB.Model(&models.OrganizationMember{}).
+		Joins("JOIN users ON users.id = organization_members.user_id").
+		Where("organization_members.organization_id = ? AND users.ethereum_address = ?", caller.OrganizationID, req.Address).
+		Count(&existing).Error
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if existing > 0 {
+		http.Error(w, "Address is already a member", http.StatusConflict)
+		return
+	}
+
+	invitation := models.OrganizationInvitation{
+		OrganizationID:  caller.OrganizationID,
+		EthereumAddress: req.Address,
+		Role:            req.Role,
+		InvitedByID:     user.ID,
+	}
+	if err := database.DB.Create(&invitation).Error; err != nil {
+		http.Error(w, "Could not save invitation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitation)
+}
+
+// GetOrganizationInvitations handles GET /organizations/{organizationId}/invitations
+// Lists the invitations that were neither accepted nor revoked.
+func GetOrganizationInvitations(w http.ResponseWriter, r *http.Request) {
+	_, caller, ok := requireOrganizationPermission(w, r, mux.Vars(r)["organizationId"], models.PermissionManageMembers)
+	if !ok {
+		return
+	}
+
+	var invitations []models.OrganizationInvitation
+	err := database.DB.
+		Where("organization_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", caller.OrganizationID).
+		Order("id").
+		Find(&invitations).Error
+	if err != nil {
+		http.Error(w, "Error fetching invitations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// RevokeOrganizationInvitation handles DELETE /organizations/{organizationId}/invitations/{invitationId}
+func RevokeOrganizationInvitation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	_, caller, ok := requireOrganizationPermission(w, r, vars["organizationId"], models.PermissionManageMembers)
+	if !ok {
+		return
+	}
+
+	result := database.DB.Model(&models.OrganizationInvitation{}).
+		Where("id = ? AND organization_id = ? AND accepted_at IS NULL AND revoked_at IS NULL", vars["invitationId"], caller.OrganizationID).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected == 0 {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "success",
+	})
+}
+
+// GetUserInvitations handles GET /invitations
+// Lists the open invitations addressed to the caller.
+func GetUserInvitations(w http.ResponseWriter, r *http.Request) {
+	user, ok := findContextUser(w, r)
+	if !ok {
+		return
+	}
+
+	var invitations []models.OrganizationInvitation
+	err := database.DB.Preload("Organization").
+		Where("ethereum_address = ? AND accepted_at IS NULL AND revoked_at IS NULL", user.EthereumAddress).
+		Order("id").
+		Find(&invitations).Error
+	if err != nil {
+		http.Error(w, "Error fetching invitations", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(invitations)
+}
+
+// AcceptInvitation handles POST /invitations/{invitationId}/accept
+// Only the invited address can accept, which makes it a member with the offered role.
+func AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	user, ok := findContextUser(w, r)
+	if !ok {
+		return
+	}
+
+	var invitation models.OrganizationInvitation
+	err := database.DB.
+		Where("accepted_at IS NULL AND revoked_at IS NULL").
+		First(&invitation, "id = ?", mux.Vars(r)["invitationId"]).Error
+	if err != nil || !strings.EqualFold(invitation.EthereumAddress, user.EthereumAddress) {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+
+	member := models.OrganizationMember{
+		OrganizationID: invitation.OrganizationID,
+		UserID:         user.ID,
+		Role:           invitation.Role,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&invitation).Update("accepted_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&member).Error
+	})
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		http.Error(w, "Already a member", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Could not accept invitation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(member)
+}
+
+// GetOrganizationTemplates handles GET /organizations/{organizationId}/templates
+// Takes the same parameters as GET /templates/{userAddress}.
+func GetOrganizationTemplates(w http.ResponseWriter, r *http.Request) {
+	_, caller, ok := requireOrganizationPermission(w, r, mux.Vars(r)["organizationId"], models.PermissionViewTemplates)
+	if !ok {
+		return
+	}
+
+	listTemplates(w, r, database.DB.Where("payment_templates.organization_id = ?", caller.OrganizationID))
+}
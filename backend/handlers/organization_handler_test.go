@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/jwtLogic"
+	"backend/models"
+	"backend/testdb"
+)
+
+func TestAuthorizeOrganizationTemplate(t *testing.T) {
+	const member = "0x1234567890AbcdEF1234567890aBcdef12345678"
+	var role models.OrganizationRole
+	testdb.Use(t, func(query string, args []driver.Value) (testdb.Rows, error) {
+		switch {
+		case strings.Contains(query, "FROM `users`"):
+			return testdb.Rows{Columns: []string{"id", "ethereum_address"}, Values: [][]driver.Value{{int64(8), member}}}, nil
+		case strings.Contains(query, "FROM `organization_members`"):
+			rows := testdb.Rows{Columns: []string{"id", "organization_id", "user_id", "role"}}
+			if role != "" {
+				rows.Values = append(rows.Values, []driver.Value{int64(1), int64(2), int64(8), string(role)})
+			}
+			return rows, nil
+		}
+		return testdb.Rows{}, errors.New("unexpected query: " + query)
+	})
+
+	organizationID := uint(2)
+	template := models.PaymentTemplate{ID: 5, UserID: 7, OrganizationID: &organizationID}
+
+	tests := []struct {
+		role       models.OrganizationRole
+		permission models.OrganizationPermission
+		want       int
+	}{
+		{"", models.PermissionViewTemplates, http.StatusNotFound},
+		{models.OrganizationRoleViewer, models.PermissionViewTemplates, http.StatusOK},
+		{models.OrganizationRoleViewer, models.PermissionPrepareTemplates, http.StatusForbidden},
+		{models.OrganizationRolePreparer, models.PermissionPrepareTemplates, http.StatusOK},
+		{models.OrganizationRolePreparer, models.PermissionApproveTemplates, http.StatusForbidden},
+		{models.OrganizationRoleApprover, models.PermissionApproveTemplates, http.StatusOK},
+		{models.OrganizationRoleApprover, models.PermissionPrepareTemplates, http.StatusForbidden},
+		{models.OrganizationRoleAdmin, models.PermissionManageOwners, http.StatusForbidden},
+		{models.OrganizationRoleOwner, models.PermissionManageOwners, http.StatusOK},
+	}
+	for _, tt := range tests {
+		role = tt.role
+		r := httptest.NewRequest(http.MethodGet, "/templates/5", nil)
+		r = r.WithContext(context.WithValue(r.Context(), jwtLogic.UserContextKey, member))
+		w := httptest.NewRecorder()
+
+		if ok := authorizeTemplate(w, r, template, tt.permission); ok != (tt.want == http.StatusOK) || w.Code != tt.want {
+			t.Errorf("role %q, %s: authorized %v with status %d, want %d", tt.role, tt.permission, ok, w.Code, tt.want)
+		}
+	}
+}
+
+func TestAuthorizePersonalTemplate(t *testing.T) {
+	testdb.Use(t, func(query string, _ []driver.Value) (testdb.Rows, error) {
+		return testdb.Rows{Columns: []string{"id", "ethereum_address"}, Values: [][]driver.Value{{int64(8), "0x1234567890AbcdEF1234567890aBcdef12345678"}}}, nil
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/templates/5", nil)
+	r = r.WithContext(context.WithValue(r.Context(), jwtLogic.UserContextKey, "0x1234567890AbcdEF1234567890aBcdef12345678"))
+	w := httptest.NewRecorder()
+
+	if authorizeTemplate(w, r, models.PaymentTemplate{ID: 5, UserID: 7}, models.PermissionViewTemplates) || w.Code != http.StatusUnauthorized {
+		t.Errorf("another user's personal template: status %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/database"
+	"backend/jwtLogic"
+	"backend/models"
+	"backend/scheduler"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+type PermitInput struct {
+	AssetID   uint   `json:"assetId"`   // Asset DB ID
+	Value     string `json:"value"`     // Allowance in the asset's smallest unit
+	Nonce     uint64 `json:"nonce"`     // Token permit nonce of the user
+	Deadline  int64  `json:"deadline"`  // Permit deadline in seconds, as signed
+	Signature string `json:"signature"` // EIP-2612 signature of the user
+}
+
+// permitsFromRequest verifies the permits and binds them to the user's smart
+// account, which is the spender that pulls the funds
+func permitsFromRequest(inputs []PermitInput, user models.User, transfers []models.Transfer) ([]models.TokenPermit, error) {
+	var permits []models.TokenPermit
+	for _, in := range inputs {
+		used := false
+		for _, t := range transfers {
+			used = used || t.AssetID == in.AssetID
+		}
+		if !used {
+			return nil, errors.New("Permit asset is not used by the template")
+		}
+
+		var asset models.Asset
+		if err := database.DB.First(&asset, in.AssetID).Error; err != nil {
+			return nil, errors.New("Asset not found")
+		}
+
+		var account models.SmartAccount
+		if err := database.DB.Where("user_id = ? AND chain_id = ?", user.ID, asset.ChainID).First(&account).Error; err != nil {
+			return nil, errors.New("Smart account required for permits")
+		}
+
+		permit := models.TokenPermit{
+			AssetID:   asset.ID,
+			Owner:     user.EthereumAddress,
+			Spender:   account.Address,
+			Value:     in.Value,
+			Nonce:     in.Nonce,
+			Deadline:  time.Unix(in.Deadline, 0),
+			Signature: in.Signature,
+		}
+		if err := scheduler.VerifyPermit(&permit, asset); err != nil {
+			return nil, err
+		}
+
+		permits = append(permits, permit)
+	}
+	return permits, nil
+}
+
+// AddTemplatePermit handles POST /templates/{templateId}/permits
+// It lets users renew the permit of a recurring template.
+func AddTemplatePermit(w http.ResponseWriter, r *http.Request) {
+	templateId := mux.Vars(r)["templateId"]
+
+	var template models.PaymentTemplate
+	if err := database.DB.Preload("User").Preload("Transfers").First(&template, "id = ?", templateId).Error; err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !authorizeTemplate(w, r, template, models.PermissionPrepareTemplates) {
+		return
+	}
+
+	var input PermitInput
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	permits, err := permitsFromRequest([]PermitInput{input}, template.User, template.Transfers)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	permit := permits[0]
+	permit.PaymentTemplateID = template.ID
+	if err := database.DB.Create(&permit).Error; err != nil {
+		http.Error(w, "Could not save permit", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(permit)
+}
+
+// GetUserPermits handles GET /permits/{userAddress}
+// Permits listed under "expiring" belong to active recurring templates and
+// will pass their deadline unused within the warning window.
+func GetUserPermits(w http.ResponseWriter, r *http.Request) {
+	userAddressFromCookie := r.Context().Value(jwtLogic.UserContextKey).(string)
+	userAddress := mux.Vars(r)["userAddress"]
+
+	if !strings.EqualFold(userAddressFromCookie, userAddress) {
+		http.Error(w, "wrong cookie", http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("ethereum_address = ?", userAddress).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var permits []models.TokenPermit
+	err := database.DB.
+		Preload("Asset").
+		Joins("JOIN payment_templates ON payment_templates.id = token_permits.payment_template_id").
+		Where("payment_templates.user_id = ?", user.ID).
+		Order("token_permits.id").
+		Find(&permits).Error
+	if err != nil {
+		http.Error(w, "Error fetching permits", http.StatusInternalServerError)
+		return
+	}
+
+	var expiring []models.TokenPermit
+	err = scheduler.ExpiringPermitsQuery().
+		Preload("Asset").
+		Where("payment_templates.user_id = ?", user.ID).
+		Order("token_permits.deadline").
+		Find(&expiring).Error
+	if err != nil {
+		http.Error(w, "Error fetching permits", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string][]models.TokenPermit{
+		"permits":  permits,
+		"expiring": expiring,
+	})
+}
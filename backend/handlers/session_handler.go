@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"backend/audit"
+	"backend/database"
+	"backend/jwtLogic"
+	"backend/models"
+
+	"github.com/gorilla/mux"
+)
+
+// GetSessions handles GET /sessions
+// Lists the caller's active sessions and marks the one making the request.
+func GetSessions(w http.ResponseWriter, r *http.Request) {
+	userAddress := r.Context().Value(jwtLogic.UserContextKey).(string)
+	currentID := r.Context().Value(jwtLogic.SessionContextKey).(uint)
+
+	var user models.User
+	if err := database.DB.Where("ethereum_address = ?", userAddress).First(&user).Error; err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	var sessions []models.Session
+	err := database.DB.
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		http.Error(w, "Error fetching sessions", http.StatusInternalServerError)
+		return
+	}
+
+	type sessionResponse struct {
+		models.Session
+		Current bool `json:"current"`
+	}
+	response := make([]sessionResponse, len(sessions))
+	for i, s := range sessions {
+		response[i] = sessionResponse{Session: s, Current: s.ID == currentID}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteSession handles DELETE /sessions/{sessionId}
+// Revokes one of the caller's sessions, e.g. a lost device.
+func DeleteSession(w http.ResponseWriter, r *http.Request) {
+	userAddress := r.Context().Value(jwtLogic.UserContextKey).(string)
+	sessionId := mux.Vars(r)["sessionId"]
+
+	var session models.Session
+	if err := database.DB.Preload("User").First(&session, "id = ?", sessionId).Error; err != nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	if session.User.EthereumAddress != userAddress {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+
+	if session.RevokedAt == nil {
+		if err := jwtLogic.RevokeSession(session.ID, jwtLogic.RevokedReasonUser); err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return
+		}
+		audit.Record(r, audit.Entry{
+			UserID:     session.UserID,
+			Actor:      userAddress,
+			Action:     audit.ActionSessionRevoke,
+			TargetType: audit.TargetSession,
+			TargetID:   session.ID,
+			After:      map[string]interface{}{"reason": jwtLogic.RevokedReasonUser},
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "success",
+	})
+}
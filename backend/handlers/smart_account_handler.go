@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"backend/database"
+	"backend/jwtLogic"
+	"backend/models"
+	"backend/scheduler"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// verifyAccountOwner checks the caller controls the account being registered
+var verifyAccountOwner = scheduler.VerifyAccountOwner
+
+// GetUserSmartAccounts handles GET /accounts/{userAddress}
+func GetUserSmartAccounts(w http.ResponseWriter, r *http.Request) {
+	userAddressFromCookie := r.Context().Value(jwtLogic.UserContextKey).(string)
+	userAddress := mux.Vars(r)["userAddress"]
+
+	if !strings.EqualFold(userAddressFromCookie, userAddress) {
+		http.Error(w, "wrong cookie", http.StatusUnauthorized)
+		return
+	}
+
+	var user models.User
+	result := database.DB.
+		Preload("SmartAccounts").
+		Where("ethereum_address = ?", userAddress).
+		First(&user)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(user.SmartAccounts)
+}
+
+// RegisterSmartAccount handles POST /accounts/{userAddress}
+// It checks on-chain that the user holds privileges on the account, then
+// creates or replaces the user's account for the chain and checks whether
+// the executor has been authorised on it.
+func RegisterSmartAccount(w http.ResponseWriter, r *http.Request) {
+	userAddressFromCookie := r.Context().Value(jwtLogic.UserContextKey).(string)
+	userAddress := mux.Vars(r)["userAddress"]
+
+	var req struct {
+		Address     string                  `json:"address"`
+		ChainID     uint64                  `json:"chainId"`
+		AccountType models.SmartAccountType `json:"accountType"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !strings.EqualFold(userAddressFromCookie, userAddress) {
+		http.Error(w, "wrong cookie", http.StatusUnauthorized)
+		return
+	}
+
+	if !common.IsHexAddress(req.Address) {
+		http.Error(w, "Invalid account address", http.StatusBadRequest)
+		return
+	}
+
+	switch req.AccountType {
+	case models.SmartAccountTypeAmbire:
+	case models.SmartAccountTypeAmbire7702:
+		// A delegated EOA is its own account
+		if !strings.EqualFold(req.Address, userAddress) {
+			http.Error(w, "EIP-7702 account must be the user address", http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "Unsupported account type", http.StatusBadRequest)
+		return
+	}
+
+	// Registering an account lets the scheduler spend from it, so only its owner may
+	if err := verifyAccountOwner(req.ChainID, req.AccountType, common.HexToAddress(req.Address), common.HexToAddress(userAddress)); err != nil {
+		if errors.Is(err, scheduler.ErrNotAccountOwner) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+		http.Error(w, "Could not verify account: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Where("ethereum_address = ?", userAddress).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var account models.SmartAccount
+	err := database.DB.Where("user_id = ? AND chain_id = ?", user.ID, req.ChainID).First(&account).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	account.UserID = user.ID
+	account.ChainID = req.ChainID
+	account.Address = common.HexToAddress(req.Address).Hex()
+	account.AccountType = req.AccountType
+	account.ExecutorAuthorized = false
+
+	if err := scheduler.VerifySmartAccount(&account); err != nil {
+		http.Error(w, "Could not verify account: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if err := database.DB.Save(&account).Error; err != nil {
+		http.Error(w, "Could not save account", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+// VerifySmartAccount handles POST /accounts/{accountId}/verify
+// Users call it after granting the executor privileges on-chain.
+func VerifySmartAccount(w http.ResponseWriter, r *http.Request) {
+	userAddress := r.Context().Value(jwtLogic.UserContextKey).(string)
+	accountId := mux.Vars(r)["accountId"]
+
+	var account models.SmartAccount
+	if err := database.DB.Preload("User").First(&account, "id = ?", accountId).Error; err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !strings.EqualFold(account.User.EthereumAddress, userAddress) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if err := scheduler.VerifySmartAccount(&account); err != nil {
+		http.Error(w, "Could not verify account: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if err := database.DB.Omit("User").Save(&account).Error; err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(account)
+}
+
+// DeleteSmartAccount handles DELETE /accounts/{accountId}
+func DeleteSmartAccount(w http.ResponseWriter, r *http.Request) {
+	userAddress := r.Context().Value(jwtLogic.UserContextKey).(string)
+	accountId := mux.Vars(r)["accountId"]
+
+	var account models.SmartAccount
+	if err := database.DB.Preload("User").First(&account, "id = ?", accountId).Error; err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if !strings.EqualFold(account.User.EthereumAddress, userAddress) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if err := database.DB.Delete(&account).Error; err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "success",
+	})
+}
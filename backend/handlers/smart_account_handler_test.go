@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/jwtLogic"
+	"backend/models"
+	"backend/scheduler"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gorilla/mux"
+)
+
+func TestRegisterSmartAccountRequiresOwnership(t *testing.T) {
+	useTestDB(t)
+
+	const (
+		user   = "0x6969174FD72466430a46e18234D0b530c9FD5f49"
+		victim = "0x1234567890AbcdEF1234567890aBcdef12345678"
+	)
+	var checked []common.Address
+	previous := verifyAccountOwner
+	verifyAccountOwner = func(chainID uint64, accountType models.SmartAccountType, account, owner common.Address) error {
+		checked = append(checked, account, owner)
+		return scheduler.ErrNotAccountOwner
+	}
+	t.Cleanup(func() { verifyAccountOwner = previous })
+
+	body := `{"address":"` + victim + `","chainId":8453,"accountType":"ambire"}`
+	r := httptest.NewRequest(http.MethodPost, "/accounts/"+user, strings.NewReader(body))
+	r = mux.SetURLVars(r, map[string]string{"userAddress": user})
+	r = r.WithContext(context.WithValue(r.Context(), jwtLogic.UserContextKey, user))
+	w := httptest.NewRecorder()
+
+	RegisterSmartAccount(w, r)
+
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status = %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), http.StatusForbidden)
+	}
+	if len(checked) != 2 || checked[0] != common.HexToAddress(victim) || checked[1] != common.HexToAddress(user) {
+		t.Errorf("ownership checked for %v, want the account and the user", checked)
+	}
+}
+
+func TestRegisterSmartAccountRejectsAnotherEOA(t *testing.T) {
+	const user = "0x6969174FD72466430a46e18234D0b530c9FD5f49"
+
+	body := `{"address":"0x1234567890AbcdEF1234567890aBcdef12345678","chainId":8453,"accountType":"ambire_7702"}`
+	r := httptest.NewRequest(http.MethodPost, "/accounts/"+user, strings.NewReader(body))
+	r = mux.SetURLVars(r, map[string]string{"userAddress": user})
+	r = r.WithContext(context.WithValue(r.Context(), jwtLogic.UserContextKey, user))
+	w := httptest.NewRecorder()
+
+	RegisterSmartAccount(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+}
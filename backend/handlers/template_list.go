@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultTemplateLimit = 50
+	maxTemplateLimit     = 200
+)
+
+// templateSort is a sort key of template listings; Value reads the key from a
+// template so the cursor row can be compared against
+type templateSort struct {
+	Column string
+	Value  func(t models.PaymentTemplate) interface{}
+}
+
+var templateSorts = map[string]templateSort{
+	"created_at": {
+		Column: "payment_templates.created_at",
+		Value:  func(t models.PaymentTemplate) interface{} { return t.CreatedAt },
+	},
+	// Templates without a schedule run when they are created
+	"scheduled_at": {
+		Column: "COALESCE(payment_templates.scheduled_at, payment_templates.created_at)",
+		Value: func(t models.PaymentTemplate) interface{} {
+			if t.ScheduledAt != nil {
+				return *t.ScheduledAt
+			}
+			return t.CreatedAt
+		},
+	},
+	"name": {
+		Column: "payment_templates.name",
+		Value:  func(t models.PaymentTemplate) interface{} { return t.Name },
+	},
+}
+
+// TemplateSummary is the list view of a template, without nested relations
+type TemplateSummary struct {
+	ID                uint                  `json:"id"`
+	CreatedAt         time.Time             `json:"created_at"`
+	UserID            uint                  `json:"user_id"`
+	OrganizationID    *uint                 `json:"organization_id,omitempty"`
+	Name              string                `json:"name"`
+	Type              TypeOfBatch           `json:"type"`
+	IsCancelled       bool                  `json:"is_cancelled"`
+	ApprovalStatus    models.ApprovalStatus `json:"approval_status"`
+	ScheduledAt       *time.Time            `json:"scheduled_at,omitempty"`
+	RecurringInterval *int64                `json:"recurring_interval,omitempty"`
+	EndsAt            *time.Time            `json:"ends_at,omitempty"`
+	TransferCount     int                   `json:"transfer_count"`
+}
+
+// templateType tells which kind of request created the template
+func templateType(t models.PaymentTemplate) TypeOfBatch {
+	switch {
+	case t.Condition != nil:
+		return TypeConditional
+	case t.RecurringInterval != nil && *t.RecurringInterval > 0:
+		return TypeRecurring
+	case t.ScheduledAt != nil:
+		return TypeSchedule
+	default:
+		return TypeNow
+	}
+}
+
+const (
+	hasCondition       = "EXISTS (SELECT 1 FROM payment_conditions WHERE payment_conditions.payment_template_id = payment_templates.id)"
+	hasTransferWhere   = "EXISTS (SELECT 1 FROM transfers WHERE transfers.payment_template_id = payment_templates.id AND "
+	hasTransferOnChain = "EXISTS (SELECT 1 FROM transfers JOIN assets ON assets.id = transfers.asset_id WHERE transfers.payment_template_id = payment_templates.id AND assets.chain_id = ?)"
+)
+
+// parseTimeParam reads an optional RFC 3339 query parameter
+func parseTimeParam(w http.ResponseWriter, r *http.Request, name string) (*time.Time, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		http.Error(w, "Invalid "+name+", expected RFC 3339", http.StatusBadRequest)
+		return nil, false
+	}
+	return &t, true
+}
+
+// listTemplates serves a page of the templates matched by query
+//
+// Filters: type (NOW, SCHEDULE, RECURRING or CONDITIONAL), status (active,
+// cancelled or ended), approval_status, asset_id, chain_id, destination, q
+// (name search), created_since/created_until and scheduled_since/scheduled_until
+// (RFC 3339). sort is created_at, scheduled_at or name, prefixed with "-" for
+// descending order (default -created_at). view=summary leaves out transfers
+// and other relations. The next page is linked in the Link header.
+func listTemplates(w http.ResponseWriter, r *http.Request, query *gorm.DB) {
+	params := r.URL.Query()
+
+	switch TypeOfBatch(strings.ToUpper(params.Get("type"))) {
+	case "":
+	case TypeNow:
+		query = query.Where("payment_templates.scheduled_at IS NULL AND NOT " + hasCondition)
+	case TypeSchedule:
+		query = query.Where("payment_templates.scheduled_at IS NOT NULL AND (payment_templates.recurring_interval IS NULL OR payment_templates.recurring_interval = 0) AND NOT " + hasCondition)
+	case TypeRecurring:
+		query = query.Where("payment_templates.recurring_interval > 0 AND NOT " + hasCondition)
+	case TypeConditional:
+		query = query.Where(hasCondition)
+	default:
+		http.Error(w, "Invalid type", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	switch params.Get("status") {
+	case "":
+	case "active":
+		query = query.Where("payment_templates.is_cancelled = ? AND (payment_templates.ends_at IS NULL OR payment_templates.ends_at > ?)", false, now)
+	case "cancelled":
+		query = query.Where("payment_templates.is_cancelled = ?", true)
+	case "ended":
+		query = query.Where("payment_templates.is_cancelled = ? AND payment_templates.ends_at <= ?", false, now)
+	default:
+		http.Error(w, "Invalid status", http.StatusBadRequest)
+		return
+	}
+
+	if status := params.Get("approval_status"); status != "" {
+		query = query.Where("payment_templates.approval_status = ?", status)
+	}
+	if assetID := params.Get("asset_id"); assetID != "" {
+		id, err := strconv.ParseUint(assetID, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid asset_id", http.StatusBadRequest)
+			return
+		}
+		query = query.Where(hasTransferWhere+"transfers.asset_id = ?)", id)
+	}
+	if chainID := params.Get("chain_id"); chainID != "" {
+		id, err := strconv.ParseUint(chainID, 10, 64)
+		if err != nil {
+			http.Error(w, "Invalid chain_id", http.StatusBadRequest)
+			return
+		}
+		query = query.Where(hasTransferOnChain, id)
+	}
+	if destination := params.Get("destination"); destination != "" {
+		query = query.Where(hasTransferWhere+"LOWER(transfers.destination_user_address) = ?)", strings.ToLower(destination))
+	}
+	if q := strings.TrimSpace(params.Get("q")); q != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(q)
+		query = query.Where("payment_templates.name LIKE ?", "%"+escaped+"%")
+	}
+
+	for _, p := range []struct{ param, column, op string }{
+		{"created_since", "payment_templates.created_at", ">="},
+		{"created_until", "payment_templates.created_at", "<"},
+		{"scheduled_since", "payment_templates.scheduled_at", ">="},
+		{"scheduled_until", "payment_templates.scheduled_at", "<"},
+	} {
+		t, ok := parseTimeParam(w, r, p.param)
+		if !ok {
+			return
+		}
+		if t != nil {
+			query = query.Where(p.column+" "+p.op+" ?", *t)
+		}
+	}
+
+	sortParam := params.Get("sort")
+	if sortParam == "" {
+		sortParam = "-created_at"
+	}
+	descending := strings.HasPrefix(sortParam, "-")
+	sort, ok := templateSorts[strings.TrimPrefix(sortParam, "-")]
+	if !ok {
+		http.Error(w, "Invalid sort", http.StatusBadRequest)
+		return
+	}
+	direction, comparison := "ASC", ">"
+	if descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	limit := defaultTemplateLimit
+	if l := params.Get("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxTemplateLimit)
+	}
+
+	// The cursor is the last template of the previous page; rows continue
+	// after its sort key, with the ID breaking ties
+	if cursor := params.Get("cursor"); cursor != "" {
+		var last models.PaymentTemplate
+		if err := query.Session(&gorm.Session{}).Where("payment_templates.id = ?", cursor).First(&last).Error; err != nil {
+			http.Error(w, "Invalid cursor", http.StatusBadRequest)
+			return
+		}
+		value := sort.Value(last)
+		query = query.Where(
+			"("+sort.Column+" "+comparison+" ?) OR ("+sort.Column+" = ? AND payment_templates.id "+comparison+" ?)",
+			value, value, last.ID,
+		)
+	}
+
+	summary := params.Get("view") == "summary"
+	if summary {
+		query = query.Preload("Condition").Preload("Transfers", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "payment_template_id")
+		})
+	} else {
+		query = query.
+			Preload("Transfers.SourceUser").
+			Preload("Transfers.Asset").
+			Preload("User").
+			Preload("Authorization.MaxTotals.Asset").
+			Preload("Condition")
+	}
+
+	var templates []models.PaymentTemplate
+	err := query.
+		Order(sort.Column + " " + direction).
+		Order("payment_templates.id " + direction).
+		Limit(limit + 1).
+		Find(&templates).Error
+	if err != nil {
+		http.Error(w, "Error fetching templates", http.StatusInternalServerError)
+		return
+	}
+
+	if len(templates) > limit {
+		templates = templates[:limit]
+		next := *r.URL
+		nextParams := next.Query()
+		nextParams.Set("cursor", strconv.FormatUint(uint64(templates[limit-1].ID), 10))
+		next.RawQuery = nextParams.Encode()
+		w.Header().Set("Link", "<"+next.RequestURI()+`>; rel="next"`)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !summary {
+		for i := range templates {
+			templates[i].User.Email = nil
+			for j := range templates[i].Transfers {
+				templates[i].Transfers[j].SourceUser.Email = nil
+			}
+		}
+		json.NewEncoder(w).Encode(templates)
+		return
+	}
+
+	summaries := make([]TemplateSummary, len(templates))
+	for i, t := range templates {
+		summaries[i] = TemplateSummary{
+			ID:                t.ID,
+			CreatedAt:         t.CreatedAt,
+			UserID:            t.UserID,
+			OrganizationID:    t.OrganizationID,
+			Name:              t.Name,
+			Type:              templateType(t),
+			IsCancelled:       t.IsCancelled,
+			ApprovalStatus:    t.ApprovalStatus,
+			ScheduledAt:       t.ScheduledAt,
+			RecurringInterval: t.RecurringInterval,
+			EndsAt:            t.EndsAt,
+			TransferCount:     len(t.Transfers),
+		}
+	}
+	json.NewEncoder(w).Encode(summaries)
+}
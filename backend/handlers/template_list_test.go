@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/database"
+	"backend/testdb"
+)
+
+// templateQuery is a query of payment_templates the listing ran
+type templateQuery struct {
+	SQL  string
+	Args []driver.Value
+}
+
+// useTemplateRows serves count templates of user 7, newest first, and records
+// the queries of payment_templates
+func useTemplateRows(t *testing.T, count int) *[]templateQuery {
+	t.Helper()
+	var queries []templateQuery
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	testdb.Use(t, func(query string, args []driver.Value) (testdb.Rows, error) {
+		switch {
+		case strings.Contains(query, "FROM `payment_templates`"):
+			queries = append(queries, templateQuery{query, args})
+			rows := testdb.Rows{Columns: []string{"id", "user_id", "name", "created_at"}}
+			for i := 0; i < count; i++ {
+				id := int64(20 - i)
+				rows.Values = append(rows.Values, []driver.Value{id, int64(7), "Template", created.Add(time.Duration(id) * time.Hour)})
+			}
+			return rows, nil
+		case strings.Contains(query, "FROM `payment_conditions`"), strings.Contains(query, "FROM `transfers`"):
+			return testdb.Rows{Columns: []string{"id"}}, nil
+		}
+		return testdb.Rows{}, errors.New("unexpected query: " + query)
+	})
+	return &queries
+}
+
+func listRequest(query string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/templates/0x6969174FD72466430a46e18234D0b530c9FD5f49?"+query, nil)
+	w := httptest.NewRecorder()
+	listTemplates(w, r, database.DB.Where("payment_templates.user_id = ?", 7))
+	return w
+}
+
+func TestListTemplatesInvalidParams(t *testing.T) {
+	useTemplateRows(t, 0)
+	for _, query := range []string{
+		"type=weekly",
+		"status=paused",
+		"sort=amount",
+		"limit=0",
+		"limit=ten",
+		"asset_id=usdc",
+		"chain_id=-1",
+		"created_since=yesterday",
+	} {
+		if w := listRequest(query); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", query, w.Code, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestListTemplatesPages(t *testing.T) {
+	queries := useTemplateRows(t, 3)
+
+	w := listRequest("view=summary&limit=2&sort=-created_at")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s)", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+	var page []TemplateSummary
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page) != 2 || page[0].ID != 20 || page[1].ID != 19 || page[0].Type != TypeNow {
+		t.Errorf("page = %+v, want templates 20 and 19", page)
+	}
+	if link := w.Header().Get("Link"); !strings.Contains(link, "cursor=19") || !strings.HasSuffix(link, `rel="next"`) {
+		t.Errorf("Link = %q, want the next page after template 19", link)
+	}
+
+	// One more row than the page is fetched to tell whether there is a next one
+	last := (*queries)[len(*queries)-1]
+	if !strings.Contains(last.SQL, "ORDER BY payment_templates.created_at DESC,payment_templates.id DESC LIMIT ?") || last.Args[len(last.Args)-1] != int64(3) {
+		t.Errorf("query %s %v, want newest first with limit 3", last.SQL, last.Args)
+	}
+
+	*queries = nil
+	if w := listRequest("view=summary&limit=2&sort=name&cursor=19"); w.Code != http.StatusOK {
+		t.Fatalf("next page status = %d (%s)", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+	if len(*queries) != 2 {
+		t.Fatalf("ran %d template queries, want the cursor lookup and the page", len(*queries))
+	}
+	next := (*queries)[1]
+	if !strings.Contains(next.SQL, "(payment_templates.name > ?) OR (payment_templates.name = ? AND payment_templates.id > ?)") {
+		t.Errorf("next page query %s, want rows after the cursor's name and id", next.SQL)
+	}
+}
+
+func TestListTemplatesEscapesSearch(t *testing.T) {
+	queries := useTemplateRows(t, 0)
+
+	if w := listRequest("view=summary&q=50%25_off"); w.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s)", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+	found := false
+	for _, arg := range (*queries)[0].Args {
+		found = found || arg == `%50\%\_off%`
+	}
+	if !found {
+		t.Errorf("search args %v, want the wildcards escaped", (*queries)[0].Args)
+	}
+}
@@ -0,0 +1,529 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"backend/approvals"
+	"backend/audit"
+	"backend/authorization"
+	"backend/database"
+	"backend/events"
+	"backend/models"
+	"backend/scheduler"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// TransferSnapshot is a transfer as recorded in a template version
+type TransferSnapshot struct {
+	TransferID   uint     `json:"transfer_id"`
+	Destination  string   `json:"destination"`
+	AssetID      uint     `json:"asset_id"`
+	AssetSymbol  string   `json:"asset_symbol"`
+	Amount       float64  `json:"amount"`
+	FiatAmount   *float64 `json:"fiat_amount,omitempty"`
+	FiatCurrency *string  `json:"fiat_currency,omitempty"`
+}
+
+// TemplateSnapshot is the content of a template version: what it pays, when,
+// and the authorization that was signed for it
+type TemplateSnapshot struct {
+	ScheduledAt         *time.Time         `json:"scheduled_at,omitempty"`
+	RecurringInterval   *int64             `json:"recurring_interval,omitempty"`
+	EndsAt              *time.Time         `json:"ends_at,omitempty"`
+	Transfers           []TransferSnapshot `json:"transfers"`
+	AuthorizationDigest string             `json:"authorization_digest,omitempty"`
+}
+
+// VersionChange is one difference between two template versions; From is
+// null for added transfers and To for removed ones
+type VersionChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// snapshotTemplate records the versioned content of template; transfers need their Asset loaded
+func snapshotTemplate(template models.PaymentTemplate) TemplateSnapshot {
+	snapshot := TemplateSnapshot{
+		ScheduledAt:       template.ScheduledAt,
+		RecurringInterval: template.RecurringInterval,
+		EndsAt:            template.EndsAt,
+		Transfers:         make([]TransferSnapshot, len(template.Transfers)),
+	}
+	for i, t := range template.Transfers {
+		snapshot.Transfers[i] = TransferSnapshot{
+			TransferID:   t.ID,
+			Destination:  t.DestinationUserAddress,
+			AssetID:      t.AssetID,
+			AssetSymbol:  t.Asset.Symbol,
+			Amount:       t.Amount,
+			FiatAmount:   t.FiatAmount,
+			FiatCurrency: t.FiatCurrency,
+		}
+	}
+	if template.Authorization != nil {
+		snapshot.AuthorizationDigest = template.Authorization.Digest
+	}
+	return snapshot
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+func sameInt64(a, b *int64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameFloat64(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func sameString(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// diffSnapshots lists what changed from prev to next; transfers are compared by position
+func diffSnapshots(prev, next TemplateSnapshot) []VersionChange {
+	var changes []VersionChange
+	if !sameTime(prev.ScheduledAt, next.ScheduledAt) {
+		changes = append(changes, VersionChange{"scheduled_at", prev.ScheduledAt, next.ScheduledAt})
+	}
+	if !sameInt64(prev.RecurringInterval, next.RecurringInterval) {
+		changes = append(changes, VersionChange{"recurring_interval", prev.RecurringInterval, next.RecurringInterval})
+	}
+	if !sameTime(prev.EndsAt, next.EndsAt) {
+		changes = append(changes, VersionChange{"ends_at", prev.EndsAt, next.EndsAt})
+	}
+
+	for i := 0; i < max(len(prev.Transfers), len(next.Transfers)); i++ {
+		field := fmt.Sprintf("transfers[%d]", i)
+		if i >= len(prev.Transfers) {
+			changes = append(changes, VersionChange{field, nil, next.Transfers[i]})
+			continue
+		}
+		if i >= len(next.Transfers) {
+			changes = append(changes, VersionChange{field, prev.Transfers[i], nil})
+			continue
+		}
+
+		a, b := prev.Transfers[i], next.Transfers[i]
+		if a.Destination != b.Destination {
+			changes = append(changes, VersionChange{field + ".destination", a.Destination, b.Destination})
+		}
+		if a.AssetID != b.AssetID {
+			changes = append(changes, VersionChange{field + ".asset", a.AssetSymbol, b.AssetSymbol})
+		}
+		if a.Amount != b.Amount {
+			changes = append(changes, VersionChange{field + ".amount", a.Amount, b.Amount})
+		}
+		if !sameFloat64(a.FiatAmount, b.FiatAmount) {
+			changes = append(changes, VersionChange{field + ".fiat_amount", a.FiatAmount, b.FiatAmount})
+		}
+		if !sameString(a.FiatCurrency, b.FiatCurrency) {
+			changes = append(changes, VersionChange{field + ".fiat_currency", a.FiatCurrency, b.FiatCurrency})
+		}
+	}
+
+	if prev.AuthorizationDigest != next.AuthorizationDigest {
+		changes = append(changes, VersionChange{"authorization_digest", prev.AuthorizationDigest, next.AuthorizationDigest})
+	}
+	return changes
+}
+
+// recordTemplateVersion stores the template's current content as its Version
+func recordTemplateVersion(tx *gorm.DB, template models.PaymentTemplate, createdByID uint, changes []VersionChange) error {
+	content, err := json.Marshal(snapshotTemplate(template))
+	if err != nil {
+		return err
+	}
+	version := models.TemplateVersion{
+		PaymentTemplateID: template.ID,
+		Version:           template.Version,
+		CreatedByID:       createdByID,
+		Content:           content,
+	}
+	if changes != nil {
+		if version.Changes, err = json.Marshal(changes); err != nil {
+			return err
+		}
+	}
+	return tx.Create(&version).Error
+}
+
+// ensureFirstVersion records the content of templates created before
+// versioning as their current version
+func ensureFirstVersion(tx *gorm.DB, template models.PaymentTemplate) error {
+	var count int64
+	if err := tx.Model(&models.TemplateVersion{}).Where("payment_template_id = ?", template.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	return recordTemplateVersion(tx, template, template.UserID, nil)
+}
+
+// loadVersionedTemplate loads a template with everything its versions record
+func loadVersionedTemplate(templateID interface{}) (models.PaymentTemplate, error) {
+	var template models.PaymentTemplate
+	err := database.DB.
+		Preload("User").
+		Preload("Transfers", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Transfers.Asset").
+		Preload("Authorization.MaxTotals.Asset").
+		Preload("Condition.Asset").
+		First(&template, "id = ?", templateID).Error
+	return template, err
+}
+
+// nextRun is the first run of a schedule that is not in the past
+func nextRun(scheduledAt time.Time, interval *int64, now time.Time) time.Time {
+	if scheduledAt.After(now) || interval == nil || *interval <= 0 {
+		return scheduledAt
+	}
+	step := time.Duration(*interval) * time.Second
+	periods := now.Sub(scheduledAt)/step + 1
+	return scheduledAt.Add(periods * step)
+}
+
+// editedTemplate applies the edit in body to current, writing the error
+// response if the template cannot be edited that way. It returns the edited
+// template, the request its authorization is checked against and whether the
+// schedule changed.
+func editedTemplate(w http.ResponseWriter, current models.PaymentTemplate, body UpdateTemplateRequest, now time.Time) (edited models.PaymentTemplate, req CreateTemplateRequest, scheduleChanged bool, ok bool) {
+	var err error
+	kind := templateType(current)
+	switch {
+	case kind == TypeNow:
+		http.Error(w, "Only scheduled, recurring and conditional templates can be edited", http.StatusBadRequest)
+		return edited, req, false, false
+	case current.IsCancelled:
+		http.Error(w, "Cancelled templates cannot be edited", http.StatusConflict)
+		return edited, req, false, false
+	case current.EndsAt != nil && now.After(*current.EndsAt):
+		http.Error(w, "Ended templates cannot be edited", http.StatusConflict)
+		return edited, req, false, false
+	}
+
+	// One-off templates are fixed once they ran
+	if kind != TypeRecurring {
+		var executed int64
+		err := database.DB.Model(&models.Execution{}).
+			Where("payment_template_id = ? AND status IN ?", current.ID,
+				[]models.ExecutionStatus{models.ExecutionStatusSubmitted, models.ExecutionStatusConfirmed, models.ExecutionStatusUnknown}).
+			Count(&executed).Error
+		if err != nil {
+			http.Error(w, "Database error", http.StatusInternalServerError)
+			return edited, req, false, false
+		}
+		if executed > 0 {
+			http.Error(w, "Template was already executed", http.StatusConflict)
+			return edited, req, false, false
+		}
+	}
+
+	var chainID uint64
+	if current.Authorization != nil {
+		chainID = current.Authorization.ChainID
+	} else if len(current.Transfers) > 0 {
+		chainID = current.Transfers[0].Asset.ChainID
+	}
+
+	edited = current
+	req = CreateTemplateRequest{
+		ChainID:       chainID,
+		Type:          kind,
+		Transfers:     body.Transfers,
+		Authorization: body.Authorization,
+	}
+	if body.Transfers != nil {
+		if len(body.Transfers) == 0 {
+			http.Error(w, "At least one transfer is required", http.StatusBadRequest)
+			return edited, req, false, false
+		}
+		// Transfers stay funded by the template's owner, whoever edits them
+		edited.Transfers, err = transfersFromRequest(req, current.User)
+		if err != nil {
+			writeTemplateError(w, err)
+			return edited, req, false, false
+		}
+	}
+
+	if body.ScheduledAt != nil {
+		if kind == TypeConditional {
+			http.Error(w, "Conditional templates run when their condition holds", http.StatusBadRequest)
+			return edited, req, false, false
+		}
+		t := time.Unix(*body.ScheduledAt/1000, 0)
+		if !t.After(now) {
+			http.Error(w, "Scheduled time must be in the future", http.StatusBadRequest)
+			return edited, req, false, false
+		}
+		scheduleChanged = scheduleChanged || !sameTime(current.ScheduledAt, &t)
+		edited.ScheduledAt = &t
+	}
+	if body.RecurringInterval != nil {
+		if kind != TypeRecurring {
+			http.Error(w, "Only recurring templates have an interval", http.StatusBadRequest)
+			return edited, req, false, false
+		}
+		interval := *body.RecurringInterval / 1000
+		if interval <= 0 {
+			http.Error(w, "Interval must be positive", http.StatusBadRequest)
+			return edited, req, false, false
+		}
+		scheduleChanged = scheduleChanged || !sameInt64(current.RecurringInterval, &interval)
+		edited.RecurringInterval = &interval
+	}
+	if body.EndsAt != nil {
+		if *body.EndsAt == 0 && kind != TypeConditional {
+			edited.EndsAt = nil
+		} else {
+			t := time.Unix(*body.EndsAt/1000, 0)
+			if !t.After(now) {
+				http.Error(w, "End date must be in the future", http.StatusBadRequest)
+				return edited, req, false, false
+			}
+			edited.EndsAt = &t
+		}
+	}
+	// The end date of a conditional template is its condition's expiry, which is signed too
+	if kind == TypeConditional && edited.EndsAt != nil {
+		condition := *current.Condition
+		condition.ExpiresAt = *edited.EndsAt
+		edited.Condition = &condition
+	}
+
+	return edited, req, scheduleChanged, true
+}
+
+// editTemplateContent replaces the transfers, schedule, recurrence or end
+// date of a template with a newly signed authorization and records the
+// result as a new version
+func editTemplateContent(w http.ResponseWriter, r *http.Request, templateID uint, body UpdateTemplateRequest, actor models.User) {
+	current, err := loadVersionedTemplate(templateID)
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+
+	now := time.Now()
+	edited, req, scheduleChanged, ok := editedTemplate(w, current, body, now)
+	if !ok {
+		return
+	}
+
+	kind := templateType(current)
+	repeating := kind == TypeRecurring || (kind == TypeConditional && current.Condition.Repeat)
+	edited.Authorization, ok = authorizationFromRequest(w, req, edited, current.User.EthereumAddress, repeating)
+	if !ok {
+		return
+	}
+
+	previous := snapshotTemplate(current)
+	changes := diffSnapshots(previous, snapshotTemplate(edited))
+	if len(changes) == 0 {
+		http.Error(w, "Nothing to change", http.StatusBadRequest)
+		return
+	}
+
+	edited.Version = current.Version + 1
+	if scheduleChanged {
+		edited.ScheduleRevision = current.ScheduleRevision + 1
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := ensureFirstVersion(tx, current); err != nil {
+			return err
+		}
+
+		// Past executions keep their records of the replaced transfers
+		if body.Transfers != nil {
+			if err := tx.Where("payment_template_id = ?", current.ID).Delete(&models.Transfer{}).Error; err != nil {
+				return err
+			}
+			for i := range edited.Transfers {
+				edited.Transfers[i].PaymentTemplateID = &current.ID
+			}
+			if err := tx.Create(&edited.Transfers).Error; err != nil {
+				return err
+			}
+		}
+
+		if current.Authorization != nil {
+			if err := tx.Delete(current.Authorization).Error; err != nil {
+				return err
+			}
+		}
+		if err := consumeAuthorizationNonce(tx, edited.Authorization); err != nil {
+			return err
+		}
+		edited.Authorization.PaymentTemplateID = current.ID
+		if err := tx.Create(edited.Authorization).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&models.PaymentTemplate{ID: current.ID}).Updates(map[string]interface{}{
+			"scheduled_at":       edited.ScheduledAt,
+			"recurring_interval": edited.RecurringInterval,
+			"ends_at":            edited.EndsAt,
+			"version":            edited.Version,
+			"schedule_revision":  edited.ScheduleRevision,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if kind == TypeConditional && edited.EndsAt != nil {
+			if err := tx.Model(current.Condition).Update("expires_at", *edited.EndsAt).Error; err != nil {
+				return err
+			}
+		}
+
+		changes = diffSnapshots(previous, snapshotTemplate(edited))
+		return recordTemplateVersion(tx, edited, actor.ID, changes)
+	})
+	if err != nil {
+		if errors.Is(err, errAuthorizationNonce) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(w, "Template was edited at the same time, try again", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	// Approvals only count for the transfers they were given on
+	if _, err := approvals.Refresh(&edited); err != nil {
+		log.Printf("could not evaluate approvals of templateId=%d: %v", edited.ID, err)
+	}
+
+	if scheduleChanged {
+		runAt := nextRun(*edited.ScheduledAt, edited.RecurringInterval, now)
+		scheduler.Schedule(edited.UserID, edited.ID, edited.ScheduleRevision, runAt)
+	}
+
+	published := edited
+	published.User.Email = nil
+	events.Publish(edited.UserID, events.TemplateUpdated, published)
+	audit.Record(r, audit.Entry{
+		UserID:     edited.UserID,
+		Actor:      actor.EthereumAddress,
+		Action:     audit.ActionTemplateUpdate,
+		TargetType: audit.TargetPaymentTemplate,
+		TargetID:   edited.ID,
+		Before:     previous,
+		After:      snapshotTemplate(edited),
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"version":  edited.Version,
+		"changes":  changes,
+		"warnings": addressBookWarnings(current.User, edited.Transfers),
+	})
+}
+
+// GetTemplateEditTypedData handles PUT /templates/{templateId}/typed-data
+// It takes the same edit as PUT /templates/{templateId} and returns the EIP-712
+// message the template's user must sign for it, with a new nonce.
+func GetTemplateEditTypedData(w http.ResponseWriter, r *http.Request) {
+	current, err := loadVersionedTemplate(mux.Vars(r)["templateId"])
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+
+	if !authorizeTemplate(w, r, current, models.PermissionPrepareTemplates) {
+		return
+	}
+
+	var body UpdateTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if body.NewName != "" || body.IsCancelled != nil {
+		http.Error(w, "Only edits of the transfers, schedule or end date are signed", http.StatusBadRequest)
+		return
+	}
+
+	edited, req, _, ok := editedTemplate(w, current, body, time.Now())
+	if !ok {
+		return
+	}
+
+	maxTotals, err := maxTotalsFromRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Whoever prepares the edit, the template's user signs it
+	signer := current.User.EthereumAddress
+	nonce, err := issueAuthorizationNonce(signer)
+	if err != nil {
+		http.Error(w, "Could not issue nonce", http.StatusInternalServerError)
+		return
+	}
+
+	typedData := authorization.PaymentTypedData(edited, signer, req.ChainID, maxTotals, nonce)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(typedData)
+}
+
+// GetTemplateVersions handles GET /templates/{templateId}/versions
+// Lists every version of the template, oldest first, with the changes to the one before.
+func GetTemplateVersions(w http.ResponseWriter, r *http.Request) {
+	template, err := loadVersionedTemplate(mux.Vars(r)["templateId"])
+	if err != nil {
+		http.Error(w, "Template not found", http.StatusNotFound)
+		return
+	}
+
+	if !authorizeTemplate(w, r, template, models.PermissionViewTemplates) {
+		return
+	}
+
+	if err := ensureFirstVersion(database.DB, template); err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	var versions []models.TemplateVersion
+	err = database.DB.
+		Where("payment_template_id = ?", template.ID).
+		Order("version").
+		Find(&versions).Error
+	if err != nil {
+		http.Error(w, "Error fetching versions", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(versions)
+}
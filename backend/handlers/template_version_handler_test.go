@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/authorization"
+	"backend/jwtLogic"
+	"backend/testdb"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+	"github.com/gorilla/mux"
+)
+
+func TestNextRun(t *testing.T) {
+	start := time.Date(2026, 2, 9, 13, 9, 39, 0, time.UTC)
+	day := int64(86400)
+	zero := int64(0)
+
+	tests := []struct {
+		name     string
+		interval *int64
+		now      time.Time
+		want     time.Time
+	}{
+		{"future start", &day, start.Add(-time.Hour), start},
+		{"one-off in the past", nil, start.Add(time.Hour), start},
+		{"zero interval", &zero, start.Add(time.Hour), start},
+		{"at the start", &day, start, start.Add(24 * time.Hour)},
+		{"within the first period", &day, start.Add(time.Hour), start.Add(24 * time.Hour)},
+		{"several periods later", &day, start.Add(50 * time.Hour), start.Add(72 * time.Hour)},
+		{"exactly on a later run", &day, start.Add(48 * time.Hour), start.Add(72 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextRun(start, tt.interval, tt.now); !got.Equal(tt.want) {
+				t.Errorf("nextRun = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// useEditableTemplate serves a scheduled template of owner paying 10 USDC,
+// with nonces and nothing executed yet
+func useEditableTemplate(t *testing.T, owner string, scheduledAt time.Time) *testdb.DB {
+	t.Helper()
+	return testdb.Use(t, func(query string, args []driver.Value) (testdb.Rows, error) {
+		switch {
+		case strings.Contains(query, "count(*)"):
+			count := int64(1)
+			if strings.Contains(query, "FROM `executions`") {
+				count = 0
+			}
+			return testdb.Rows{Columns: []string{"count(*)"}, Values: [][]driver.Value{{count}}}, nil
+		case strings.Contains(query, "FROM `payment_templates`"):
+			return testdb.Rows{
+				Columns: []string{"id", "user_id", "name", "version", "scheduled_at"},
+				Values:  [][]driver.Value{{int64(5), int64(7), "Rent", int64(1), scheduledAt}},
+			}, nil
+		case strings.Contains(query, "FROM `users`"):
+			return testdb.Rows{Columns: []string{"id", "ethereum_address"}, Values: [][]driver.Value{{int64(7), owner}}}, nil
+		case strings.Contains(query, "FROM `transfers`"):
+			return testdb.Rows{
+				Columns: []string{"id", "source_user_id", "destination_user_address", "payment_template_id", "amount", "asset_id", "status"},
+				Values:  [][]driver.Value{{int64(11), int64(7), csvTestUser.EthereumAddress, int64(5), 10.0, int64(1), "pending"}},
+			}, nil
+		case strings.Contains(query, "FROM `assets`"):
+			return queryAssets(query, args)
+		case strings.Contains(query, "FROM `payment_authorizations`"), strings.Contains(query, "FROM `payment_conditions`"),
+			strings.Contains(query, "FROM `contacts`"):
+			return testdb.Rows{Columns: []string{"id"}}, nil
+		}
+		return testdb.Rows{}, errors.New("unexpected query: " + query)
+	})
+}
+
+// editRequest is a PUT of body to path by owner
+func editRequest(path, owner, body string) *http.Request {
+	r := httptest.NewRequest(http.MethodPut, path, strings.NewReader(body))
+	r = mux.SetURLVars(r, map[string]string{"templateId": "5"})
+	return r.WithContext(context.WithValue(r.Context(), jwtLogic.UserContextKey, owner))
+}
+
+func TestEditWithStaleSignatureIsRejected(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	db := useEditableTemplate(t, owner, time.Now().Add(24*time.Hour).Truncate(time.Second))
+
+	edit := func(amount string) string {
+		return `{"transfers":[{"amount":` + amount + `,"destination":"` + csvTestUser.EthereumAddress + `","asset":{"id":1}}]`
+	}
+
+	// The owner signs the typed data of an edit to 20 USDC
+	w := httptest.NewRecorder()
+	GetTemplateEditTypedData(w, editRequest("/templates/5/typed-data", owner, edit("20")+"}"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("typed data status = %d (%s)", w.Code, strings.TrimSpace(w.Body.String()))
+	}
+	var typedData apitypes.TypedData
+	if err := json.NewDecoder(w.Body).Decode(&typedData); err != nil {
+		t.Fatal(err)
+	}
+	if got := typedData.Message["transfers"].([]interface{})[0].(map[string]interface{})["amount"]; got != "20000000" {
+		t.Errorf("typed data amount = %v, want the edited 20000000", got)
+	}
+	nonce, _ := typedData.Message["nonce"].(string)
+	if len(db.Execs("INSERT INTO `authorization_nonces`")) != 1 || nonce == "" {
+		t.Fatalf("typed data did not issue a nonce")
+	}
+
+	digest, err := authorization.Digest(typedData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := crypto.Sign(digest.Bytes(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig[64] += 27
+	signed := `,"authorization":{"nonce":"` + nonce + `","signature":"` + hexutil.Encode(sig) + `"}}`
+
+	// Sending another edit with that signature is rejected before anything is stored
+	w = httptest.NewRecorder()
+	UpdateTemplate(w, editRequest("/templates/5", owner, edit("25")+signed))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("stale signature status = %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), http.StatusUnauthorized)
+	}
+	if len(db.Execs("UPDATE `authorization_nonces`")) != 0 || len(db.Execs("INSERT INTO `payment_authorizations`")) != 0 {
+		t.Errorf("stale signature consumed the nonce or stored an authorization")
+	}
+
+	// The edit it was signed for goes through
+	w = httptest.NewRecorder()
+	UpdateTemplate(w, editRequest("/templates/5", owner, edit("20")+signed))
+	if w.Code != http.StatusOK {
+		t.Fatalf("signed edit status = %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), http.StatusOK)
+	}
+	if len(db.Execs("UPDATE `authorization_nonces`")) != 1 || len(db.Execs("INSERT INTO `payment_authorizations`")) == 0 {
+		t.Errorf("signed edit did not consume its nonce and store its authorization")
+	}
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"backend/models"
+	"backend/testdb"
+)
+
+// testAssets is the asset table served by the test database
+var testAssets = []models.Asset{
+	{ID: 1, Symbol: "USDC", Name: "USD Coin", Decimals: 6, ContractAddress: "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", ChainID: 8453, Enabled: true},
+	{ID: 2, Symbol: "ETH", Name: "Ether", Decimals: 18, ContractAddress: "0xEeeeeEeeeEeEeeEeEeEeeEEEeeeeEeeeeeeeEEeE", ChainID: 8453, Enabled: true},
+	{ID: 3, Symbol: "EURC", Name: "Euro Coin", Decimals: 6, ContractAddress: "0x60a3E35Cc302bFA44Cb288Bc5a4F316Fdb1adb42", ChainID: 8453, Enabled: true},
+}
+
+// useTestDB points database.DB at a database serving testAssets by id
+func useTestDB(t *testing.T) *testdb.DB {
+	t.Helper()
+	return testdb.Use(t, queryAssets)
+}
+
+// queryAssets serves lookups of an asset by id, the first argument; anything else fails
+func queryAssets(query string, args []driver.Value) (testdb.Rows, error) {
+	rows := testdb.Rows{Columns: []string{"id", "symbol", "name", "decimals", "contract_address", "chain_id", "enabled"}}
+	if !strings.Contains(query, "FROM `assets`") || len(args) == 0 {
+		return rows, errors.New("unexpected query: " + query)
+	}
+
+	for _, a := range testAssets {
+		// Ids taken from the URL are passed as strings
+		if fmt.Sprint(args[0]) == fmt.Sprint(a.ID) {
+			rows.Values = append(rows.Values, []driver.Value{int64(a.ID), a.Symbol, a.Name, int64(a.Decimals), a.ContractAddress, int64(a.ChainID), a.Enabled})
+		}
+	}
+	return rows, nil
+}
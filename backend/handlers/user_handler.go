@@ -3,14 +3,24 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"math/big"
 	"net/http"
+	"strconv"
 	"time"
 
+	"backend/approvals"
+	"backend/audit"
+	"backend/authorization"
+	"backend/chain"
 	"backend/database"
+	"backend/events"
 	"backend/jwtLogic"
 	"backend/models"
 	"backend/scheduler"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/gorilla/mux"
 	"gorm.io/gorm"
 
@@ -53,6 +63,7 @@ func GetUserByAddress(w http.ResponseWriter, r *http.Request) {
 }
 
 // GetUserTemplates handles GET /templates/{userAddress}
+// Lists a page of the user's templates, see listTemplates for the parameters.
 func GetUserTemplates(w http.ResponseWriter, r *http.Request) {
 	userAddressFromCookie := r.Context().Value(jwtLogic.UserContextKey).(string)
 	vars := mux.Vars(r)
@@ -68,15 +79,8 @@ func GetUserTemplates(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Find the user by Ethereum address and preload payment templates and transfers inside them
 	var user models.User
-	result := database.DB.
-		Preload("PaymentTemplates.Transfers").
-		Preload("PaymentTemplates.Transfers.SourceUser").
-		Preload("PaymentTemplates.Transfers.Asset").
-		Preload("PaymentTemplates.User").
-		Where("ethereum_address = ?", userAddress).
-		First(&user)
+	result := database.DB.Where("ethereum_address = ?", userAddress).First(&user)
 
 	if result.Error != nil {
 		if result.Error == gorm.ErrRecordNotFound {
@@ -87,78 +91,81 @@ func GetUserTemplates(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(user.PaymentTemplates)
+	listTemplates(w, r, database.DB.Where("payment_templates.user_id = ?", user.ID))
 }
 
-func CreateUserTemplate(w http.ResponseWriter, r *http.Request) {
-	userAddressFromCookie := r.Context().Value(jwtLogic.UserContextKey).(string)
-	vars := mux.Vars(r)
-	userAddress := vars["userAddress"]
-
-	type TypeOfBatch string
-
-	const (
-		TypeNow       TypeOfBatch = "NOW"
-		TypeSchedule  TypeOfBatch = "SCHEDULE"
-		TypeRecurring TypeOfBatch = "RECURRING"
-	)
+type TypeOfBatch string
 
-	type AssetInput struct {
-		ID              uint   `json:"id"`                         // Asset DB ID
-		Symbol          string `json:"symbol"`                     // Asset symbol
-		Name            string `json:"name"`                       // Asset name
-		Decimals        uint8  `json:"decimals"`                   // Asset decimals
-		ContractAddress string `json:"contract_address,omitempty"` // ERC-20 contract address
-		ChainID         uint64 `json:"chain_id"`                   // Blockchain chain ID
-	}
+const (
+	TypeNow       TypeOfBatch = "NOW"
+	TypeSchedule  TypeOfBatch = "SCHEDULE"
+	TypeRecurring TypeOfBatch = "RECURRING"
+	// TypeConditional executes once Condition holds on-chain instead of at a set time
+	TypeConditional TypeOfBatch = "CONDITIONAL"
+)
 
-	type TransferInput struct {
-		Amount      float64    `json:"amount"`      // Transfer amount
-		Destination string     `json:"destination"` // Destination Ethereum address
-		Asset       AssetInput `json:"asset"`       // Asset info
-	}
+type AssetInput struct {
+	ID              uint   `json:"id"`                         // Asset DB ID
+	Symbol          string `json:"symbol"`                     // Asset symbol
+	Name            string `json:"name"`                       // Asset name
+	Decimals        uint8  `json:"decimals"`                   // Asset decimals
+	ContractAddress string `json:"contract_address,omitempty"` // ERC-20 contract address
+	ChainID         uint64 `json:"chain_id"`                   // Blockchain chain ID
+}
 
-	type CreateTemplateRequest struct {
-		UserAddress       string          `json:"userAddress"` // Ethereum address of the user
-		ChainID           uint64          `json:"chainId"`     // Blockchain network ID
-		Type              TypeOfBatch     `json:"type"`        // Payment type, e.g., "NOW"
-		Transfers         []TransferInput `json:"transfers"`   // List of transfers
-		ScheduledAt       int64           `json:"scheduledAt"` // List of transfers
-		RecurringInterval int64           `json:"timeInterval"`
-	}
+type TransferInput struct {
+	Amount       float64    `json:"amount"`                 // Transfer amount
+	Destination  string     `json:"destination"`            // Destination Ethereum address
+	ContactID    uint       `json:"contactId,omitempty"`    // Optional contact paid instead of Destination
+	Asset        AssetInput `json:"asset"`                  // Asset info
+	FiatAmount   float64    `json:"fiatAmount,omitempty"`   // Optional amount in FiatCurrency, converted at execution
+	FiatCurrency string     `json:"fiatCurrency,omitempty"` // Optional fiat currency, e.g. "USD"
+}
 
-	var req CreateTemplateRequest
+type MaxTotalInput struct {
+	AssetID uint    `json:"assetId"` // Asset DB ID
+	Amount  float64 `json:"amount"`  // Most the backend may move in this asset over the template's life
+}
 
-	err := json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
+type AuthorizationInput struct {
+	MaxTotals []MaxTotalInput `json:"maxTotals"`
+	Signature string          `json:"signature"` // EIP-712 signature over the template content
+	Nonce     string          `json:"nonce"`     // Nonce of the signed typed data, as issued by /typed-data
+}
 
-	if userAddress == "" {
-		http.Error(w, "User address is required", http.StatusBadRequest)
-		return
-	}
+type ConditionInput struct {
+	Type            models.ConditionType       `json:"type"`                      // balance_above, gas_price_below or contract_call
+	AssetID         uint                       `json:"assetId,omitempty"`         // Asset whose balance is watched
+	Threshold       string                     `json:"threshold"`                 // Asset units, gwei or a raw uint256 depending on Type
+	ContractAddress string                     `json:"contractAddress,omitempty"` // Contract to call for contract_call
+	CallData        string                     `json:"callData,omitempty"`        // Hex encoded view call for contract_call
+	Comparator      models.ConditionComparator `json:"comparator,omitempty"`      // Only used by contract_call
+	ExpiresAt       int64                      `json:"expiresAt"`                 // Expiry in milliseconds
+	Repeat          bool                       `json:"repeat"`                    // Re-arm after each execution
+}
 
-	if !strings.EqualFold(userAddressFromCookie, userAddress) {
-		http.Error(w, "wrong cookie", http.StatusUnauthorized)
-		return
-	}
+type CreateTemplateRequest struct {
+	UserAddress       string              `json:"userAddress"` // Ethereum address of the user
+	ChainID           uint64              `json:"chainId"`     // Blockchain network ID
+	Type              TypeOfBatch         `json:"type"`        // Payment type, e.g., "NOW"
+	Transfers         []TransferInput     `json:"transfers"`   // List of transfers
+	ScheduledAt       int64               `json:"scheduledAt"` // List of transfers
+	RecurringInterval int64               `json:"timeInterval"`
+	EndsAt            int64               `json:"endsAt"`         // Optional end date in milliseconds
+	Authorization     *AuthorizationInput `json:"authorization"`  // Required for SCHEDULE and RECURRING
+	Permits           []PermitInput       `json:"permits"`        // Optional EIP-2612 permits instead of prior approvals
+	Condition         *ConditionInput     `json:"condition"`      // Required for CONDITIONAL
+	OrganizationID    *uint               `json:"organizationId"` // Optional organization sharing the template
+	Name              string              `json:"name"`           // Optional, named after Type otherwise
+}
 
-	var user models.User
-	result := database.DB.Where("ethereum_address = ?", userAddress).First(&user)
-	if result.Error != nil {
-		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-			http.Error(w, "User not found", http.StatusNotFound)
-			return
-		}
-		http.Error(w, "Database error", http.StatusInternalServerError)
-		return
+// templateFromRequest builds the template and its transfers described by req
+func templateFromRequest(req CreateTemplateRequest, user models.User) (models.PaymentTemplate, error) {
+	var template models.PaymentTemplate
+	if _, ok := chain.Networks[req.ChainID]; !ok {
+		return template, fmt.Errorf("Unsupported chain id: %d", req.ChainID)
 	}
 
-	// start creating the record itself
-	var template models.PaymentTemplate
 	switch req.Type {
 	case TypeNow:
 		name := "Payment"
@@ -187,69 +194,470 @@ func CreateUserTemplate(w http.ResponseWriter, r *http.Request) {
 			ScheduledAt:       &t,
 			RecurringInterval: &interval,
 		}
+	case TypeConditional:
+		name := "Conditional Payment"
+		condition, err := conditionFromRequest(req)
+		if err != nil {
+			return template, err
+		}
+		template = models.PaymentTemplate{
+			UserID:      user.ID,
+			Name:        name,
+			IsCancelled: false,
+			EndsAt:      &condition.ExpiresAt,
+			Condition:   condition,
+		}
+	default:
+		return template, errors.New("Unsupported payment type")
+	}
+
+	if req.EndsAt > 0 {
+		t := time.Unix(req.EndsAt/1000, 0)
+		template.EndsAt = &t
+	}
+	if name := strings.TrimSpace(req.Name); name != "" {
+		template.Name = name
+	}
+
+	transfers, err := transfersFromRequest(req, user)
+	if err != nil {
+		return template, err
+	}
+
+	// Attach transfers to template
+	template.Transfers = transfers
+	return template, nil
+}
+
+// conditionFromRequest converts the condition's threshold to the smallest unit
+// of what it is compared with
+func conditionFromRequest(req CreateTemplateRequest) (*models.PaymentCondition, error) {
+	in := req.Condition
+	if in == nil {
+		return nil, errors.New("Condition is required")
+	}
+
+	threshold, err := strconv.ParseFloat(in.Threshold, 64)
+	if err != nil || threshold < 0 {
+		return nil, errors.New("Invalid condition threshold")
+	}
+
+	expiresAt := time.Unix(in.ExpiresAt/1000, 0)
+	if !expiresAt.After(time.Now()) {
+		return nil, errors.New("Condition expiry must be in the future")
+	}
+
+	condition := models.PaymentCondition{
+		ChainID:   req.ChainID,
+		Type:      in.Type,
+		Repeat:    in.Repeat,
+		ExpiresAt: expiresAt,
+		Status:    models.ConditionStatusWaiting,
 	}
 
-	var transfers []models.Transfer
-	for _, t := range req.Transfers {
+	switch in.Type {
+	case models.ConditionTypeBalanceAbove:
 		var asset models.Asset
-		if err := database.DB.First(&asset, t.Asset.ID).Error; err != nil {
-			http.Error(w, "Asset not found", http.StatusBadRequest)
-			return
+		if err := database.DB.First(&asset, in.AssetID).Error; err != nil {
+			return nil, errors.New("Asset not found")
+		}
+		condition.AssetID = &asset.ID
+		condition.Asset = &asset
+		condition.Comparator = models.ComparatorGreater
+		condition.Threshold = chain.ToBaseUnits(threshold, asset.Decimals).String()
+	case models.ConditionTypeGasPriceBelow:
+		condition.Comparator = models.ComparatorLess
+		condition.Threshold = chain.ToBaseUnits(threshold, 9).String() // gwei to wei
+	case models.ConditionTypeContractCall:
+		value, ok := new(big.Int).SetString(in.Threshold, 10)
+		if !ok {
+			return nil, errors.New("Contract call threshold must be an integer")
+		}
+		if !common.IsHexAddress(in.ContractAddress) {
+			return nil, errors.New("Invalid condition contract address")
+		}
+		switch in.Comparator {
+		case models.ComparatorEqual, models.ComparatorGreater, models.ComparatorGreaterOrEqual,
+			models.ComparatorLess, models.ComparatorLessOrEqual:
+		default:
+			return nil, errors.New("Invalid condition comparator")
 		}
+		condition.ContractAddress = in.ContractAddress
+		condition.CallData = in.CallData
+		condition.Comparator = in.Comparator
+		condition.Threshold = value.String()
+	default:
+		return nil, errors.New("Unsupported condition type")
+	}
+
+	return &condition, nil
+}
 
-		transfers = append(transfers, models.Transfer{
-			SourceUserID:           user.ID,
-			DestinationUserAddress: t.Destination,
-			Amount:                 t.Amount,
-			AssetID:                asset.ID,
-			Status:                 models.TransferStatusPending,
-			Asset:                  asset,
+// maxTotalsFromRequest loads the assets of the requested max totals
+func maxTotalsFromRequest(req CreateTemplateRequest) ([]models.AuthorizationMaxTotal, error) {
+	if req.Authorization == nil {
+		return nil, nil
+	}
+
+	var maxTotals []models.AuthorizationMaxTotal
+	for _, m := range req.Authorization.MaxTotals {
+		var asset models.Asset
+		if err := database.DB.First(&asset, m.AssetID).Error; err != nil {
+			return nil, errors.New("Asset not found")
+		}
+		if asset.ChainID != req.ChainID {
+			return nil, fmt.Errorf("Asset %s is on chain %d, not %d", asset.Symbol, asset.ChainID, req.ChainID)
+		}
+		if !asset.Enabled {
+			return nil, fmt.Errorf("Asset %s is disabled", asset.Symbol)
+		}
+		maxTotals = append(maxTotals, models.AuthorizationMaxTotal{
+			AssetID: asset.ID,
+			Amount:  m.Amount,
+			Asset:   asset,
 		})
 	}
+	return maxTotals, nil
+}
 
-	// Attach transfers to template
-	template.Transfers = transfers
+// AuthorizationNonceLifetime is how long the typed data of a template can be signed and submitted
+const AuthorizationNonceLifetime = time.Hour
+
+var errAuthorizationNonce = errors.New("Authorization nonce is invalid, expired or already used")
+
+// issueAuthorizationNonce stores a new nonce for an authorization signer will sign
+func issueAuthorizationNonce(signer string) (string, error) {
+	now := time.Now()
+	if err := database.DB.Where("expires_at < ?", now).Delete(&models.AuthorizationNonce{}).Error; err != nil {
+		log.Printf("could not delete expired authorization nonces: %v", err)
+	}
+
+	nonce := models.AuthorizationNonce{
+		Address:   strings.ToLower(signer),
+		Nonce:     authorization.NewNonce(),
+		ExpiresAt: now.Add(AuthorizationNonceLifetime),
+	}
+	if err := database.DB.Create(&nonce).Error; err != nil {
+		return "", err
+	}
+	return nonce.Nonce, nil
+}
+
+// consumeAuthorizationNonce marks the nonce of auth as used, in the transaction
+// storing it; it fails if the nonce was used by another authorization
+func consumeAuthorizationNonce(tx *gorm.DB, auth *models.PaymentAuthorization) error {
+	now := time.Now()
+	result := tx.Model(&models.AuthorizationNonce{}).
+		Where("nonce = ? AND address = ? AND used_at IS NULL AND expires_at > ?", auth.Nonce, strings.ToLower(auth.Signer), now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return errAuthorizationNonce
+	}
+	return nil
+}
+
+// authorizationFromRequest checks the signed authorization of req covers the
+// template's transfers and schedule, writing the error response on failure.
+// Repeating templates need a max total for every asset they move.
+func authorizationFromRequest(w http.ResponseWriter, req CreateTemplateRequest, template models.PaymentTemplate, signer string, repeating bool) (*models.PaymentAuthorization, bool) {
+	if req.Authorization == nil || req.Authorization.Signature == "" {
+		http.Error(w, "Signed authorization is required", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// The nonce is consumed when the authorization is stored
+	var issued int64
+	err := database.DB.Model(&models.AuthorizationNonce{}).
+		Where("nonce = ? AND address = ? AND used_at IS NULL AND expires_at > ?", req.Authorization.Nonce, strings.ToLower(signer), time.Now()).
+		Count(&issued).Error
+	if err != nil {
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return nil, false
+	}
+	if req.Authorization.Nonce == "" || issued == 0 {
+		http.Error(w, errAuthorizationNonce.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+
+	maxTotals, err := maxTotalsFromRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+
+	if repeating {
+		for _, t := range template.Transfers {
+			covered := false
+			for _, m := range maxTotals {
+				covered = covered || m.AssetID == t.AssetID
+			}
+			if !covered {
+				http.Error(w, "Max total is required for every asset of a repeating payment", http.StatusBadRequest)
+				return nil, false
+			}
+		}
+	}
+
+	typedData := authorization.PaymentTypedData(template, signer, req.ChainID, maxTotals, req.Authorization.Nonce)
+	digest, err := authorization.Digest(typedData)
+	if err != nil {
+		http.Error(w, "Invalid authorization", http.StatusBadRequest)
+		return nil, false
+	}
+
+	// Smart accounts sign with EIP-1271 or, before deployment, ERC-6492
+	if err := authorization.VerifyAccountSignature(digest, req.Authorization.Signature, signer, req.ChainID); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return nil, false
+	}
+
+	return &models.PaymentAuthorization{
+		Signer:    signer,
+		ChainID:   req.ChainID,
+		Nonce:     req.Authorization.Nonce,
+		Digest:    digest.Hex(),
+		Signature: req.Authorization.Signature,
+		MaxTotals: maxTotals,
+	}, true
+}
+
+// decodeTemplateRequest parses the body of a template request and loads the
+// authenticated user it is for, writing the error response on failure
+func decodeTemplateRequest(w http.ResponseWriter, r *http.Request) (CreateTemplateRequest, models.User, bool) {
+	userAddressFromCookie := r.Context().Value(jwtLogic.UserContextKey).(string)
+	vars := mux.Vars(r)
+	userAddress := vars["userAddress"]
 
-	newRecord := database.DB.Create(&template)
-	if newRecord.Error != nil {
+	var req CreateTemplateRequest
+	var user models.User
+
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return req, user, false
+	}
+
+	if userAddress == "" {
+		http.Error(w, "User address is required", http.StatusBadRequest)
+		return req, user, false
+	}
+
+	if !strings.EqualFold(userAddressFromCookie, userAddress) {
+		http.Error(w, "wrong cookie", http.StatusUnauthorized)
+		return req, user, false
+	}
+
+	result := database.DB.Where("ethereum_address = ?", userAddress).First(&user)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return req, user, false
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return req, user, false
+	}
+
+	return req, user, true
+}
+
+// GetTemplateTypedData handles POST /templates/{userAddress}/typed-data
+// It returns the EIP-712 message the user must sign before creating the same
+// scheduled or recurring template.
+func GetTemplateTypedData(w http.ResponseWriter, r *http.Request) {
+	req, user, ok := decodeTemplateRequest(w, r)
+	if !ok {
+		return
+	}
+	writeTemplateTypedData(w, req, user)
+}
+
+// writeTemplateTypedData responds with the EIP-712 message of the template req describes
+func writeTemplateTypedData(w http.ResponseWriter, req CreateTemplateRequest, user models.User) {
+	template, err := templateFromRequest(req, user)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	maxTotals, err := maxTotalsFromRequest(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	nonce, err := issueAuthorizationNonce(user.EthereumAddress)
+	if err != nil {
+		http.Error(w, "Could not issue nonce", http.StatusInternalServerError)
+		return
+	}
+
+	typedData := authorization.PaymentTypedData(template, user.EthereumAddress, req.ChainID, maxTotals, nonce)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(typedData)
+}
+
+func CreateUserTemplate(w http.ResponseWriter, r *http.Request) {
+	req, user, ok := decodeTemplateRequest(w, r)
+	if !ok {
+		return
+	}
+	createTemplate(w, r, req, user)
+}
+
+// createTemplate stores the template req describes for user and queues its first run
+func createTemplate(w http.ResponseWriter, r *http.Request, req CreateTemplateRequest, user models.User) {
+	var ok bool
+
+	// start creating the record itself
+	template, err := templateFromRequest(req, user)
+	if err != nil {
+		writeTemplateError(w, err)
+		return
+	}
+
+	if req.OrganizationID != nil {
+		if _, _, ok := requireOrganizationPermission(w, r, *req.OrganizationID, models.PermissionPrepareTemplates); !ok {
+			return
+		}
+		template.OrganizationID = req.OrganizationID
+	}
+
+	// The backend only executes scheduled and recurring templates, and only
+	// what the user has signed for
+	if req.Type == TypeSchedule || req.Type == TypeRecurring || req.Type == TypeConditional {
+		repeating := req.Type == TypeRecurring || (req.Type == TypeConditional && req.Condition.Repeat)
+		template.Authorization, ok = authorizationFromRequest(w, req, template, user.EthereumAddress, repeating)
+		if !ok {
+			return
+		}
+	}
+
+	if len(req.Permits) > 0 {
+		if req.Type == TypeNow {
+			http.Error(w, "Permits are only used by scheduled payments", http.StatusBadRequest)
+			return
+		}
+
+		template.Permits, err = permitsFromRequest(req.Permits, user, template.Transfers)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	template.Version = 1
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if template.Authorization != nil {
+			if err := consumeAuthorizationNonce(tx, template.Authorization); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&template).Error
+	})
+	if err != nil {
+		if errors.Is(err, errAuthorizationNonce) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			http.Error(w, "Authorization was already used", http.StatusConflict)
+			return
+		}
 		http.Error(w, "Asset not found", http.StatusInternalServerError)
 		return
 	}
+	if err := recordTemplateVersion(database.DB, template, user.ID, nil); err != nil {
+		log.Printf("could not record version of templateId=%d: %v", template.ID, err)
+	}
+
+	// Organization templates wait for the approvals their policy requires
+	if _, err := approvals.Refresh(&template); err != nil {
+		log.Printf("could not evaluate approvals of templateId=%d: %v", template.ID, err)
+	}
+
+	published := template
+	published.User = user
+	published.User.Email = nil
+	events.Publish(user.ID, events.TemplateCreated, published)
+	audit.Record(r, audit.Entry{
+		UserID:     user.ID,
+		Actor:      user.EthereumAddress,
+		Action:     audit.ActionTemplateCreate,
+		TargetType: audit.TargetPaymentTemplate,
+		TargetID:   template.ID,
+		After:      published,
+	})
 
 	switch req.Type {
 	case TypeSchedule:
-		scheduler.JobsChan <- scheduler.Job{RunAt: time.Unix(req.ScheduledAt/1000, 0), TemplateId: template.ID}
+		scheduler.Schedule(user.ID, template.ID, template.ScheduleRevision, time.Unix(req.ScheduledAt/1000, 0))
 	case TypeRecurring:
-		scheduler.JobsChan <- scheduler.Job{RunAt: time.Unix(req.ScheduledAt/1000, 0), TemplateId: template.ID}
+		// A schedule that started in the past continues from its next run
+		runAt := nextRun(*template.ScheduledAt, template.RecurringInterval, time.Now())
+		scheduler.Schedule(user.ID, template.ID, template.ScheduleRevision, runAt)
 	}
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "success",
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":   "success",
+		"id":       template.ID,
+		"warnings": addressBookWarnings(user, template.Transfers),
 	})
 }
 
+// DeleteTemplate handles DELETE /templates/{templateId}
+// The template is cancelled and hidden; its executions and their history stay.
 func DeleteTemplate(w http.ResponseWriter, r *http.Request) {
 	userAddressFromCookie := r.Context().Value(jwtLogic.UserContextKey).(string)
 	vars := mux.Vars(r)
 	templateId := vars["templateId"]
 
 	var template models.PaymentTemplate
-	if err := database.DB.Preload("User").First(&template, "id = ?", templateId).Error; err != nil {
+	if err := database.DB.Preload("User").Preload("Transfers").First(&template, "id = ?", templateId).Error; err != nil {
 		w.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	if template.User.EthereumAddress != userAddressFromCookie {
-		w.WriteHeader(http.StatusUnauthorized)
+	if !authorizeTemplate(w, r, template, models.PermissionPrepareTemplates) {
 		return
 	}
 
-	if err := database.DB.Delete(&template).Delete(&models.Transfer{}).Error; err != nil {
+	before := template
+	before.User.Email = nil
+
+	// Executions keep referring to the template, so it is cancelled and hidden
+	// rather than removed
+	cancelled := !template.IsCancelled
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&template).Update("is_cancelled", true).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&template).Error
+	})
+	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	audit.Record(r, audit.Entry{
+		UserID:     template.UserID,
+		Actor:      userAddressFromCookie,
+		Action:     audit.ActionTemplateDelete,
+		TargetType: audit.TargetPaymentTemplate,
+		TargetID:   template.ID,
+		Before:     before,
+	})
+
+	if cancelled {
+		published := template
+		published.User.Email = nil
+		events.Publish(template.UserID, events.TemplateCancelled, published)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{
@@ -257,6 +665,27 @@ func DeleteTemplate(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// UpdateTemplateRequest renames or cancels a template, or edits what it pays
+// and when; times and the interval are in milliseconds like on creation
+type UpdateTemplateRequest struct {
+	NewName     string `json:"newName"`
+	IsCancelled *bool  `json:"isCancelled"`
+
+	Transfers         []TransferInput     `json:"transfers"`
+	ScheduledAt       *int64              `json:"scheduledAt"`
+	RecurringInterval *int64              `json:"timeInterval"`
+	EndsAt            *int64              `json:"endsAt"`        // 0 removes the end date
+	Authorization     *AuthorizationInput `json:"authorization"` // Signature over the edited template
+}
+
+// editsContent reports whether the request changes a versioned part of the template
+func (req UpdateTemplateRequest) editsContent() bool {
+	return req.Transfers != nil || req.ScheduledAt != nil || req.RecurringInterval != nil ||
+		req.EndsAt != nil || req.Authorization != nil
+}
+
+// UpdateTemplate handles PUT /templates/{templateId}
+// Edits of the transfers, schedule or end date create a new template version.
 func UpdateTemplate(w http.ResponseWriter, r *http.Request) {
 	userAddress := r.Context().Value(jwtLogic.UserContextKey).(string)
 	vars := mux.Vars(r)
@@ -268,31 +697,72 @@ func UpdateTemplate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if template.User.EthereumAddress != userAddress {
-		w.WriteHeader(http.StatusUnauthorized)
+	if !authorizeTemplate(w, r, template, models.PermissionPrepareTemplates) {
 		return
 	}
 
-	// Parse newName from request body
-	var body struct {
-		NewName string `json:"newName"`
-	}
+	var body UpdateTemplateRequest
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
 
+	if body.editsContent() {
+		if body.NewName != "" || body.IsCancelled != nil {
+			http.Error(w, "Rename or cancel a template separately from editing it", http.StatusBadRequest)
+			return
+		}
+		actor, ok := findContextUser(w, r)
+		if !ok {
+			return
+		}
+		editTemplateContent(w, r, template.ID, body, actor)
+		return
+	}
+
+	before := template
+	before.User.Email = nil
+
+	cancelled := false
+	if body.IsCancelled != nil {
+		if template.IsCancelled && !*body.IsCancelled {
+			http.Error(w, "Cancelled templates cannot be resumed", http.StatusBadRequest)
+			return
+		}
+		cancelled = !template.IsCancelled && *body.IsCancelled
+		template.IsCancelled = *body.IsCancelled
+	}
+
 	// Update the template name
-	template.Name = body.NewName
+	if body.NewName != "" {
+		template.Name = body.NewName
+	}
 	if err := database.DB.Save(&template).Error; err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
+	published := template
+	published.User.Email = nil
+	audit.Record(r, audit.Entry{
+		UserID:     template.UserID,
+		Actor:      userAddress,
+		Action:     audit.ActionTemplateUpdate,
+		TargetType: audit.TargetPaymentTemplate,
+		TargetID:   template.ID,
+		Before:     before,
+		After:      published,
+	})
+
+	if cancelled {
+		events.Publish(template.UserID, events.TemplateCancelled, published)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "success",
-		"name":   template.Name,
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":       "success",
+		"name":         template.Name,
+		"is_cancelled": template.IsCancelled,
 	})
 }
@@ -0,0 +1,26 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"backend/database"
+	"backend/models"
+
+	"gorm.io/gorm"
+)
+
+func TestDeletedTemplatesKeepTheirRow(t *testing.T) {
+	useTestDB(t)
+	dryRun := database.DB.Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true})
+
+	sql := dryRun.Delete(&models.PaymentTemplate{ID: 1}).Statement.SQL.String()
+	if !strings.HasPrefix(sql, "UPDATE `payment_templates` SET `deleted_at`") {
+		t.Errorf("deleting a template runs %q, want a soft delete", sql)
+	}
+
+	sql = dryRun.Find(&[]models.PaymentTemplate{}).Statement.SQL.String()
+	if !strings.Contains(sql, "`payment_templates`.`deleted_at` IS NULL") {
+		t.Errorf("listing templates runs %q, want deleted ones excluded", sql)
+	}
+}
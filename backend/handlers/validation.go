@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"backend/database"
+	"backend/models"
+	"backend/pricing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+// RowError describes one invalid field of a transfer in a template request,
+// or of a line in an imported CSV file
+type RowError struct {
+	Row     int    `json:"row"`   // Index of the transfer in the request, or line of the CSV file
+	Field   string `json:"field"` // JSON name or CSV column of the invalid field, e.g. "destination"
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid transfer of a request, so clients can
+// show all problems at once instead of the first one
+type ValidationError struct {
+	Rows []RowError
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Rows) == 1 {
+		return "1 transfer is invalid"
+	}
+	return fmt.Sprintf("%d transfer errors", len(e.Rows))
+}
+
+func (e *ValidationError) add(row int, field, format string, args ...interface{}) {
+	e.Rows = append(e.Rows, RowError{Row: row, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// writeTemplateError responds with the per-row errors of a ValidationError as
+// JSON, and with any other error as plain text
+func writeTemplateError(w http.ResponseWriter, err error) {
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":  validationErr.Error(),
+		"errors": validationErr.Rows,
+	})
+}
+
+// validAddressChecksum reports whether a mixed-case address carries a valid
+// EIP-55 checksum; all lower or upper case addresses have none to check
+func validAddressChecksum(address string) bool {
+	hex := strings.TrimPrefix(address, "0x")
+	if hex == strings.ToLower(hex) || hex == strings.ToUpper(hex) {
+		return true
+	}
+	return common.HexToAddress(address).Hex() == address
+}
+
+// decimalPlaces counts the digits after the decimal point of amount
+func decimalPlaces(amount float64) int {
+	_, fraction, found := strings.Cut(strconv.FormatFloat(amount, 'f', -1, 64), ".")
+	if !found {
+		return 0
+	}
+	return len(fraction)
+}
+
+// findTransferAsset loads the asset of a transfer on chainID, by ID or else by
+// contract address
+func findTransferAsset(in AssetInput, chainID uint64) (models.Asset, error) {
+	var asset models.Asset
+	query := database.DB
+	if in.ID != 0 {
+		query = query.Where("id = ?", in.ID)
+	} else if in.ContractAddress != "" {
+		query = query.Where("chain_id = ? AND LOWER(contract_address) = ?", chainID, strings.ToLower(in.ContractAddress))
+	} else {
+		return asset, errors.New("Asset id or contract address is required")
+	}
+
+	if err := query.First(&asset).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return asset, errors.New("Asset not found")
+		}
+		return asset, err
+	}
+	if asset.ChainID != chainID {
+		return asset, fmt.Errorf("Asset %s is on chain %d, not %d", asset.Symbol, asset.ChainID, chainID)
+	}
+	if in.ContractAddress != "" && !strings.EqualFold(in.ContractAddress, asset.ContractAddress) {
+		return asset, fmt.Errorf("Asset %s has contract address %s", asset.Symbol, asset.ContractAddress)
+	}
+	if !asset.Enabled {
+		return asset, fmt.Errorf("Asset %s is disabled", asset.Symbol)
+	}
+	return asset, nil
+}
+
+// transfersFromRequest validates every transfer of req and builds them,
+// returning a ValidationError that lists all invalid rows
+func transfersFromRequest(req CreateTemplateRequest, user models.User) ([]models.Transfer, error) {
+	var transfers []models.Transfer
+	invalid := &ValidationError{}
+
+	for i, t := range req.Transfers {
+		rowErrors := len(invalid.Rows)
+
+		// A contact stands for its address, and for its default asset when the transfer names none
+		if t.ContactID != 0 {
+			contact, err := findTransferContact(t.ContactID, user)
+			if err != nil {
+				invalid.add(i, "contactId", "%s", err.Error())
+				continue
+			}
+			if t.Destination != "" && !strings.EqualFold(t.Destination, contact.Address) {
+				invalid.add(i, "destination", "Destination differs from the address of contact %s", contact.Name)
+			}
+			t.Destination = contact.Address
+			if t.Asset.ID == 0 && t.Asset.ContractAddress == "" && contact.DefaultAssetID != nil {
+				t.Asset.ID = *contact.DefaultAssetID
+			}
+		}
+
+		asset, err := findTransferAsset(t.Asset, req.ChainID)
+		if err != nil {
+			invalid.add(i, "asset", "%s", err.Error())
+		}
+
+		if !common.IsHexAddress(t.Destination) {
+			invalid.add(i, "destination", "Destination is not a valid address")
+		} else if !validAddressChecksum(t.Destination) {
+			invalid.add(i, "destination", "Destination has an invalid EIP-55 checksum")
+		}
+
+		transfer := models.Transfer{
+			SourceUserID:           user.ID,
+			DestinationUserAddress: t.Destination,
+			Amount:                 t.Amount,
+			AssetID:                asset.ID,
+			Status:                 models.TransferStatusPending,
+			Asset:                  asset,
+		}
+
+		if t.FiatCurrency != "" {
+			currency := strings.ToUpper(t.FiatCurrency)
+			if !pricing.IsSupportedCurrency(currency) {
+				invalid.add(i, "fiatCurrency", "Unsupported fiat currency")
+			}
+			if pricing.Default == nil {
+				invalid.add(i, "fiatCurrency", "Fiat amounts need a price source, none is configured")
+			}
+			if req.Type == TypeNow {
+				invalid.add(i, "fiatCurrency", "Fiat amounts are only supported for scheduled payments")
+			}
+			if t.FiatAmount <= 0 {
+				invalid.add(i, "fiatAmount", "Fiat amount must be positive")
+			}
+			fiatAmount := t.FiatAmount
+			transfer.FiatAmount = &fiatAmount
+			transfer.FiatCurrency = &currency
+			transfer.Amount = 0
+		} else if t.Amount <= 0 {
+			invalid.add(i, "amount", "Amount must be positive")
+		} else if asset.ID != 0 && decimalPlaces(t.Amount) > int(asset.Decimals) {
+			invalid.add(i, "amount", "%s supports at most %d decimals", asset.Symbol, asset.Decimals)
+		}
+
+		if len(invalid.Rows) == rowErrors {
+			transfers = append(transfers, transfer)
+		}
+	}
+
+	if len(invalid.Rows) > 0 {
+		return nil, invalid
+	}
+	return transfers, nil
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"backend/pricing"
+)
+
+// fixedRate prices every asset at the same rate
+type fixedRate float64
+
+func (r fixedRate) Rate(string, string) (float64, error) { return float64(r), nil }
+
+func TestTransfersFromRequestFiat(t *testing.T) {
+	useTestDB(t)
+	previous := pricing.Default
+	t.Cleanup(func() { pricing.Default = previous })
+
+	req := CreateTemplateRequest{
+		ChainID: 8453,
+		Type:    TypeSchedule,
+		Transfers: []TransferInput{{
+			Destination:  "0x1234567890abcdef1234567890abcdef12345678",
+			Asset:        AssetInput{ID: 1},
+			FiatAmount:   12.34,
+			FiatCurrency: "usd",
+		}},
+	}
+
+	pricing.Default = nil
+	_, err := transfersFromRequest(req, csvTestUser)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) || len(invalid.Rows) != 1 || invalid.Rows[0].Field != "fiatCurrency" {
+		t.Errorf("without a price source got %v, want a fiatCurrency error", err)
+	}
+
+	pricing.Default = fixedRate(1)
+	transfers, err := transfersFromRequest(req, csvTestUser)
+	if err != nil {
+		t.Fatalf("with a price source: %v", err)
+	}
+	if transfers[0].FiatAmount == nil || *transfers[0].FiatAmount != 12.34 || *transfers[0].FiatCurrency != "USD" || transfers[0].Amount != 0 {
+		t.Errorf("got %+v", transfers[0])
+	}
+}
+
+func TestTransfersFromRequestReportsEveryRow(t *testing.T) {
+	useTestDB(t)
+	const valid = "0x6969174FD72466430a46e18234D0b530c9FD5f49"
+
+	req := CreateTemplateRequest{
+		ChainID: 8453,
+		Type:    TypeSchedule,
+		Transfers: []TransferInput{
+			{Destination: valid, Asset: AssetInput{ID: 1}, Amount: 1.5},
+			{Destination: "0x6969174fd72466430a46e18234D0b530c9FD5f49", Asset: AssetInput{ID: 1}, Amount: 1},
+			{Destination: "0x1234", Asset: AssetInput{ID: 99}, Amount: 1},
+			{Destination: valid, Asset: AssetInput{ID: 1}, Amount: 0.1234567},
+			{Destination: valid, Asset: AssetInput{ID: 2}, Amount: -1},
+			{Destination: valid, Asset: AssetInput{ID: 1, ContractAddress: "0x60a3E35Cc302bFA44Cb288Bc5a4F316Fdb1adb42"}, Amount: 1},
+		},
+	}
+
+	_, err := transfersFromRequest(req, csvTestUser)
+	var invalid *ValidationError
+	if !errors.As(err, &invalid) {
+		t.Fatalf("err = %v, want a ValidationError", err)
+	}
+
+	want := []struct {
+		row   int
+		field string
+	}{
+		{1, "destination"},
+		{2, "asset"},
+		{2, "destination"},
+		{3, "amount"},
+		{4, "amount"},
+		{5, "asset"},
+	}
+	if len(invalid.Rows) != len(want) {
+		t.Fatalf("got errors %+v, want %d", invalid.Rows, len(want))
+	}
+	for i, w := range want {
+		if got := invalid.Rows[i]; got.Row != w.row || got.Field != w.field {
+			t.Errorf("error %d = %+v, want row %d, field %s", i, got, w.row, w.field)
+		}
+	}
+
+	req.ChainID = 10
+	req.Transfers = req.Transfers[:1]
+	if _, err := transfersFromRequest(req, csvTestUser); !errors.As(err, &invalid) || invalid.Rows[0].Field != "asset" {
+		t.Errorf("asset of another chain: err = %v, want an asset error", err)
+	}
+}
+
+func TestWriteTemplateError(t *testing.T) {
+	invalid := &ValidationError{}
+	invalid.add(0, "amount", "Amount must be positive")
+	invalid.add(2, "destination", "Destination is not a valid address")
+
+	w := httptest.NewRecorder()
+	writeTemplateError(w, invalid)
+	if w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	var body struct {
+		Error  string     `json:"error"`
+		Errors []RowError `json:"errors"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&body); err != nil || body.Error != "2 transfer errors" || len(body.Errors) != 2 || body.Errors[1].Row != 2 {
+		t.Errorf("body = %+v (%v), want both row errors", body, err)
+	}
+
+	w = httptest.NewRecorder()
+	writeTemplateError(w, errors.New("Asset not found"))
+	if w.Code != http.StatusBadRequest || strings.TrimSpace(w.Body.String()) != "Asset not found" {
+		t.Errorf("plain error: status %d, body %q", w.Code, w.Body.String())
+	}
+}
+
+func TestValidAddressChecksum(t *testing.T) {
+	tests := map[string]bool{
+		"0x6969174FD72466430a46e18234D0b530c9FD5f49": true,
+		"0x6969174fd72466430a46e18234d0b530c9fd5f49": true,
+		"0x6969174FD72466430A46E18234D0B530C9FD5F49": true,
+		"0x6969174fd72466430a46e18234D0b530c9FD5f49": false,
+	}
+	for address, want := range tests {
+		if got := validAddressChecksum(address); got != want {
+			t.Errorf("validAddressChecksum(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
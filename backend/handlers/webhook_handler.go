@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"backend/database"
+	"backend/events"
+	"backend/jwtLogic"
+	"backend/models"
+	"backend/webhooks"
+
+	"github.com/gorilla/mux"
+	"gorm.io/gorm"
+)
+
+// GetUserWebhooks handles GET /webhooks/{userAddress}
+func GetUserWebhooks(w http.ResponseWriter, r *http.Request) {
+	userAddressFromCookie := r.Context().Value(jwtLogic.UserContextKey).(string)
+	userAddress := mux.Vars(r)["userAddress"]
+
+	if !strings.EqualFold(userAddressFromCookie, userAddress) {
+		http.Error(w, "wrong cookie", http.StatusUnauthorized)
+		return
+	}
+
+	var endpoints []models.WebhookEndpoint
+	err := database.DB.
+		Joins("JOIN users ON users.id = webhook_endpoints.user_id").
+		Where("users.ethereum_address = ?", userAddress).
+		Order("webhook_endpoints.id").
+		Find(&endpoints).Error
+	if err != nil {
+		http.Error(w, "Error fetching webhooks", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(endpoints)
+}
+
+// CreateWebhook handles POST /webhooks/{userAddress}
+// The signing secret is only returned in this response.
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userAddressFromCookie := r.Context().Value(jwtLogic.UserContextKey).(string)
+	userAddress := mux.Vars(r)["userAddress"]
+
+	var req struct {
+		URL    string   `json:"url"`
+		Secret string   `json:"secret"` // Optional, generated when empty
+		Events []string `json:"events"` // Optional filter, all events when empty
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !strings.EqualFold(userAddressFromCookie, userAddress) {
+		http.Error(w, "wrong cookie", http.StatusUnauthorized)
+		return
+	}
+
+	if err := webhooks.ValidateURL(req.URL); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for _, e := range req.Events {
+		known := false
+		for _, t := range events.Types {
+			known = known || e == string(t)
+		}
+		if !known {
+			http.Error(w, "Unknown event type: "+e, http.StatusBadRequest)
+			return
+		}
+	}
+
+	var user models.User
+	if err := database.DB.Where("ethereum_address = ?", userAddress).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Database error", http.StatusInternalServerError)
+		return
+	}
+
+	endpoint := models.WebhookEndpoint{
+		UserID: user.ID,
+		URL:    req.URL,
+		Secret: req.Secret,
+		Events: strings.Join(req.Events, ","),
+		Active: true,
+	}
+	if endpoint.Secret == "" {
+		endpoint.Secret = webhooks.NewSecret()
+	}
+
+	if err := database.DB.Create(&endpoint).Error; err != nil {
+		http.Error(w, "Could not save webhook", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"webhook": endpoint,
+		"secret":  endpoint.Secret,
+	})
+}
+
+// findUserWebhook loads a webhook endpoint owned by the authenticated user, writing the error response on failure
+func findUserWebhook(w http.ResponseWriter, r *http.Request, webhookId interface{}) (models.WebhookEndpoint, bool) {
+	userAddress := r.Context().Value(jwtLogic.UserContextKey).(string)
+
+	var endpoint models.WebhookEndpoint
+	if err := database.DB.Preload("User").First(&endpoint, "id = ?", webhookId).Error; err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return endpoint, false
+	}
+
+	if !strings.EqualFold(endpoint.User.EthereumAddress, userAddress) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return endpoint, false
+	}
+
+	return endpoint, true
+}
+
+// DeleteWebhook handles DELETE /webhooks/{webhookId}
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := findUserWebhook(w, r, mux.Vars(r)["webhookId"])
+	if !ok {
+		return
+	}
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_endpoint_id = ?", endpoint.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&endpoint).Error
+	})
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "success",
+	})
+}
+
+// GetWebhookDeliveries handles GET /webhooks/{webhookId}/deliveries
+func GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	endpoint, ok := findUserWebhook(w, r, mux.Vars(r)["webhookId"])
+	if !ok {
+		return
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := database.DB.
+		Where("webhook_endpoint_id = ?", endpoint.ID).
+		Order("id DESC").
+		Limit(200).
+		Find(&deliveries).Error
+	if err != nil {
+		http.Error(w, "Error fetching deliveries", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(deliveries)
+}
+
+// RedeliverWebhook handles POST /webhook-deliveries/{deliveryId}/redeliver
+func RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	deliveryId := mux.Vars(r)["deliveryId"]
+
+	var previous models.WebhookDelivery
+	if err := database.DB.First(&previous, "id = ?", deliveryId).Error; err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if _, ok := findUserWebhook(w, r, previous.WebhookEndpointID); !ok {
+		return
+	}
+
+	delivery, err := webhooks.Redeliver(previous)
+	if err != nil {
+		http.Error(w, "Could not queue delivery", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(delivery)
+}
@@ -0,0 +1,90 @@
+package jwtLogic
+
+import (
+	"backend/database"
+	"backend/models"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// CSRFHeader carries the session's CSRF token on state-changing requests
+const CSRFHeader = "X-CSRF-Token"
+
+// csrfCookieName is readable by scripts so a frontend on the same site can
+// copy it into CSRFHeader
+const csrfCookieName = "csrf_token"
+
+var ErrCSRFTokenMismatch = errors.New("missing or invalid csrf token")
+
+// newCSRFToken returns a random token for a new session
+func newCSRFToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// setCSRFToken hands the session's CSRF token to the client, both as a cookie
+// and as a response header for frontends served from another origin
+func setCSRFToken(w http.ResponseWriter, session models.Session) {
+	http.SetCookie(w, &http.Cookie{
+		Name:    csrfCookieName,
+		Value:   session.CSRFToken,
+		Path:    "/",
+		Expires: session.ExpiresAt,
+		// Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	w.Header().Set(CSRFHeader, session.CSRFToken)
+}
+
+// CSRFRequired reports whether the request method can change state
+func CSRFRequired(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	return true
+}
+
+// VerifyCSRF compares the request's CSRF header with the token of its session
+func VerifyCSRF(r *http.Request, session models.Session) error {
+	token := r.Header.Get(CSRFHeader)
+	if token == "" || session.CSRFToken == "" ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(session.CSRFToken)) != 1 {
+		return ErrCSRFTokenMismatch
+	}
+	return nil
+}
+
+// GetCSRFToken handles GET /auth/csrf
+// Returns the CSRF token of the current session, e.g. after a page reload.
+func GetCSRFToken(w http.ResponseWriter, r *http.Request) {
+	var session models.Session
+	if err := database.DB.First(&session, r.Context().Value(SessionContextKey).(uint)).Error; err != nil {
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	// Sessions started before CSRF protection get their token on first request
+	if session.CSRFToken == "" {
+		token, err := newCSRFToken()
+		if err != nil {
+			http.Error(w, "could not generate token", http.StatusInternalServerError)
+			return
+		}
+		if err := database.DB.Model(&session).Update("csrf_token", token).Error; err != nil {
+			http.Error(w, "database error", http.StatusInternalServerError)
+			return
+		}
+		session.CSRFToken = token
+	}
+
+	setCSRFToken(w, session)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"csrf_token": session.CSRFToken})
+}
@@ -1,6 +1,8 @@
 package jwtLogic
 
 import (
+	"backend/audit"
+	"backend/authorization"
 	"backend/database"
 	"backend/models"
 	"encoding/json"
@@ -11,19 +13,15 @@ import (
 	"strings"
 	"time"
 
-	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
 
-	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
-// @TODO change
-var JwtKey = []byte("TODO_CHANGE_SECRET")
-
 const UserContextKey string = "userAddress"
 
 // GenerateToken sets the JWT in an HTTP-only cookie
+// The request carries a signed EIP-4361 message using a nonce from GET /auth/nonce.
 func GenerateToken(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		UserAddress string `json:"userAddress"`
@@ -36,42 +34,46 @@ func GenerateToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.UserAddress == "" || req.Message == "" || req.Signature == "" {
+	if req.Message == "" || req.Signature == "" {
 		http.Error(w, "missing fields", http.StatusBadRequest)
 		return
 	}
 
-	// @TODO check timestamp of the message
-	msg := []byte("\x19Ethereum Signed Message:\n" +
-		strconv.Itoa(len(req.Message)) + req.Message)
-
-	hash := crypto.Keccak256Hash(msg)
-
-	sig := common.FromHex(req.Signature)
-	if len(sig) != 65 {
-		http.Error(w, "invalid signature length", http.StatusBadRequest)
+	siwe, err := ParseSiweMessage(req.Message)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	if sig[64] >= 27 {
-		sig[64] -= 27
+	if err := siwe.Validate(SiweDomain(), time.Now()); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
 	}
-
-	pubKey, err := crypto.SigToPub(hash.Bytes(), sig)
-	if err != nil {
-		http.Error(w, "invalid signature", http.StatusUnauthorized)
+	if req.UserAddress == "" {
+		req.UserAddress = siwe.Address.Hex()
+	} else if !strings.EqualFold(siwe.Address.Hex(), req.UserAddress) {
+		http.Error(w, "message is signed for another address", http.StatusUnauthorized)
 		return
 	}
 
-	recoveredAddr := crypto.PubkeyToAddress(*pubKey)
-	if !strings.EqualFold(recoveredAddr.Hex(), req.UserAddress) {
-		http.Error(w, "signature does not match address", http.StatusUnauthorized)
+	msg := []byte("\x19Ethereum Signed Message:\n" +
+		strconv.Itoa(len(req.Message)) + req.Message)
+
+	hash := crypto.Keccak256Hash(msg)
+
+	// Smart contract wallets are checked on the chain the message names
+	if err := authorization.VerifyAccountSignature(hash, req.Signature, siwe.Address.Hex(), siwe.ChainID); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
 
-	claims := jwt.MapClaims{
-		"userAddress": req.UserAddress,
-		"exp":         time.Now().Add(15 * time.Minute).Unix(),
+	// Only a correctly signed message uses up its nonce, so it cannot be replayed
+	if err := consumeNonce(siwe.Nonce, siwe.Address); err != nil {
+		if errors.Is(err, ErrInvalidNonce) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
 	}
 
 	var existingUser models.User
@@ -85,27 +87,25 @@ func GenerateToken(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "could not create user", http.StatusInternalServerError)
 			return
 		}
+		existingUser = user
 	} else if err != nil {
 		http.Error(w, "database error", http.StatusInternalServerError)
 		return
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenStr, err := token.SignedString(JwtKey)
+	session, err := startSession(w, r, existingUser, req.UserAddress)
 	if err != nil {
 		http.Error(w, "could not generate token", http.StatusInternalServerError)
 		return
 	}
 
-	// Set HTTP-only cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:    "token",
-		Value:   tokenStr,
-		Path:    "/",
-		Expires: time.Now().Add(15 * time.Minute),
-		// Secure:   true,
-		HttpOnly: true,
-		SameSite: http.SameSiteLaxMode,
+	audit.Record(r, audit.Entry{
+		UserID:     existingUser.ID,
+		Actor:      req.UserAddress,
+		Action:     audit.ActionTokenIssue,
+		TargetType: audit.TargetSession,
+		TargetID:   session.ID,
+		After:      map[string]interface{}{"expires_at": session.ExpiresAt},
 	})
 
 	w.WriteHeader(http.StatusOK)
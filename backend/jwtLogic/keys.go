@@ -0,0 +1,288 @@
+package jwtLogic
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmES256 = "ES256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// minSecretLength is the shortest HS256 secret accepted, in bytes
+const minSecretLength = 32
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// Key is one JWT key identified by its kid; verify-only keys have no signKey
+type Key struct {
+	ID        string
+	Algorithm string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// KeySet holds every key tokens are accepted from and the one new tokens are signed with
+type KeySet struct {
+	Signing *Key
+	keys    map[string]*Key
+}
+
+// Keys is the key set used to issue and verify tokens, loaded by LoadKeys
+var Keys *KeySet
+
+// KeyConfig is one entry of the JWT_KEYS_FILE configuration. HS256 keys take
+// a secret; ES256 and EdDSA keys take a PEM private key, or only a public key
+// for retired keys whose tokens are still accepted.
+type KeyConfig struct {
+	ID             string `json:"kid"`
+	Algorithm      string `json:"alg"`
+	Secret         string `json:"secret,omitempty"`
+	PrivateKeyFile string `json:"private_key_file,omitempty"`
+	PublicKeyFile  string `json:"public_key_file,omitempty"`
+}
+
+// KeysConfig is the JWT_KEYS_FILE format; SigningKeyID defaults to the first key
+type KeysConfig struct {
+	SigningKeyID string      `json:"signing_kid"`
+	Keys         []KeyConfig `json:"keys"`
+}
+
+// LoadKeys sets Keys from JWT_KEYS_FILE, or a single HS256 key from JWT_SECRET.
+// Without either a random secret is generated, so tokens do not survive a restart.
+func LoadKeys() error {
+	var err error
+	switch {
+	case os.Getenv("JWT_KEYS_FILE") != "":
+		Keys, err = KeysFromFile(os.Getenv("JWT_KEYS_FILE"))
+	case os.Getenv("JWT_SECRET") != "":
+		kid := os.Getenv("JWT_KID")
+		if kid == "" {
+			kid = "default"
+		}
+		Keys, err = NewKeySet(KeysConfig{Keys: []KeyConfig{{ID: kid, Algorithm: AlgorithmHS256, Secret: os.Getenv("JWT_SECRET")}}})
+	default:
+		log.Println("JWT_KEYS_FILE and JWT_SECRET are not set, signing tokens with a random key")
+		secret := make([]byte, minSecretLength)
+		rand.Read(secret)
+		Keys, err = NewKeySet(KeysConfig{Keys: []KeyConfig{{ID: "ephemeral", Algorithm: AlgorithmHS256, Secret: string(secret)}}})
+	}
+	return err
+}
+
+// KeysFromFile reads a KeysConfig from a JSON file
+func KeysFromFile(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read JWT keys file: %w", err)
+	}
+
+	var config KeysConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid JWT keys file: %w", err)
+	}
+	return NewKeySet(config)
+}
+
+// NewKeySet loads every configured key and picks the signing one
+func NewKeySet(config KeysConfig) (*KeySet, error) {
+	if len(config.Keys) == 0 {
+		return nil, errors.New("no JWT keys configured")
+	}
+
+	set := &KeySet{keys: make(map[string]*Key, len(config.Keys))}
+	for _, c := range config.Keys {
+		if c.ID == "" {
+			return nil, errors.New("JWT key without kid")
+		}
+		if _, ok := set.keys[c.ID]; ok {
+			return nil, fmt.Errorf("duplicate JWT kid %q", c.ID)
+		}
+		key, err := loadKey(c)
+		if err != nil {
+			return nil, fmt.Errorf("JWT key %q: %w", c.ID, err)
+		}
+		set.keys[c.ID] = key
+	}
+
+	signingID := config.SigningKeyID
+	if signingID == "" {
+		signingID = config.Keys[0].ID
+	}
+	set.Signing = set.keys[signingID]
+	if set.Signing == nil {
+		return nil, fmt.Errorf("signing kid %q is not configured", signingID)
+	}
+	if set.Signing.signKey == nil {
+		return nil, fmt.Errorf("signing kid %q has no private key", signingID)
+	}
+	return set, nil
+}
+
+func loadKey(c KeyConfig) (*Key, error) {
+	key := &Key{ID: c.ID, Algorithm: c.Algorithm}
+
+	switch c.Algorithm {
+	case AlgorithmHS256:
+		if len(c.Secret) < minSecretLength {
+			return nil, fmt.Errorf("secret must be at least %d bytes", minSecretLength)
+		}
+		key.method = jwt.SigningMethodHS256
+		key.signKey = []byte(c.Secret)
+		key.verifyKey = []byte(c.Secret)
+		return key, nil
+	case AlgorithmES256:
+		key.method = jwt.SigningMethodES256
+	case AlgorithmEdDSA:
+		key.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported alg %q", c.Algorithm)
+	}
+
+	switch {
+	case c.PrivateKeyFile != "":
+		block, err := readPEM(c.PrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil && c.Algorithm == AlgorithmES256 {
+			private, err = x509.ParseECPrivateKey(block.Bytes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		key.signKey = private
+		switch k := private.(type) {
+		case *ecdsa.PrivateKey:
+			key.verifyKey = &k.PublicKey
+		case ed25519.PrivateKey:
+			key.verifyKey = k.Public()
+		}
+	case c.PublicKeyFile != "":
+		block, err := readPEM(c.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		key.verifyKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid public key: %w", err)
+		}
+	default:
+		return nil, errors.New("private_key_file or public_key_file is required")
+	}
+
+	switch k := key.verifyKey.(type) {
+	case *ecdsa.PublicKey:
+		if c.Algorithm != AlgorithmES256 || k.Curve != elliptic.P256() {
+			return nil, errors.New("key does not match ES256")
+		}
+	case ed25519.PublicKey:
+		if c.Algorithm != AlgorithmEdDSA {
+			return nil, errors.New("key does not match EdDSA")
+		}
+	default:
+		return nil, fmt.Errorf("key does not match %s", c.Algorithm)
+	}
+	return key, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block in %s", path)
+	}
+	return block, nil
+}
+
+// Sign issues a token with the signing key, naming it in the kid header
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(s.Signing.method, claims)
+	token.Header["kid"] = s.Signing.ID
+	return token.SignedString(s.Signing.signKey)
+}
+
+// Keyfunc picks the verification key named by the token's kid, refusing any
+// other algorithm than the one configured for that key
+func (s *KeySet) Keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, jwt.ErrSignatureInvalid
+	}
+	return key.verifyKey, nil
+}
+
+// JWK is the public part of an asymmetric key as published in the JWKS
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y,omitempty"`
+	ID        string `json:"kid"`
+	Algorithm string `json:"alg"`
+	Use       string `json:"use"`
+}
+
+// PublicJWKs lists the asymmetric keys; HS256 secrets are never published
+func (s *KeySet) PublicJWKs() []JWK {
+	jwks := []JWK{}
+	for _, key := range s.keys {
+		jwk := JWK{ID: key.ID, Algorithm: key.Algorithm, Use: "sig"}
+		switch k := key.verifyKey.(type) {
+		case *ecdsa.PublicKey:
+			ecdhKey, err := k.ECDH()
+			if err != nil {
+				log.Printf("could not encode JWT key %q: %v", key.ID, err)
+				continue
+			}
+			// Uncompressed point: 0x04 || X || Y
+			point := ecdhKey.Bytes()
+			jwk.KeyType, jwk.Curve = "EC", "P-256"
+			jwk.X = base64.RawURLEncoding.EncodeToString(point[1:33])
+			jwk.Y = base64.RawURLEncoding.EncodeToString(point[33:])
+		case ed25519.PublicKey:
+			jwk.KeyType, jwk.Curve = "OKP", "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(k)
+		default:
+			continue
+		}
+		jwks = append(jwks, jwk)
+	}
+	sort.Slice(jwks, func(i, j int) bool { return jwks[i].ID < jwks[j].ID })
+	return jwks
+}
+
+// GetJWKS handles GET /.well-known/jwks.json
+// Other services verify this backend's ES256 and EdDSA tokens with these keys.
+func GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": Keys.PublicJWKs(),
+	})
+}
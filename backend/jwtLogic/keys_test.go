@@ -0,0 +1,139 @@
+package jwtLogic
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writePEM writes der as a PEM block of the given type and returns its path
+func writePEM(t *testing.T, blockType string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), strings.ReplaceAll(strings.ToLower(blockType), " ", "-")+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// ecKeyFiles generates a P-256 key and returns its private and public key files
+func ecKeyFiles(t *testing.T) (string, string) {
+	t.Helper()
+	private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateDER, _ := x509.MarshalECPrivateKey(private)
+	publicDER, _ := x509.MarshalPKIXPublicKey(&private.PublicKey)
+	return writePEM(t, "EC PRIVATE KEY", privateDER), writePEM(t, "PUBLIC KEY", publicDER)
+}
+
+func TestKeyRotation(t *testing.T) {
+	oldPrivate, oldPublic := ecKeyFiles(t)
+	_, edPrivate, _ := ed25519.GenerateKey(rand.Reader)
+	edDER, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+	newPrivate := writePEM(t, "PRIVATE KEY", edDER)
+
+	before, err := NewKeySet(KeysConfig{Keys: []KeyConfig{{ID: "2025", Algorithm: AlgorithmES256, PrivateKeyFile: oldPrivate}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldToken, err := before.Sign(jwt.MapClaims{"userAddress": sessionTestAddress})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The new key signs, the old one only verifies
+	after, err := NewKeySet(KeysConfig{
+		SigningKeyID: "2026",
+		Keys: []KeyConfig{
+			{ID: "2025", Algorithm: AlgorithmES256, PublicKeyFile: oldPublic},
+			{ID: "2026", Algorithm: AlgorithmEdDSA, PrivateKeyFile: newPrivate},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	newToken, err := after.Sign(jwt.MapClaims{"userAddress": sessionTestAddress})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tokenStr := range map[string]string{"old": oldToken, "new": newToken} {
+		if _, err := jwt.Parse(tokenStr, after.Keyfunc); err != nil {
+			t.Errorf("%s token rejected after rotation: %v", name, err)
+		}
+	}
+	if token, _, _ := jwt.NewParser().ParseUnverified(newToken, jwt.MapClaims{}); token.Header["kid"] != "2026" || token.Method.Alg() != AlgorithmEdDSA {
+		t.Errorf("new token has kid %v and alg %s, want 2026 and EdDSA", token.Header["kid"], token.Method.Alg())
+	}
+	if _, err := jwt.Parse(newToken, before.Keyfunc); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token of an unknown kid: err = %v, want %v", err, ErrUnknownKey)
+	}
+
+	jwks := after.PublicJWKs()
+	if len(jwks) != 2 || jwks[0].ID != "2025" || jwks[0].KeyType != "EC" || jwks[1].ID != "2026" || jwks[1].KeyType != "OKP" {
+		t.Errorf("PublicJWKs = %+v, want the EC and OKP keys", jwks)
+	}
+}
+
+func TestKeyfuncRefusesOtherAlgorithms(t *testing.T) {
+	_, public := ecKeyFiles(t)
+	set, err := NewKeySet(KeysConfig{
+		SigningKeyID: "hmac",
+		Keys: []KeyConfig{
+			{ID: "hmac", Algorithm: AlgorithmHS256, Secret: strings.Repeat("s", minSecretLength)},
+			{ID: "ec", Algorithm: AlgorithmES256, PublicKeyFile: public},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An HS256 token naming the EC key must not be checked with its public key as the secret
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{})
+	forged.Header["kid"] = "ec"
+	publicPEM, _ := os.ReadFile(public)
+	tokenStr, _ := forged.SignedString(publicPEM)
+	if _, err := jwt.Parse(tokenStr, set.Keyfunc); err == nil {
+		t.Error("HS256 token naming an ES256 key was accepted")
+	}
+
+	if jwks := set.PublicJWKs(); len(jwks) != 1 || jwks[0].ID != "ec" {
+		t.Errorf("PublicJWKs = %+v, want only the EC key", jwks)
+	}
+}
+
+func TestNewKeySetErrors(t *testing.T) {
+	_, public := ecKeyFiles(t)
+	secret := strings.Repeat("s", minSecretLength)
+
+	tests := []struct {
+		name   string
+		config KeysConfig
+	}{
+		{"no keys", KeysConfig{}},
+		{"short secret", KeysConfig{Keys: []KeyConfig{{ID: "a", Algorithm: AlgorithmHS256, Secret: "short"}}}},
+		{"missing kid", KeysConfig{Keys: []KeyConfig{{Algorithm: AlgorithmHS256, Secret: secret}}}},
+		{"duplicate kid", KeysConfig{Keys: []KeyConfig{{ID: "a", Algorithm: AlgorithmHS256, Secret: secret}, {ID: "a", Algorithm: AlgorithmHS256, Secret: secret}}}},
+		{"unsupported alg", KeysConfig{Keys: []KeyConfig{{ID: "a", Algorithm: "RS256", Secret: secret}}}},
+		{"key of another alg", KeysConfig{Keys: []KeyConfig{{ID: "a", Algorithm: AlgorithmEdDSA, PublicKeyFile: public}}}},
+		{"unknown signing kid", KeysConfig{SigningKeyID: "b", Keys: []KeyConfig{{ID: "a", Algorithm: AlgorithmHS256, Secret: secret}}}},
+		{"signing key without private key", KeysConfig{Keys: []KeyConfig{{ID: "a", Algorithm: AlgorithmES256, PublicKeyFile: public}}}},
+	}
+	for _, tt := range tests {
+		if _, err := NewKeySet(tt.config); err == nil {
+			t.Errorf("%s: NewKeySet succeeded", tt.name)
+		}
+	}
+}
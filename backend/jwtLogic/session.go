@@ -0,0 +1,348 @@
+package jwtLogic
+
+import (
+	"backend/audit"
+	"backend/database"
+	"backend/models"
+	"backend/proxy"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	AccessTokenLifetime  = 15 * time.Minute
+	RefreshTokenLifetime = 30 * 24 * time.Hour
+)
+
+const (
+	accessCookieName  = "token"
+	refreshCookieName = "refresh_token"
+	// The refresh token is only sent to the /auth routes
+	refreshCookiePath = "/auth"
+)
+
+// lastUsedResolution limits how often a session's last use is written
+const lastUsedResolution = time.Minute
+
+const SessionContextKey string = "sessionID"
+
+const (
+	RevokedReasonLogout = "logout"
+	RevokedReasonUser   = "revoked"
+	RevokedReasonReuse  = "refresh_token_reuse"
+)
+
+var (
+	ErrSessionInactive     = errors.New("session is revoked or expired")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrInvalidToken        = errors.New("invalid token")
+)
+
+// ParseToken validates an access token and returns the address and session it was issued for
+func ParseToken(tokenStr string) (string, uint, error) {
+	token, err := jwt.Parse(tokenStr, Keys.Keyfunc)
+	if err != nil || !token.Valid {
+		return "", 0, ErrInvalidToken
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	address, ok := claims["userAddress"].(string)
+	if !ok {
+		return "", 0, ErrInvalidToken
+	}
+	sid, ok := claims["sid"].(float64)
+	if !ok {
+		return "", 0, ErrInvalidToken
+	}
+	return address, uint(sid), nil
+}
+
+// TouchSession fails if the session was revoked or expired, otherwise records its use
+func TouchSession(sessionID uint) (models.Session, error) {
+	var session models.Session
+	if err := database.DB.First(&session, sessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return session, ErrSessionInactive
+		}
+		return session, err
+	}
+
+	now := time.Now()
+	if !session.Active(now) {
+		return session, ErrSessionInactive
+	}
+	if now.Sub(session.LastUsedAt) > lastUsedResolution {
+		if err := database.DB.Model(&session).Update("last_used_at", now).Error; err != nil {
+			log.Printf("could not update session %d: %v", session.ID, err)
+		}
+	}
+	return session, nil
+}
+
+// RevokeSession ends the session; its access and refresh tokens stop working immediately
+func RevokeSession(sessionID uint, reason string) error {
+	return database.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{"revoked_at": time.Now(), "revoked_reason": reason}).Error
+}
+
+// startSession records a new session for the request's device, sets both token
+// cookies and hands out the session's CSRF token
+func startSession(w http.ResponseWriter, r *http.Request, user models.User, address string) (models.Session, error) {
+	csrfToken, err := newCSRFToken()
+	if err != nil {
+		return models.Session{}, err
+	}
+
+	userAgent := r.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.ID,
+		UserAgent:  userAgent,
+		IPAddress:  proxy.ClientIP(r),
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(RefreshTokenLifetime),
+		CSRFToken:  csrfToken,
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		return session, err
+	}
+
+	if err := issueRefreshToken(w, &session); err != nil {
+		return session, err
+	}
+	if err := issueAccessToken(w, address, session.ID); err != nil {
+		return session, err
+	}
+	setCSRFToken(w, session)
+	return session, nil
+}
+
+// issueAccessToken signs a short-lived JWT for the session and sets it in an HTTP-only cookie
+func issueAccessToken(w http.ResponseWriter, address string, sessionID uint) error {
+	expiresAt := time.Now().Add(AccessTokenLifetime)
+	claims := jwt.MapClaims{
+		"userAddress": address,
+		"sid":         sessionID,
+		"exp":         expiresAt.Unix(),
+	}
+
+	tokenStr, err := Keys.Sign(claims)
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:    accessCookieName,
+		Value:   tokenStr,
+		Path:    "/",
+		Expires: expiresAt,
+		// Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// issueRefreshToken stores a new refresh token of the session, extends the
+// session and sets the token in an HTTP-only cookie
+func issueRefreshToken(w http.ResponseWriter, session *models.Session) error {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(raw)
+
+	expiresAt := time.Now().Add(RefreshTokenLifetime)
+	refreshToken := models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: expiresAt,
+	}
+	if err := database.DB.Create(&refreshToken).Error; err != nil {
+		return err
+	}
+
+	session.ExpiresAt = expiresAt
+	if err := database.DB.Model(session).Update("expires_at", expiresAt).Error; err != nil {
+		return err
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:    refreshCookieName,
+		Value:   token,
+		Path:    refreshCookiePath,
+		Expires: expiresAt,
+		// Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func clearCookies(w http.ResponseWriter) {
+	for _, c := range []struct {
+		name, path string
+		httpOnly   bool
+	}{
+		{accessCookieName, "/", true},
+		{refreshCookieName, refreshCookiePath, true},
+		{csrfCookieName, "/", false},
+	} {
+		http.SetCookie(w, &http.Cookie{
+			Name:     c.name,
+			Value:    "",
+			Path:     c.path,
+			MaxAge:   -1,
+			HttpOnly: c.httpOnly,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+}
+
+// RefreshSession handles POST /auth/refresh
+// The refresh token cookie is exchanged for a new access token and a new
+// refresh token. Presenting an already used refresh token revokes the session.
+func RefreshSession(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(refreshCookieName)
+	if err != nil {
+		http.Error(w, "missing refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	var refreshToken models.RefreshToken
+	err = database.DB.Preload("Session.User").
+		Where("token_hash = ?", hashToken(cookie.Value)).
+		First(&refreshToken).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		clearCookies(w)
+		http.Error(w, ErrInvalidRefreshToken.Error(), http.StatusUnauthorized)
+		return
+	} else if err != nil {
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	session := refreshToken.Session
+	now := time.Now()
+
+	// Mark the token used, unless another request already did
+	result := database.DB.Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", refreshToken.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	if result.RowsAffected != 1 {
+		if session.Active(now) {
+			if err := RevokeSession(session.ID, RevokedReasonReuse); err != nil {
+				log.Printf("could not revoke session %d: %v", session.ID, err)
+			}
+			audit.Record(r, audit.Entry{
+				UserID:     session.UserID,
+				Actor:      audit.ActorSystem,
+				Action:     audit.ActionSessionRevoke,
+				TargetType: audit.TargetSession,
+				TargetID:   session.ID,
+				After:      map[string]interface{}{"reason": RevokedReasonReuse},
+			})
+		}
+		clearCookies(w)
+		http.Error(w, "refresh token already used, session revoked", http.StatusUnauthorized)
+		return
+	}
+
+	if !session.Active(now) || now.After(refreshToken.ExpiresAt) {
+		clearCookies(w)
+		http.Error(w, ErrSessionInactive.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := issueRefreshToken(w, &session); err != nil {
+		http.Error(w, "could not generate token", http.StatusInternalServerError)
+		return
+	}
+	if err := issueAccessToken(w, session.User.EthereumAddress, session.ID); err != nil {
+		http.Error(w, "could not generate token", http.StatusInternalServerError)
+		return
+	}
+	if err := database.DB.Model(&session).Update("last_used_at", now).Error; err != nil {
+		log.Printf("could not update session %d: %v", session.ID, err)
+	}
+	// The CSRF token lives as long as the session; refreshing hands it out again
+	setCSRFToken(w, session)
+
+	audit.Record(r, audit.Entry{
+		UserID:     session.UserID,
+		Actor:      session.User.EthereumAddress,
+		Action:     audit.ActionTokenRefresh,
+		TargetType: audit.TargetSession,
+		TargetID:   session.ID,
+		After:      map[string]interface{}{"expires_at": session.ExpiresAt},
+	})
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "token refreshed")
+}
+
+// Logout handles POST /auth/logout
+// Revokes the session of the refresh or access token cookie and clears both cookies.
+func Logout(w http.ResponseWriter, r *http.Request) {
+	var session models.Session
+	found := false
+
+	if cookie, err := r.Cookie(refreshCookieName); err == nil {
+		var refreshToken models.RefreshToken
+		if err := database.DB.Preload("Session.User").Where("token_hash = ?", hashToken(cookie.Value)).First(&refreshToken).Error; err == nil {
+			session, found = refreshToken.Session, true
+		}
+	}
+	if !found {
+		if cookie, err := r.Cookie(accessCookieName); err == nil {
+			if _, sessionID, err := ParseToken(cookie.Value); err == nil {
+				found = database.DB.Preload("User").First(&session, sessionID).Error == nil
+			}
+		}
+	}
+
+	clearCookies(w)
+	if !found {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := RevokeSession(session.ID, RevokedReasonLogout); err != nil {
+		http.Error(w, "database error", http.StatusInternalServerError)
+		return
+	}
+	if session.RevokedAt == nil {
+		audit.Record(r, audit.Entry{
+			UserID:     session.UserID,
+			Actor:      session.User.EthereumAddress,
+			Action:     audit.ActionSessionRevoke,
+			TargetType: audit.TargetSession,
+			TargetID:   session.ID,
+			After:      map[string]interface{}{"reason": RevokedReasonLogout},
+		})
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,140 @@
+package jwtLogic
+
+import (
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/testdb"
+)
+
+const sessionTestAddress = "0x6969174FD72466430a46e18234D0b530c9FD5f49"
+
+// useSessionKeys signs tokens with a test key until the test ends
+func useSessionKeys(t *testing.T) {
+	t.Helper()
+	keys, err := NewKeySet(KeysConfig{Keys: []KeyConfig{{ID: "test", Algorithm: AlgorithmHS256, Secret: strings.Repeat("s", minSecretLength)}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := Keys
+	Keys = keys
+	t.Cleanup(func() { Keys = previous })
+}
+
+// useRefreshToken serves one refresh token, rawToken, of active session 3
+func useRefreshToken(t *testing.T, rawToken string) *testdb.DB {
+	t.Helper()
+	expires := time.Now().Add(time.Hour)
+	return testdb.Use(t, func(query string, args []driver.Value) (testdb.Rows, error) {
+		switch {
+		case strings.Contains(query, "FROM `refresh_tokens`"):
+			rows := testdb.Rows{Columns: []string{"id", "session_id", "token_hash", "expires_at"}}
+			if len(args) > 0 && args[0] == hashToken(rawToken) {
+				rows.Values = append(rows.Values, []driver.Value{int64(9), int64(3), hashToken(rawToken), expires})
+			}
+			return rows, nil
+		case strings.Contains(query, "FROM `sessions`"):
+			return testdb.Rows{
+				Columns: []string{"id", "user_id", "expires_at", "last_used_at", "csrf_token"},
+				Values:  [][]driver.Value{{int64(3), int64(7), expires, time.Now(), "csrf"}},
+			}, nil
+		case strings.Contains(query, "FROM `users`"):
+			return testdb.Rows{Columns: []string{"id", "ethereum_address"}, Values: [][]driver.Value{{int64(7), sessionTestAddress}}}, nil
+		}
+		return testdb.Rows{}, errors.New("unexpected query: " + query)
+	})
+}
+
+func refreshRequest(rawToken string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	r.AddCookie(&http.Cookie{Name: refreshCookieName, Value: rawToken})
+	return r
+}
+
+// cookies indexes the cookies set on the response by name
+func cookies(w *httptest.ResponseRecorder) map[string]*http.Cookie {
+	set := map[string]*http.Cookie{}
+	for _, c := range w.Result().Cookies() {
+		set[c.Name] = c
+	}
+	return set
+}
+
+func TestRefreshSessionRotatesTheToken(t *testing.T) {
+	useSessionKeys(t)
+	db := useRefreshToken(t, "old-token")
+
+	w := httptest.NewRecorder()
+	RefreshSession(w, refreshRequest("old-token"))
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d (%s), want %d", w.Code, strings.TrimSpace(w.Body.String()), http.StatusOK)
+	}
+	set := cookies(w)
+	if set[refreshCookieName] == nil || set[refreshCookieName].Value == "old-token" || set[refreshCookieName].Path != refreshCookiePath {
+		t.Errorf("refresh cookie = %+v, want a new token on %s", set[refreshCookieName], refreshCookiePath)
+	}
+	if address, sessionID, err := ParseToken(set[accessCookieName].Value); err != nil || address != sessionTestAddress || sessionID != 3 {
+		t.Errorf("access token is for %s, session %d (%v); want %s, session 3", address, sessionID, err, sessionTestAddress)
+	}
+	if w.Header().Get(CSRFHeader) != "csrf" {
+		t.Errorf("%s = %q, want the session's token", CSRFHeader, w.Header().Get(CSRFHeader))
+	}
+	if len(db.Execs("UPDATE `refresh_tokens` SET `used_at`")) != 1 || len(db.Execs("INSERT INTO `refresh_tokens`")) != 1 {
+		t.Error("old token was not marked used and replaced")
+	}
+}
+
+func TestRefreshSessionReuseRevokesTheSession(t *testing.T) {
+	useSessionKeys(t)
+	db := useRefreshToken(t, "used-token")
+	// Another request already used the token
+	db.Affected = func(query string, _ []driver.Value) int64 {
+		if strings.Contains(query, "UPDATE `refresh_tokens` SET `used_at`") {
+			return 0
+		}
+		return 1
+	}
+
+	w := httptest.NewRecorder()
+	RefreshSession(w, refreshRequest("used-token"))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	revoked := false
+	for _, s := range db.Execs("UPDATE `sessions`") {
+		for _, arg := range s.Args {
+			revoked = revoked || arg == RevokedReasonReuse
+		}
+	}
+	if !revoked {
+		t.Error("reusing a refresh token did not revoke its session")
+	}
+	if len(db.Execs("INSERT INTO `refresh_tokens`")) != 0 {
+		t.Error("reused refresh token was exchanged for a new one")
+	}
+	if c := cookies(w)[refreshCookieName]; c == nil || c.MaxAge >= 0 {
+		t.Errorf("refresh cookie = %+v, want it cleared", c)
+	}
+}
+
+func TestRefreshSessionUnknownToken(t *testing.T) {
+	useSessionKeys(t)
+	db := useRefreshToken(t, "issued-token")
+
+	w := httptest.NewRecorder()
+	RefreshSession(w, refreshRequest("forged-token"))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+	if len(db.Execs("")) != 0 {
+		t.Error("unknown refresh token changed the database")
+	}
+}
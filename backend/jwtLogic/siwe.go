@@ -0,0 +1,227 @@
+package jwtLogic
+
+import (
+	"backend/chain"
+	"backend/database"
+	"backend/models"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// NonceLifetime is how long an issued nonce can be used to sign in
+const NonceLifetime = 10 * time.Minute
+
+// clockSkew tolerates small differences between the wallet's and the server's clocks
+const clockSkew = time.Minute
+
+const siweHeaderSuffix = " wants you to sign in with your Ethereum account:"
+
+var (
+	ErrMalformedMessage = errors.New("malformed sign-in message")
+	ErrWrongDomain      = errors.New("sign-in message is for another domain")
+	ErrWrongURI         = errors.New("sign-in message URI does not match the domain")
+	ErrWrongVersion     = errors.New("unsupported sign-in message version")
+	ErrMessageExpired   = errors.New("sign-in message has expired")
+	ErrMessageNotYet    = errors.New("sign-in message is not valid yet")
+	ErrUnsupportedChain = errors.New("sign-in message is for an unsupported chain")
+	ErrInvalidNonce     = errors.New("nonce is invalid, expired, already used or issued to another address")
+)
+
+var nonceRegexp = regexp.MustCompile(`^[a-zA-Z0-9]{8,}$`)
+
+// SiweMessage is a parsed EIP-4361 message
+type SiweMessage struct {
+	Domain         string
+	Address        common.Address
+	Statement      string
+	URI            string
+	Version        string
+	ChainID        uint64
+	Nonce          string
+	IssuedAt       time.Time
+	ExpirationTime *time.Time
+	NotBefore      *time.Time
+	RequestID      string
+	Resources      []string
+}
+
+// SiweDomain is the domain sign-in messages must be addressed to, from SIWE_DOMAIN
+func SiweDomain() string {
+	if domain := os.Getenv("SIWE_DOMAIN"); domain != "" {
+		return domain
+	}
+	return "localhost:3000"
+}
+
+// ParseSiweMessage parses the EIP-4361 text representation
+func ParseSiweMessage(message string) (*SiweMessage, error) {
+	lines := strings.Split(strings.ReplaceAll(message, "\r\n", "\n"), "\n")
+	if len(lines) < 2 || !strings.HasSuffix(lines[0], siweHeaderSuffix) {
+		return nil, ErrMalformedMessage
+	}
+
+	var m SiweMessage
+	m.Domain = strings.TrimSuffix(lines[0], siweHeaderSuffix)
+	if i := strings.Index(m.Domain, "://"); i >= 0 {
+		m.Domain = m.Domain[i+3:]
+	}
+	if m.Domain == "" || !common.IsHexAddress(lines[1]) || !strings.HasPrefix(lines[1], "0x") {
+		return nil, ErrMalformedMessage
+	}
+	m.Address = common.HexToAddress(lines[1])
+
+	// An optional statement sits between the address and the fields, surrounded by empty lines
+	i := 2
+	for ; i < len(lines) && !strings.HasPrefix(lines[i], "URI: "); i++ {
+		if lines[i] == "" {
+			continue
+		}
+		if m.Statement != "" {
+			return nil, ErrMalformedMessage
+		}
+		m.Statement = lines[i]
+	}
+
+	fields := make(map[string]string)
+	for ; i < len(lines); i++ {
+		if lines[i] == "Resources:" {
+			for _, line := range lines[i+1:] {
+				if !strings.HasPrefix(line, "- ") {
+					return nil, ErrMalformedMessage
+				}
+				m.Resources = append(m.Resources, strings.TrimPrefix(line, "- "))
+			}
+			break
+		}
+		key, value, ok := strings.Cut(lines[i], ": ")
+		if !ok {
+			return nil, ErrMalformedMessage
+		}
+		if _, seen := fields[key]; seen {
+			return nil, ErrMalformedMessage
+		}
+		fields[key] = value
+	}
+
+	var err error
+	m.URI = fields["URI"]
+	m.Version = fields["Version"]
+	m.Nonce = fields["Nonce"]
+	m.RequestID = fields["Request ID"]
+	if m.URI == "" || m.Version == "" || !nonceRegexp.MatchString(m.Nonce) {
+		return nil, ErrMalformedMessage
+	}
+	if m.ChainID, err = strconv.ParseUint(fields["Chain ID"], 10, 64); err != nil {
+		return nil, ErrMalformedMessage
+	}
+	if m.IssuedAt, err = time.Parse(time.RFC3339, fields["Issued At"]); err != nil {
+		return nil, ErrMalformedMessage
+	}
+	if v, ok := fields["Expiration Time"]; ok {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, ErrMalformedMessage
+		}
+		m.ExpirationTime = &t
+	}
+	if v, ok := fields["Not Before"]; ok {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, ErrMalformedMessage
+		}
+		m.NotBefore = &t
+	}
+
+	return &m, nil
+}
+
+// Validate checks the message is addressed to domain, on a supported chain
+// and valid at now. The nonce is checked separately by consumeNonce.
+func (m *SiweMessage) Validate(domain string, now time.Time) error {
+	if m.Domain != domain {
+		return ErrWrongDomain
+	}
+	uri, err := url.Parse(m.URI)
+	if err != nil || uri.Host != domain {
+		return ErrWrongURI
+	}
+	if m.Version != "1" {
+		return ErrWrongVersion
+	}
+	if _, ok := chain.Networks[m.ChainID]; !ok {
+		return ErrUnsupportedChain
+	}
+	if m.IssuedAt.After(now.Add(clockSkew)) {
+		return ErrMessageNotYet
+	}
+	if m.NotBefore != nil && m.NotBefore.After(now.Add(clockSkew)) {
+		return ErrMessageNotYet
+	}
+	if m.ExpirationTime != nil && !m.ExpirationTime.After(now) {
+		return ErrMessageExpired
+	}
+	if now.Sub(m.IssuedAt) > NonceLifetime {
+		return ErrMessageExpired
+	}
+	return nil
+}
+
+// consumeNonce marks a nonce issued to address, unexpired, as used; it fails
+// if another sign-in got there first
+func consumeNonce(nonce string, address common.Address) error {
+	now := time.Now()
+	result := database.DB.Model(&models.AuthNonce{}).
+		Where("nonce = ? AND address = ? AND used_at IS NULL AND expires_at > ?", nonce, strings.ToLower(address.Hex()), now).
+		Update("used_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != 1 {
+		return ErrInvalidNonce
+	}
+	return nil
+}
+
+// GetNonce handles GET /auth/nonce?address=
+// Issues a single-use nonce to embed in the next sign-in message of address.
+func GetNonce(w http.ResponseWriter, r *http.Request) {
+	address := r.URL.Query().Get("address")
+	if !common.IsHexAddress(address) {
+		http.Error(w, "address is required", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	if err := database.DB.Where("expires_at < ?", now).Delete(&models.AuthNonce{}).Error; err != nil {
+		log.Printf("could not delete expired nonces: %v", err)
+	}
+
+	nonce := models.AuthNonce{
+		Address:   strings.ToLower(common.HexToAddress(address).Hex()),
+		Nonce:     rand.Text(),
+		ExpiresAt: now.Add(NonceLifetime),
+	}
+	if err := database.DB.Create(&nonce).Error; err != nil {
+		http.Error(w, "could not issue nonce", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"nonce":      nonce.Nonce,
+		"domain":     SiweDomain(),
+		"expires_at": nonce.ExpiresAt,
+	})
+}
@@ -0,0 +1,125 @@
+package jwtLogic
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const siweTestMessage = `localhost:3000 wants you to sign in with your Ethereum account:
+0x6969174FD72466430a46e18234D0b530c9FD5f49
+
+Sign in to GoPayments
+
+URI: http://localhost:3000
+Version: 1
+Chain ID: 8453
+Nonce: abcd1234efgh
+Issued At: 2026-03-01T10:00:00Z
+Expiration Time: 2026-03-01T10:05:00Z
+Resources:
+- https://example.com/terms`
+
+func TestParseSiweMessage(t *testing.T) {
+	m, err := ParseSiweMessage(siweTestMessage)
+	if err != nil {
+		t.Fatalf("ParseSiweMessage: %v", err)
+	}
+	if m.Domain != "localhost:3000" || m.URI != "http://localhost:3000" || m.Version != "1" || m.ChainID != 8453 || m.Nonce != "abcd1234efgh" {
+		t.Errorf("got %+v", m)
+	}
+	if m.Address != common.HexToAddress("0x6969174FD72466430a46e18234D0b530c9FD5f49") {
+		t.Errorf("Address = %s", m.Address)
+	}
+	if m.Statement != "Sign in to GoPayments" {
+		t.Errorf("Statement = %q", m.Statement)
+	}
+	if !m.IssuedAt.Equal(time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("IssuedAt = %s", m.IssuedAt)
+	}
+	if m.ExpirationTime == nil || !m.ExpirationTime.Equal(time.Date(2026, 3, 1, 10, 5, 0, 0, time.UTC)) {
+		t.Errorf("ExpirationTime = %v", m.ExpirationTime)
+	}
+	if m.NotBefore != nil {
+		t.Errorf("NotBefore = %v, want nil", m.NotBefore)
+	}
+	if len(m.Resources) != 1 || m.Resources[0] != "https://example.com/terms" {
+		t.Errorf("Resources = %v", m.Resources)
+	}
+}
+
+func TestParseSiweMessageMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{"empty", ""},
+		{"wrong header", strings.Replace(siweTestMessage, "wants you to sign in", "asks you to sign in", 1)},
+		{"bad address", strings.Replace(siweTestMessage, "0x6969174FD72466430a46e18234D0b530c9FD5f49", "0x6969", 1)},
+		{"two statements", strings.Replace(siweTestMessage, "Sign in to GoPayments", "Sign in\nto GoPayments", 1)},
+		{"missing URI", strings.Replace(siweTestMessage, "URI: http://localhost:3000\n", "", 1)},
+		{"short nonce", strings.Replace(siweTestMessage, "abcd1234efgh", "abc", 1)},
+		{"chain id", strings.Replace(siweTestMessage, "Chain ID: 8453", "Chain ID: base", 1)},
+		{"issued at", strings.Replace(siweTestMessage, "2026-03-01T10:00:00Z", "yesterday", 1)},
+		{"expiration time", strings.Replace(siweTestMessage, "2026-03-01T10:05:00Z", "soon", 1)},
+		{"duplicate field", strings.Replace(siweTestMessage, "Version: 1\n", "Version: 1\nVersion: 1\n", 1)},
+		{"field without value", strings.Replace(siweTestMessage, "Version: 1", "Version", 1)},
+		{"bad resource", siweTestMessage + "\nhttps://example.com/privacy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSiweMessage(tt.message); !errors.Is(err, ErrMalformedMessage) {
+				t.Errorf("got error %v, want %v", err, ErrMalformedMessage)
+			}
+		})
+	}
+}
+
+func TestSiweMessageValidate(t *testing.T) {
+	issuedAt := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	expires := issuedAt.Add(5 * time.Minute)
+	notBefore := issuedAt.Add(2 * time.Minute)
+	valid := SiweMessage{
+		Domain:         "localhost:3000",
+		URI:            "http://localhost:3000",
+		Version:        "1",
+		ChainID:        8453,
+		IssuedAt:       issuedAt,
+		ExpirationTime: &expires,
+	}
+
+	tests := []struct {
+		name   string
+		modify func(m *SiweMessage)
+		now    time.Time
+		want   error
+	}{
+		{name: "valid", now: issuedAt.Add(time.Minute)},
+		{name: "optimism", modify: func(m *SiweMessage) { m.ChainID = 10 }, now: issuedAt},
+		{name: "issued slightly ahead of the server clock", now: issuedAt.Add(-30 * time.Second)},
+		{name: "other domain", modify: func(m *SiweMessage) { m.Domain = "evil.example" }, now: issuedAt, want: ErrWrongDomain},
+		{name: "other URI host", modify: func(m *SiweMessage) { m.URI = "http://evil.example" }, now: issuedAt, want: ErrWrongURI},
+		{name: "version", modify: func(m *SiweMessage) { m.Version = "2" }, now: issuedAt, want: ErrWrongVersion},
+		{name: "mainnet", modify: func(m *SiweMessage) { m.ChainID = 1 }, now: issuedAt, want: ErrUnsupportedChain},
+		{name: "issued in the future", now: issuedAt.Add(-2 * time.Minute), want: ErrMessageNotYet},
+		{name: "not before", modify: func(m *SiweMessage) { m.NotBefore = &notBefore }, now: issuedAt, want: ErrMessageNotYet},
+		{name: "expired", now: expires, want: ErrMessageExpired},
+		{name: "older than a nonce", modify: func(m *SiweMessage) { m.ExpirationTime = nil }, now: issuedAt.Add(NonceLifetime + time.Second), want: ErrMessageExpired},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := valid
+			if tt.modify != nil {
+				tt.modify(&m)
+			}
+			if err := m.Validate("localhost:3000", tt.now); !errors.Is(err, tt.want) {
+				t.Errorf("Validate = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -7,7 +7,13 @@ import (
 	"backend/database"
 	"backend/handlers"
 	"backend/jwtLogic"
+	"backend/models"
+	"backend/notifications"
+	"backend/pricing"
+	"backend/ratelimit"
 	"backend/scheduler"
+	"backend/stream"
+	"backend/webhooks"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
@@ -22,34 +28,133 @@ func main() {
 		log.Fatal("Error loading .env")
 	}
 
+	if err := jwtLogic.LoadKeys(); err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+
+	pricing.Default, err = pricing.FromEnv()
+	if err != nil {
+		log.Fatalf("Failed to load price source: %v", err)
+	}
+
 	go scheduler.JobWatcher()
 	// Initialize database
 	if err := database.InitDB(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
+	go scheduler.PermitWatcher()
+	go scheduler.ConditionWatcher()
+	webhooks.Start()
+	notifications.Start()
+	stream.Start()
 
 	// Setup router
 	router := mux.NewRouter()
 
+	router.HandleFunc("/.well-known/jwks.json", jwtLogic.GetJWKS).Methods("GET")
+	router.HandleFunc("/auth/nonce", jwtLogic.GetNonce).Methods("GET")
 	router.HandleFunc("/generate-token", jwtLogic.GenerateToken).Methods("POST")
+	router.HandleFunc("/auth/refresh", jwtLogic.RefreshSession).Methods("POST")
+	router.HandleFunc("/auth/logout", jwtLogic.Logout).Methods("POST")
+	router.Handle("/auth/csrf", handlers.JWTAuth(http.HandlerFunc(jwtLogic.GetCSRFToken))).Methods("GET")
+	router.Handle("/api-keys/{userAddress}", handlers.JWTAuth(http.HandlerFunc(handlers.GetUserAPIKeys))).Methods("GET")
+	router.Handle("/api-keys/{userAddress}", handlers.JWTAuth(http.HandlerFunc(handlers.CreateAPIKey))).Methods("POST")
+	router.Handle("/api-keys/{keyId}", handlers.JWTAuth(http.HandlerFunc(handlers.RevokeAPIKey))).Methods("DELETE")
+	router.Handle("/sessions", handlers.JWTAuth(http.HandlerFunc(handlers.GetSessions))).Methods("GET")
+	router.Handle("/sessions/{sessionId}", handlers.JWTAuth(http.HandlerFunc(handlers.DeleteSession))).Methods("DELETE")
 	// User routes
 	router.Handle("/users/{userAddress}", handlers.JWTAuth(http.HandlerFunc(handlers.GetUserByAddress))).Methods("GET")
+	router.Handle("/users/{userAddress}/email", handlers.JWTAuth(http.HandlerFunc(handlers.RequestEmailVerification))).Methods("PUT")
+	router.Handle("/users/{userAddress}/notifications", handlers.JWTAuth(http.HandlerFunc(handlers.GetNotificationPreferences))).Methods("GET")
+	router.Handle("/users/{userAddress}/notifications", handlers.JWTAuth(http.HandlerFunc(handlers.UpdateNotificationPreferences))).Methods("PUT")
+	router.HandleFunc("/verify-email", handlers.VerifyEmail).Methods("GET")
 
 	// Payment template routes
-	router.Handle("/templates/{userAddress}", handlers.JWTAuth(http.HandlerFunc(handlers.GetUserTemplates))).Methods("GET")
-	router.Handle("/templates/{userAddress}", handlers.JWTAuth(http.HandlerFunc(handlers.CreateUserTemplate))).Methods("POST")
-	router.Handle("/templates/{templateId}", handlers.JWTAuth(http.HandlerFunc(handlers.DeleteTemplate))).Methods("DELETE")
-	router.Handle("/templates/{templateId}", handlers.JWTAuth(http.HandlerFunc(handlers.UpdateTemplate))).Methods("PUT")
+	router.Handle("/templates/import", handlers.ScopedAuth(models.ScopeTemplatesWrite, http.HandlerFunc(handlers.ImportTemplateCSV))).Methods("POST")
+	router.Handle("/templates/{userAddress}", handlers.ScopedAuth(models.ScopeTemplatesRead, http.HandlerFunc(handlers.GetUserTemplates))).Methods("GET")
+	router.Handle("/templates/{userAddress}", handlers.ScopedAuth(models.ScopeTemplatesWrite, http.HandlerFunc(handlers.CreateUserTemplate))).Methods("POST")
+	router.Handle("/templates/{userAddress}/typed-data", handlers.ScopedAuth(models.ScopeTemplatesWrite, http.HandlerFunc(handlers.GetTemplateTypedData))).Methods("POST")
+	router.Handle("/templates/{templateId}", handlers.ScopedAuth(models.ScopeTemplatesWrite, http.HandlerFunc(handlers.DeleteTemplate))).Methods("DELETE")
+	router.Handle("/templates/{templateId}", handlers.ScopedAuth(models.ScopeTemplatesWrite, http.HandlerFunc(handlers.UpdateTemplate))).Methods("PUT")
+	router.Handle("/templates/{templateId}/typed-data", handlers.ScopedAuth(models.ScopeTemplatesWrite, http.HandlerFunc(handlers.GetTemplateEditTypedData))).Methods("PUT")
+	router.Handle("/templates/{templateId}/executions", handlers.ScopedAuth(models.ScopeExecutionsRead, http.HandlerFunc(handlers.GetTemplateExecutions))).Methods("GET")
+	router.Handle("/templates/{templateId}/export.csv", handlers.ScopedAuth(models.ScopeTemplatesRead, http.HandlerFunc(handlers.ExportTemplateCSV))).Methods("GET")
+	router.Handle("/templates/{templateId}/export.xml", handlers.ScopedAuth(models.ScopeTemplatesRead, http.HandlerFunc(handlers.ExportTemplatePain001))).Methods("GET")
+	router.Handle("/templates/{templateId}/versions", handlers.ScopedAuth(models.ScopeTemplatesRead, http.HandlerFunc(handlers.GetTemplateVersions))).Methods("GET")
+	router.Handle("/templates/{templateId}/approvals", handlers.ScopedAuth(models.ScopeTemplatesRead, http.HandlerFunc(handlers.GetTemplateApprovals))).Methods("GET")
+	router.Handle("/templates/{templateId}/approvals", handlers.JWTAuth(http.HandlerFunc(handlers.DecideTemplate))).Methods("POST")
+	router.Handle("/templates/{templateId}/permits", handlers.ScopedAuth(models.ScopeTemplatesWrite, http.HandlerFunc(handlers.AddTemplatePermit))).Methods("POST")
+	router.Handle("/executions/export.xml", handlers.ScopedAuth(models.ScopeExecutionsRead, http.HandlerFunc(handlers.ExportExecutionsPain001))).Methods("GET")
+	router.Handle("/executions/export.csv", handlers.ScopedAuth(models.ScopeExecutionsRead, http.HandlerFunc(handlers.ExportExecutionsJournal))).Methods("GET")
+
+	// Organization routes
+	router.Handle("/organizations", handlers.JWTAuth(http.HandlerFunc(handlers.GetOrganizations))).Methods("GET")
+	router.Handle("/organizations", handlers.JWTAuth(http.HandlerFunc(handlers.CreateOrganization))).Methods("POST")
+	router.Handle("/organizations/{organizationId}/members", handlers.JWTAuth(http.HandlerFunc(handlers.GetOrganizationMembers))).Methods("GET")
+	router.Handle("/organizations/{organizationId}/members/{memberId}", handlers.JWTAuth(http.HandlerFunc(handlers.UpdateOrganizationMember))).Methods("PUT")
+	router.Handle("/organizations/{organizationId}/members/{memberId}", handlers.JWTAuth(http.HandlerFunc(handlers.RemoveOrganizationMember))).Methods("DELETE")
+	router.Handle("/organizations/{organizationId}/invitations", handlers.JWTAuth(http.HandlerFunc(handlers.GetOrganizationInvitations))).Methods("GET")
+	router.Handle("/organizations/{organizationId}/invitations", handlers.JWTAuth(http.HandlerFunc(handlers.CreateOrganizationInvitation))).Methods("POST")
+	router.Handle("/organizations/{organizationId}/invitations/{invitationId}", handlers.JWTAuth(http.HandlerFunc(handlers.RevokeOrganizationInvitation))).Methods("DELETE")
+	router.Handle("/organizations/{organizationId}/approval-policy", handlers.JWTAuth(http.HandlerFunc(handlers.GetApprovalPolicy))).Methods("GET")
+	router.Handle("/organizations/{organizationId}/approval-policy", handlers.JWTAuth(http.HandlerFunc(handlers.UpdateApprovalPolicy))).Methods("PUT")
+	router.Handle("/organizations/{organizationId}/templates", handlers.ScopedAuth(models.ScopeTemplatesRead, http.HandlerFunc(handlers.GetOrganizationTemplates))).Methods("GET")
+	router.Handle("/invitations", handlers.JWTAuth(http.HandlerFunc(handlers.GetUserInvitations))).Methods("GET")
+	router.Handle("/invitations/{invitationId}/accept", handlers.JWTAuth(http.HandlerFunc(handlers.AcceptInvitation))).Methods("POST")
+
+	// Permit routes
+	router.Handle("/permits/{userAddress}", handlers.JWTAuth(http.HandlerFunc(handlers.GetUserPermits))).Methods("GET")
+
+	// Address book routes
+	router.Handle("/contacts/{userAddress}", handlers.JWTAuth(http.HandlerFunc(handlers.GetUserContacts))).Methods("GET")
+	router.Handle("/contacts/{userAddress}", handlers.JWTAuth(http.HandlerFunc(handlers.CreateContact))).Methods("POST")
+	router.Handle("/contacts/{contactId}", handlers.JWTAuth(http.HandlerFunc(handlers.UpdateContact))).Methods("PUT")
+	router.Handle("/contacts/{contactId}", handlers.JWTAuth(http.HandlerFunc(handlers.DeleteContact))).Methods("DELETE")
+
+	// Smart account routes
+	router.Handle("/accounts/{userAddress}", handlers.JWTAuth(http.HandlerFunc(handlers.GetUserSmartAccounts))).Methods("GET")
+	router.Handle("/accounts/{userAddress}", handlers.JWTAuth(http.HandlerFunc(handlers.RegisterSmartAccount))).Methods("POST")
+	router.Handle("/accounts/{accountId}/verify", handlers.JWTAuth(http.HandlerFunc(handlers.VerifySmartAccount))).Methods("POST")
+	router.Handle("/accounts/{accountId}", handlers.JWTAuth(http.HandlerFunc(handlers.DeleteSmartAccount))).Methods("DELETE")
+
+	// Webhook routes
+	router.Handle("/webhooks/{userAddress}", handlers.JWTAuth(http.HandlerFunc(handlers.GetUserWebhooks))).Methods("GET")
+	router.Handle("/webhooks/{userAddress}", handlers.JWTAuth(http.HandlerFunc(handlers.CreateWebhook))).Methods("POST")
+	router.Handle("/webhooks/{webhookId}", handlers.JWTAuth(http.HandlerFunc(handlers.DeleteWebhook))).Methods("DELETE")
+	router.Handle("/webhooks/{webhookId}/deliveries", handlers.JWTAuth(http.HandlerFunc(handlers.GetWebhookDeliveries))).Methods("GET")
+	router.Handle("/webhook-deliveries/{deliveryId}/redeliver", handlers.JWTAuth(http.HandlerFunc(handlers.RedeliverWebhook))).Methods("POST")
+
+	router.Handle("/audit", handlers.JWTAuth(http.HandlerFunc(handlers.GetAuditEvents))).Methods("GET")
+
+	// Status stream routes
+	router.Handle("/stream", handlers.JWTAuth(http.HandlerFunc(handlers.StreamEvents))).Methods("GET")
 
 	// Asset routes
 	router.HandleFunc("/assets", handlers.GetAllAssets).Methods("GET")
+	router.Handle("/assets", handlers.JWTAuth(http.HandlerFunc(handlers.CreateAsset))).Methods("POST")
+	router.Handle("/assets/{assetId}", handlers.JWTAuth(http.HandlerFunc(handlers.UpdateAsset))).Methods("PUT")
+	router.Handle("/assets/{assetId}", handlers.JWTAuth(http.HandlerFunc(handlers.DeleteAsset))).Methods("DELETE")
+
+	// Stricter limits where requests create users, sessions and templates
+	limiter, err := ratelimit.FromEnv(map[string]ratelimit.Class{
+		"GET /auth/nonce":               ratelimit.ClassAuth,
+		"POST /generate-token":          ratelimit.ClassAuth,
+		"POST /auth/refresh":            ratelimit.ClassAuth,
+		"POST /auth/logout":             ratelimit.ClassAuth,
+		"POST /templates/{userAddress}": ratelimit.ClassTemplates,
+		"POST /templates/import":        ratelimit.ClassTemplates,
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure rate limits: %v", err)
+	}
+	router.Use(limiter.Middleware)
 
 	// Configure CORS
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://localhost:3001"}, // Add your frontend URLs
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		ExposedHeaders:   []string{"Link", "X-CSRF-Token", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"},
 		AllowCredentials: true,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	})
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"net"
+	"strings"
+	"time"
+)
+
+// APIKeyScope limits what an API key may be used for
+type APIKeyScope string
+
+const (
+	ScopeTemplatesRead  APIKeyScope = "templates:read"
+	ScopeTemplatesWrite APIKeyScope = "templates:write"
+	ScopeExecutionsRead APIKeyScope = "executions:read"
+)
+
+// APIKeyScopes lists every scope, e.g. for validating new keys
+var APIKeyScopes = []APIKeyScope{
+	ScopeTemplatesRead,
+	ScopeTemplatesWrite,
+	ScopeExecutionsRead,
+}
+
+// APIKey lets a user's services call the API without a wallet or cookies
+type APIKey struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID     uint       `gorm:"not null;index" json:"-"`
+	Name       string     `gorm:"not null;size:100" json:"name"`
+	Prefix     string     `gorm:"not null;size:16;uniqueIndex" json:"prefix"` // Public start of the key, identifies it in logs and listings
+	KeyHash    string     `gorm:"not null;size:64" json:"-"`                  // SHA-256 of the full key
+	Scopes     string     `gorm:"not null;type:text" json:"scopes"`           // Comma separated APIKeyScope list
+	AllowedIPs string     `gorm:"type:text" json:"allowed_ips,omitempty"`     // Comma separated IPs or CIDRs, empty allows any
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// TableName specifies the table name for APIKey
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// Active reports whether the key can be used at now
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// HasScope reports whether the key was granted scope
+func (k APIKey) HasScope(scope APIKeyScope) bool {
+	for _, s := range strings.Split(k.Scopes, ",") {
+		if s == string(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsIP reports whether requests from ip may use the key
+func (k APIKey) AllowsIP(ip net.IP) bool {
+	if k.AllowedIPs == "" {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	for _, entry := range strings.Split(k.AllowedIPs, ",") {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+		} else if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+)
+
+// ApprovalStatus tracks whether a template may execute under its organization's approval policy
+type ApprovalStatus string
+
+const (
+	ApprovalStatusNotRequired ApprovalStatus = "not_required"
+	ApprovalStatusPending     ApprovalStatus = "pending"
+	ApprovalStatusApproved    ApprovalStatus = "approved"
+	ApprovalStatusRejected    ApprovalStatus = "rejected"
+)
+
+// ApprovalDecision is what an approver decided
+type ApprovalDecision string
+
+const (
+	ApprovalDecisionApprove ApprovalDecision = "approve"
+	ApprovalDecisionReject  ApprovalDecision = "reject"
+)
+
+// ApprovalPolicy is how many approvals an organization's templates need before they execute
+type ApprovalPolicy struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	OrganizationID    uint `gorm:"not null;uniqueIndex" json:"organization_id"`
+	RequiredApprovals int  `gorm:"not null" json:"required_approvals"` // Approvals every template needs, 0 for none
+
+	// Relations
+	Tiers []ApprovalTier `gorm:"foreignKey:ApprovalPolicyID;constraint:OnDelete:CASCADE;" json:"tiers"`
+}
+
+// TableName specifies the table name for ApprovalPolicy
+func (ApprovalPolicy) TableName() string {
+	return "approval_policies"
+}
+
+// ApprovalTier raises the required approvals of templates moving at least
+// MinAmount, either of an asset or, for fiat-denominated transfers, of a currency
+type ApprovalTier struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ApprovalPolicyID  uint    `gorm:"not null;index" json:"-"`
+	AssetID           *uint   `json:"asset_id,omitempty"`
+	FiatCurrency      *string `gorm:"size:3" json:"fiat_currency,omitempty"`
+	MinAmount         float64 `gorm:"not null" json:"min_amount"`
+	RequiredApprovals int     `gorm:"not null" json:"required_approvals"`
+}
+
+// TableName specifies the table name for ApprovalTier
+func (ApprovalTier) TableName() string {
+	return "approval_tiers"
+}
+
+// TemplateApproval is one approver's decision on a template's transfers; it
+// only counts while ContentDigest matches the template's current transfers
+type TemplateApproval struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	PaymentTemplateID uint             `gorm:"not null;uniqueIndex:idx_approval_template_user_digest" json:"payment_template_id"`
+	UserID            uint             `gorm:"not null;uniqueIndex:idx_approval_template_user_digest" json:"user_id"`
+	ContentDigest     string           `gorm:"not null;size:64;uniqueIndex:idx_approval_template_user_digest" json:"content_digest"`
+	Decision          ApprovalDecision `gorm:"not null;size:10" json:"decision"`
+	Comment           string           `gorm:"type:text" json:"comment,omitempty"`
+	Signature         string           `gorm:"type:text" json:"signature,omitempty"` // Optional EIP-191, EIP-1271 or ERC-6492 signature of the approval message
+
+	// Relations
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName specifies the table name for TemplateApproval
+func (TemplateApproval) TableName() string {
+	return "template_approvals"
+}
@@ -20,11 +20,14 @@ type Asset struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 
-	Symbol          string `gorm:"uniqueIndex;not null;size:10" json:"symbol"`
+	Symbol          string `gorm:"not null;size:10" json:"symbol"` // Not unique: the same token has a symbol on every chain
 	Name            string `gorm:"not null" json:"name"`
 	Decimals        uint8  `gorm:"not null;default:18" json:"decimals"`
-	ContractAddress string `gorm:"size:42" json:"contract_address,omitempty"` // For ERC-20 tokens
-	ChainID         uint64 `gorm:"not null;default:1" json:"chain_id"`        // The blockchain network's chain ID
+	ContractAddress string `gorm:"size:42;uniqueIndex:idx_assets_chain_contract" json:"contract_address,omitempty"` // For ERC-20 tokens
+	ChainID         uint64 `gorm:"not null;default:1;uniqueIndex:idx_assets_chain_contract" json:"chain_id"`        // The blockchain network's chain ID
+
+	// Disabled assets stay on existing templates and history but cannot be used in new templates
+	Enabled bool `gorm:"not null;default:true" json:"enabled"`
 }
 
 // TableName specifies the table name for Asset
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrAuditEventImmutable is returned when something tries to change a recorded audit event
+var ErrAuditEventImmutable = errors.New("audit events are append-only")
+
+// AuditEvent records one mutation made by a user or by the scheduler
+type AuditEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	UserID     uint            `gorm:"not null;index" json:"-"`              // Owner of the target, audit events are scoped to them
+	Actor      string          `gorm:"not null;size:64" json:"actor"`        // Ethereum address of the caller, or "system"
+	Action     string          `gorm:"not null;size:64;index" json:"action"` // e.g. template.update
+	TargetType string          `gorm:"not null;size:32" json:"target_type"`  // e.g. payment_template
+	TargetID   uint            `gorm:"not null" json:"target_id"`
+	Before     json.RawMessage `gorm:"type:json" json:"before,omitempty"` // Snapshot before the change, empty on creation
+	After      json.RawMessage `gorm:"type:json" json:"after,omitempty"`  // Snapshot after the change, empty on deletion
+
+	// Request metadata, empty for system actions
+	Method    string `gorm:"size:10" json:"method,omitempty"`
+	Path      string `gorm:"size:2048" json:"path,omitempty"`
+	IPAddress string `gorm:"size:45" json:"ip_address,omitempty"`
+	UserAgent string `gorm:"size:512" json:"user_agent,omitempty"`
+}
+
+// TableName specifies the table name for AuditEvent
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// BeforeUpdate keeps recorded events from being changed
+func (AuditEvent) BeforeUpdate(*gorm.DB) error {
+	return ErrAuditEventImmutable
+}
+
+// BeforeDelete keeps recorded events from being removed
+func (AuditEvent) BeforeDelete(*gorm.DB) error {
+	return ErrAuditEventImmutable
+}
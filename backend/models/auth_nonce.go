@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// AuthNonce is a server-issued Sign-In with Ethereum nonce; each one is accepted once
+type AuthNonce struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time `json:"-"`
+
+	Address   string     `gorm:"not null;size:42;index" json:"-"` // Lowercased address the nonce was issued to
+	Nonce     string     `gorm:"not null;size:32;uniqueIndex" json:"nonce"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time `json:"-"`
+}
+
+// TableName specifies the table name for AuthNonce
+func (AuthNonce) TableName() string {
+	return "auth_nonces"
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Contact is a named beneficiary in a user's address book
+type Contact struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID         uint    `gorm:"not null;uniqueIndex:idx_contacts_user_address" json:"-"`
+	Name           string  `gorm:"not null;size:100" json:"name"`
+	Address        string  `gorm:"not null;size:42;uniqueIndex:idx_contacts_user_address" json:"address"` // EIP-55 checksummed
+	DefaultAssetID *uint   `gorm:"index" json:"default_asset_id,omitempty"`                               // Paid when a transfer to the contact names no asset
+	DefaultChainID *uint64 `json:"default_chain_id,omitempty"`
+	Tags           string  `gorm:"type:text" json:"tags"` // Comma separated
+	Notes          string  `gorm:"type:text" json:"notes,omitempty"`
+
+	// Relations
+	User         User   `gorm:"foreignKey:UserID" json:"-"`
+	DefaultAsset *Asset `gorm:"foreignKey:DefaultAssetID" json:"default_asset,omitempty"`
+}
+
+// TableName specifies the table name for Contact
+func (Contact) TableName() string {
+	return "contacts"
+}
+
+// HasTag reports whether the contact is tagged with tag, ignoring case
+func (c Contact) HasTag(tag string) bool {
+	for _, t := range strings.Split(c.Tags, ",") {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+)
+
+// ExecutionStatus represents the outcome of a single scheduler run of a template
+type ExecutionStatus string
+
+const (
+	ExecutionStatusSubmitted ExecutionStatus = "submitted"
+	ExecutionStatusConfirmed ExecutionStatus = "confirmed"
+	ExecutionStatusFailed    ExecutionStatus = "failed"
+	// ExecutionStatusUnknown is a submitted transaction no receipt was seen for;
+	// it may still be mined
+	ExecutionStatusUnknown ExecutionStatus = "unknown"
+)
+
+// Execution records one attempt by the scheduler to execute a payment template
+type Execution struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	PaymentTemplateID uint            `gorm:"not null;index" json:"payment_template_id"`
+	TemplateVersion   uint            `gorm:"not null;default:1" json:"template_version"` // Version of the template that ran
+	SmartAccountID    *uint           `gorm:"index" json:"smart_account_id,omitempty"`
+	ChainID           uint64          `gorm:"not null" json:"chain_id"`
+	TxHash            string          `gorm:"size:66" json:"tx_hash,omitempty"`
+	Status            ExecutionStatus `gorm:"not null;size:20" json:"status"`
+	Error             string          `gorm:"type:text" json:"error,omitempty"`
+	BlockNumber       *uint64         `json:"block_number,omitempty"`
+	ConfirmedAt       *time.Time      `json:"confirmed_at,omitempty"`
+
+	// Relations
+	Transfers       []ExecutionTransfer `gorm:"foreignKey:ExecutionID;constraint:OnDelete:CASCADE;" json:"transfers,omitempty"`
+	PaymentTemplate *PaymentTemplate    `gorm:"foreignKey:PaymentTemplateID" json:"payment_template,omitempty"`
+	SmartAccount    *SmartAccount       `gorm:"foreignKey:SmartAccountID" json:"smart_account,omitempty"`
+}
+
+// TableName specifies the table name for Execution
+func (Execution) TableName() string {
+	return "executions"
+}
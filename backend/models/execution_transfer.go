@@ -0,0 +1,23 @@
+package models
+
+// ExecutionTransfer records what a single transfer moved in an execution
+type ExecutionTransfer struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	ExecutionID  uint     `gorm:"not null;index" json:"execution_id"`
+	TransferID   uint     `gorm:"not null;index" json:"transfer_id"`
+	AssetID      uint     `gorm:"not null" json:"asset_id"`
+	Amount       float64  `gorm:"not null" json:"amount"`             // Amount in asset units
+	BaseUnits    string   `gorm:"not null;size:78" json:"base_units"` // Amount in the asset's smallest unit, as encoded
+	FiatAmount   *float64 `json:"fiat_amount,omitempty"`              // Set for fiat-denominated transfers
+	FiatCurrency *string  `gorm:"size:3" json:"fiat_currency,omitempty"`
+	Rate         *float64 `json:"rate,omitempty"` // Price of one asset unit in FiatCurrency used for the conversion
+
+	// Relations
+	Asset Asset `gorm:"foreignKey:AssetID" json:"asset,omitempty"`
+}
+
+// TableName specifies the table name for ExecutionTransfer
+func (ExecutionTransfer) TableName() string {
+	return "execution_transfers"
+}
@@ -0,0 +1,80 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationKind identifies which email a notification is
+type NotificationKind string
+
+const (
+	NotificationKindPaymentDue      NotificationKind = "payment_due"
+	NotificationKindPaymentExecuted NotificationKind = "payment_executed"
+	NotificationKindPaymentFailed   NotificationKind = "payment_failed"
+	NotificationKindPermitExpiring  NotificationKind = "permit_expiring"
+)
+
+// NotificationPreferences holds which emails a user wants; users without a row get all of them
+type NotificationPreferences struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID          uint `gorm:"not null;uniqueIndex" json:"-"`
+	PaymentDue      bool `gorm:"not null" json:"payment_due"`
+	PaymentExecuted bool `gorm:"not null" json:"payment_executed"`
+	PaymentFailed   bool `gorm:"not null" json:"payment_failed"`
+}
+
+// TableName specifies the table name for NotificationPreferences
+func (NotificationPreferences) TableName() string {
+	return "notification_preferences"
+}
+
+// Wants reports whether the preferences allow the kind of notification
+func (p NotificationPreferences) Wants(kind NotificationKind) bool {
+	switch kind {
+	case NotificationKindPaymentDue, NotificationKindPermitExpiring:
+		return p.PaymentDue
+	case NotificationKindPaymentExecuted:
+		return p.PaymentExecuted
+	case NotificationKindPaymentFailed:
+		return p.PaymentFailed
+	}
+	return false
+}
+
+// Notification records an email sent, or attempted, to a user
+type Notification struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID            uint             `gorm:"not null;index" json:"user_id"`
+	Kind              NotificationKind `gorm:"not null;size:30" json:"kind"`
+	PaymentTemplateID uint             `gorm:"not null;index" json:"payment_template_id"`
+	ExecutionID       *uint            `json:"execution_id,omitempty"`
+	RunAt             *time.Time       `json:"run_at,omitempty"` // The run a due reminder was sent for
+	Email             string           `gorm:"not null;size:100" json:"email"`
+	Error             string           `gorm:"type:text" json:"error,omitempty"`
+}
+
+// TableName specifies the table name for Notification
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// EmailVerification is a pending confirmation that a user owns an email address
+type EmailVerification struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	Email     string     `gorm:"not null;size:100" json:"email"`
+	TokenHash string     `gorm:"not null;size:64;uniqueIndex" json:"-"` // SHA-256 of the emailed token
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// TableName specifies the table name for EmailVerification
+func (EmailVerification) TableName() string {
+	return "email_verifications"
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"time"
+)
+
+// OrganizationRole is what a member may do in an organization
+type OrganizationRole string
+
+const (
+	OrganizationRoleOwner    OrganizationRole = "owner"
+	OrganizationRoleAdmin    OrganizationRole = "admin"
+	OrganizationRolePreparer OrganizationRole = "preparer"
+	OrganizationRoleApprover OrganizationRole = "approver"
+	OrganizationRoleViewer   OrganizationRole = "viewer"
+)
+
+// OrganizationPermission is an action guarded by role
+type OrganizationPermission string
+
+const (
+	PermissionViewTemplates    OrganizationPermission = "view_templates"
+	PermissionPrepareTemplates OrganizationPermission = "prepare_templates" // Create, edit, cancel and delete
+	PermissionApproveTemplates OrganizationPermission = "approve_templates"
+	PermissionManageMembers    OrganizationPermission = "manage_members"
+	PermissionManageOwners     OrganizationPermission = "manage_owners"
+)
+
+// rolePermissions lists what each role may do. Owners and admins may both
+// prepare and approve; approvals.Evaluate keeps the two apart per template by
+// ignoring the approval of the member who prepared it.
+var rolePermissions = map[OrganizationRole][]OrganizationPermission{
+	OrganizationRoleOwner:    {PermissionViewTemplates, PermissionPrepareTemplates, PermissionApproveTemplates, PermissionManageMembers, PermissionManageOwners},
+	OrganizationRoleAdmin:    {PermissionViewTemplates, PermissionPrepareTemplates, PermissionApproveTemplates, PermissionManageMembers},
+	OrganizationRolePreparer: {PermissionViewTemplates, PermissionPrepareTemplates},
+	OrganizationRoleApprover: {PermissionViewTemplates, PermissionApproveTemplates},
+	OrganizationRoleViewer:   {PermissionViewTemplates},
+}
+
+// Valid reports whether r is a known role
+func (r OrganizationRole) Valid() bool {
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Can reports whether members with the role have the permission
+func (r OrganizationRole) Can(permission OrganizationPermission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Organization owns templates that several users prepare and review
+type Organization struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Name string `gorm:"not null;size:100" json:"name"`
+
+	// Relations
+	Members     []OrganizationMember     `gorm:"foreignKey:OrganizationID;constraint:OnDelete:CASCADE;" json:"members,omitempty"`
+	Invitations []OrganizationInvitation `gorm:"foreignKey:OrganizationID;constraint:OnDelete:CASCADE;" json:"invitations,omitempty"`
+}
+
+// TableName specifies the table name for Organization
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// OrganizationMember gives a user a role in an organization
+type OrganizationMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	OrganizationID uint             `gorm:"not null;uniqueIndex:idx_organization_user" json:"organization_id"`
+	UserID         uint             `gorm:"not null;uniqueIndex:idx_organization_user;index" json:"user_id"`
+	Role           OrganizationRole `gorm:"not null;size:20" json:"role"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	User         *User         `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName specifies the table name for OrganizationMember
+func (OrganizationMember) TableName() string {
+	return "organization_members"
+}
+
+// OrganizationInvitation offers a role to an Ethereum address until it is accepted or revoked
+type OrganizationInvitation struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	OrganizationID  uint             `gorm:"not null;index" json:"organization_id"`
+	EthereumAddress string           `gorm:"not null;size:42;index" json:"ethereum_address"`
+	Role            OrganizationRole `gorm:"not null;size:20" json:"role"`
+	InvitedByID     uint             `gorm:"not null" json:"invited_by_id"`
+	AcceptedAt      *time.Time       `json:"accepted_at,omitempty"`
+	RevokedAt       *time.Time       `json:"revoked_at,omitempty"`
+
+	// Relations
+	Organization *Organization `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+}
+
+// TableName specifies the table name for OrganizationInvitation
+func (OrganizationInvitation) TableName() string {
+	return "organization_invitations"
+}
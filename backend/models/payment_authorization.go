@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+// PaymentAuthorization is the user's EIP-712 signature over a template's
+// transfers, schedule, end date and max totals
+type PaymentAuthorization struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	PaymentTemplateID uint   `gorm:"not null;uniqueIndex" json:"payment_template_id"`
+	Signer            string `gorm:"not null;size:42" json:"signer"`
+	ChainID           uint64 `gorm:"not null" json:"chain_id"`
+	Nonce             string `gorm:"not null;size:78" json:"nonce"`              // Server-issued AuthorizationNonce of the message, empty for older authorizations
+	Digest            string `gorm:"not null;size:66;uniqueIndex" json:"digest"` // EIP-712 hash that was signed
+	Signature         string `gorm:"not null;type:text" json:"-"`                // Never serialised into responses, events or audit snapshots
+
+	// Relations
+	MaxTotals []AuthorizationMaxTotal `gorm:"foreignKey:PaymentAuthorizationID;constraint:OnDelete:CASCADE;" json:"max_totals,omitempty"`
+}
+
+// TableName specifies the table name for PaymentAuthorization
+func (PaymentAuthorization) TableName() string {
+	return "payment_authorizations"
+}
+
+// AuthorizationNonce is issued with the typed data of a payment authorization
+// and accepted once, so a signature cannot be submitted for a second template
+type AuthorizationNonce struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	CreatedAt time.Time `json:"-"`
+
+	Address   string     `gorm:"not null;size:42;index" json:"-"` // Signer the nonce was issued to
+	Nonce     string     `gorm:"not null;size:78;uniqueIndex" json:"nonce"`
+	ExpiresAt time.Time  `gorm:"not null;index" json:"expires_at"`
+	UsedAt    *time.Time `json:"-"`
+}
+
+// TableName specifies the table name for AuthorizationNonce
+func (AuthorizationNonce) TableName() string {
+	return "authorization_nonces"
+}
+
+// AuthorizationMaxTotal caps the total amount of an asset the backend may move under an authorisation
+type AuthorizationMaxTotal struct {
+	ID uint `gorm:"primaryKey" json:"id"`
+
+	PaymentAuthorizationID uint    `gorm:"not null;index" json:"payment_authorization_id"`
+	AssetID                uint    `gorm:"not null" json:"asset_id"`
+	Amount                 float64 `gorm:"not null" json:"amount"`
+
+	// Relations
+	Asset Asset `gorm:"foreignKey:AssetID" json:"asset,omitempty"`
+}
+
+// TableName specifies the table name for AuthorizationMaxTotal
+func (AuthorizationMaxTotal) TableName() string {
+	return "authorization_max_totals"
+}
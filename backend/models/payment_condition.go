@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+)
+
+// ConditionType represents the on-chain state a conditional template waits for
+type ConditionType string
+
+const (
+	// ConditionTypeBalanceAbove waits until the smart account holds more than Threshold of the asset
+	ConditionTypeBalanceAbove ConditionType = "balance_above"
+	// ConditionTypeGasPriceBelow waits until the gas price drops below Threshold wei
+	ConditionTypeGasPriceBelow ConditionType = "gas_price_below"
+	// ConditionTypeContractCall waits until a view call returns a value matching Comparator and Threshold
+	ConditionTypeContractCall ConditionType = "contract_call"
+)
+
+// ConditionComparator compares an on-chain value with the threshold
+type ConditionComparator string
+
+const (
+	ComparatorEqual          ConditionComparator = "eq"
+	ComparatorGreater        ConditionComparator = "gt"
+	ComparatorGreaterOrEqual ConditionComparator = "gte"
+	ComparatorLess           ConditionComparator = "lt"
+	ComparatorLessOrEqual    ConditionComparator = "lte"
+)
+
+// ConditionStatus represents where a condition is in its lifecycle
+type ConditionStatus string
+
+const (
+	ConditionStatusWaiting   ConditionStatus = "waiting"
+	ConditionStatusTriggered ConditionStatus = "triggered"
+	ConditionStatusExpired   ConditionStatus = "expired" // The template was not executed
+)
+
+// PaymentCondition makes a template execute when on-chain state holds instead of at ScheduledAt
+type PaymentCondition struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	PaymentTemplateID uint                `gorm:"not null;uniqueIndex" json:"payment_template_id"`
+	ChainID           uint64              `gorm:"not null;index" json:"chain_id"`
+	Type              ConditionType       `gorm:"not null;size:30" json:"type"`
+	Comparator        ConditionComparator `gorm:"not null;size:3" json:"comparator"`
+	Threshold         string              `gorm:"not null;size:78" json:"threshold"` // In the smallest unit (base units, wei or raw uint256)
+
+	AssetID         *uint  `json:"asset_id,omitempty"`                        // For balance conditions
+	ContractAddress string `gorm:"size:42" json:"contract_address,omitempty"` // For contract call conditions
+	CallData        string `gorm:"type:text" json:"call_data,omitempty"`      // Hex encoded view call, result read as uint256
+
+	// Repeat re-arms the condition after each execution, e.g. for sweeps
+	Repeat    bool            `gorm:"not null" json:"repeat"`
+	ExpiresAt time.Time       `gorm:"not null" json:"expires_at"`
+	Status    ConditionStatus `gorm:"not null;size:20;index" json:"status"`
+
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"`
+	TriggeredAt   *time.Time `json:"triggered_at,omitempty"`
+
+	// Relations
+	Asset           *Asset           `gorm:"foreignKey:AssetID" json:"asset,omitempty"`
+	PaymentTemplate *PaymentTemplate `gorm:"foreignKey:PaymentTemplateID" json:"payment_template,omitempty"`
+}
+
+// TableName specifies the table name for PaymentCondition
+func (PaymentCondition) TableName() string {
+	return "payment_conditions"
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // PaymentTemplate represents a reusable payment template
@@ -9,16 +11,36 @@ type PaymentTemplate struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 
-	UserID      uint   `gorm:"not null;index" json:"user_id"`
-	Name        string `gorm:"not null" json:"name"`
-	IsCancelled bool   `gorm:"not null;" json:"is_cancelled"`
+	UserID         uint   `gorm:"not null;index" json:"user_id"`          // Member whose account funds and signs for the template
+	OrganizationID *uint  `gorm:"index" json:"organization_id,omitempty"` // Set for templates the organization's members share
+	Name           string `gorm:"not null" json:"name"`
+	IsCancelled    bool   `gorm:"not null;" json:"is_cancelled"`
+
+	// DeletedAt hides deleted templates; they stay cancelled in the database
+	// so their executions keep their history
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	// Version is the number of the template's latest TemplateVersion
+	Version uint `gorm:"not null;default:1" json:"version"`
+	// ScheduleRevision changes whenever the schedule is edited, so runs queued for the old schedule are dropped
+	ScheduleRevision uint `gorm:"not null;default:0" json:"-"`
+
+	// ApprovalStatus is pending until the organization's approval policy is met
+	ApprovalStatus ApprovalStatus `gorm:"not null;size:20;default:'not_required'" json:"approval_status"`
 
 	ScheduledAt       *time.Time `json:"scheduled_at,omitempty"`       // Nullable scheduled time
 	RecurringInterval *int64     `json:"recurring_interval,omitempty"` // Nullable recurring interval (number, e.g. seconds)
+	EndsAt            *time.Time `json:"ends_at,omitempty"`            // Nullable time after which the template no longer runs
 
 	// Relations
-	User      User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	Transfers []Transfer `gorm:"foreignKey:PaymentTemplateID;constraint:OnDelete:CASCADE;" json:"transfers,omitempty"`
+	User          User                  `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Organization  *Organization         `gorm:"foreignKey:OrganizationID" json:"organization,omitempty"`
+	Transfers     []Transfer            `gorm:"foreignKey:PaymentTemplateID;constraint:OnDelete:CASCADE;" json:"transfers,omitempty"`
+	Authorization *PaymentAuthorization `gorm:"foreignKey:PaymentTemplateID;constraint:OnDelete:CASCADE;" json:"authorization,omitempty"`
+	Permits       []TokenPermit         `gorm:"foreignKey:PaymentTemplateID;constraint:OnDelete:CASCADE;" json:"permits,omitempty"`
+	Condition     *PaymentCondition     `gorm:"foreignKey:PaymentTemplateID;constraint:OnDelete:CASCADE;" json:"condition,omitempty"`
+	Approvals     []TemplateApproval    `gorm:"foreignKey:PaymentTemplateID;constraint:OnDelete:CASCADE;" json:"approvals,omitempty"`
+	Versions      []TemplateVersion     `gorm:"foreignKey:PaymentTemplateID;constraint:OnDelete:CASCADE;" json:"versions,omitempty"`
 }
 
 // TableName specifies the table name for PaymentTemplate
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTemplateJSONOmitsSignatures(t *testing.T) {
+	template := PaymentTemplate{
+		ID:            1,
+		Authorization: &PaymentAuthorization{Digest: "0xdigest", Signature: "0xauthorization-signature"},
+		Permits:       []TokenPermit{{ID: 2, Value: "1000000", Signature: "0xpermit-signature"}},
+	}
+
+	data, err := json.Marshal(template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"authorization-signature", "permit-signature", `"signature"`} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("template JSON contains %s: %s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "0xdigest") {
+		t.Errorf("template JSON lost the authorization digest: %s", data)
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+)
+
+// RateLimitBucket is a token bucket shared by all instances when RATE_LIMIT_STORE=db
+type RateLimitBucket struct {
+	BucketKey string    `gorm:"primaryKey;size:191"`
+	Tokens    float64   `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"not null;autoUpdateTime:false"`
+}
+
+// TableName specifies the table name for RateLimitBucket
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
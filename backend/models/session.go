@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+)
+
+// Session is one signed-in device of a user; access tokens carry its ID so it can be revoked
+type Session struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID        uint       `gorm:"not null;index" json:"-"`
+	UserAgent     string     `gorm:"size:512" json:"device"`
+	IPAddress     string     `gorm:"size:45" json:"ip_address"`
+	LastUsedAt    time.Time  `gorm:"not null" json:"last_used_at"`
+	ExpiresAt     time.Time  `gorm:"not null" json:"expires_at"` // Moves forward every time the refresh token is rotated
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	RevokedReason string     `gorm:"size:64" json:"revoked_reason,omitempty"` // logout, revoked or refresh_token_reuse
+	CSRFToken     string     `gorm:"size:64" json:"-"`                        // Required in X-CSRF-Token on state-changing requests
+
+	// Relations
+	User          User           `gorm:"foreignKey:UserID" json:"-"`
+	RefreshTokens []RefreshToken `gorm:"foreignKey:SessionID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+// TableName specifies the table name for Session
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// Active reports whether the session can still be used at now
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RefreshToken is a single-use token exchanged for a new access token; a used
+// one presented again means it leaked, and its session is revoked
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	SessionID uint       `gorm:"not null;index" json:"session_id"`
+	TokenHash string     `gorm:"not null;size:64;uniqueIndex" json:"-"` // SHA-256 of the token in the cookie
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+
+	// Relations
+	Session Session `gorm:"foreignKey:SessionID" json:"-"`
+}
+
+// TableName specifies the table name for RefreshToken
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// SmartAccountType represents the kind of account contract a user holds
+type SmartAccountType string
+
+const (
+	// SmartAccountTypeAmbire is a deployed Ambire account contract
+	SmartAccountTypeAmbire SmartAccountType = "ambire"
+	// SmartAccountTypeAmbire7702 is the user's EOA delegated to the Ambire implementation via EIP-7702
+	SmartAccountTypeAmbire7702 SmartAccountType = "ambire_7702"
+)
+
+// SmartAccount represents the account contract that holds a user's funds on a chain
+type SmartAccount struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID      uint             `gorm:"not null;uniqueIndex:idx_smart_accounts_user_chain" json:"user_id"`
+	ChainID     uint64           `gorm:"not null;uniqueIndex:idx_smart_accounts_user_chain" json:"chain_id"`
+	Address     string           `gorm:"not null;size:42" json:"address"`
+	AccountType SmartAccountType `gorm:"not null;size:20" json:"account_type"`
+
+	// ExecutorAuthorized is true when the backend executor holds privileges on the account
+	ExecutorAuthorized     bool       `gorm:"not null" json:"executor_authorized"`
+	AuthorizationCheckedAt *time.Time `json:"authorization_checked_at,omitempty"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName specifies the table name for SmartAccount
+func (SmartAccount) TableName() string {
+	return "smart_accounts"
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrTemplateVersionImmutable is returned when something tries to change a recorded template version
+var ErrTemplateVersionImmutable = errors.New("template versions are immutable")
+
+// TemplateVersion is a snapshot of what a template pays and when, recorded on
+// creation and on every edit; executions name the version they ran
+type TemplateVersion struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	PaymentTemplateID uint            `gorm:"not null;uniqueIndex:idx_template_version" json:"payment_template_id"`
+	Version           uint            `gorm:"not null;uniqueIndex:idx_template_version" json:"version"`
+	CreatedByID       uint            `gorm:"not null" json:"created_by_id"`
+	Content           json.RawMessage `gorm:"type:json;not null" json:"content"`
+	Changes           json.RawMessage `gorm:"type:json" json:"changes,omitempty"` // Differences to the previous version
+
+	// Relations
+	CreatedBy User `gorm:"foreignKey:CreatedByID" json:"-"`
+}
+
+// TableName specifies the table name for TemplateVersion
+func (TemplateVersion) TableName() string {
+	return "template_versions"
+}
+
+// BeforeUpdate keeps recorded versions unchanged; they are removed together with their template
+func (TemplateVersion) BeforeUpdate(*gorm.DB) error {
+	return ErrTemplateVersionImmutable
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// TokenPermit is an EIP-2612 permit signed by the user so the smart account
+// can pull an asset from the user's address without a prior approve
+type TokenPermit struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	PaymentTemplateID uint      `gorm:"not null;index" json:"payment_template_id"`
+	AssetID           uint      `gorm:"not null;index" json:"asset_id"`
+	Owner             string    `gorm:"not null;size:42" json:"owner"`
+	Spender           string    `gorm:"not null;size:42" json:"spender"`
+	Value             string    `gorm:"not null;size:78" json:"value"` // Allowance in the asset's smallest unit
+	Nonce             uint64    `gorm:"not null" json:"nonce"`
+	Deadline          time.Time `gorm:"not null;index" json:"deadline"`
+	Signature         string    `gorm:"not null;type:text" json:"-"` // Anyone could submit it, so it is never serialised
+
+	ConsumedAt     *time.Time `json:"consumed_at,omitempty"`      // Set once the permit has been submitted on-chain
+	ExpiryWarnedAt *time.Time `json:"expiry_warned_at,omitempty"` // Set once the user has been warned about the deadline
+
+	// Relations
+	Asset Asset `gorm:"foreignKey:AssetID" json:"asset,omitempty"`
+}
+
+// TableName specifies the table name for TokenPermit
+func (TokenPermit) TableName() string {
+	return "token_permits"
+}
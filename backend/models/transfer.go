@@ -25,6 +25,10 @@ type Transfer struct {
 	AssetID                uint           `gorm:"not null;index" json:"asset_id"`
 	Status                 TransferStatus `gorm:"not null;default:'pending'" json:"status"`
 
+	// Fiat-denominated transfers pay FiatAmount converted to the asset at execution time
+	FiatAmount   *float64 `json:"fiat_amount,omitempty"`
+	FiatCurrency *string  `gorm:"size:3" json:"fiat_currency,omitempty"`
+
 	// Relations
 	SourceUser      User             `gorm:"foreignKey:SourceUserID" json:"source_user,omitempty"`
 	PaymentTemplate *PaymentTemplate `gorm:"foreignKey:PaymentTemplateID" json:"payment_template,omitempty"`
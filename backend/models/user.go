@@ -13,10 +13,17 @@ type User struct {
 	Email    *string `gorm:"uniqueIndex;size:100" json:"email,omitempty"`
 	Username *string `gorm:"uniqueIndex;size:50" json:"username,omitempty"`
 
+	// EmailVerifiedAt is set once the user confirmed Email; nothing is sent before that
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+
 	EthereumAddress string `gorm:"uniqueIndex;size:42;not null" json:"ethereum_address"`
 
+	// IsAdmin grants access to the asset management routes
+	IsAdmin bool `gorm:"not null;default:false" json:"is_admin"`
+
 	// Relations
 	PaymentTemplates []PaymentTemplate `gorm:"foreignKey:UserID" json:"payment_templates,omitempty"`
+	SmartAccounts    []SmartAccount    `gorm:"foreignKey:UserID" json:"smart_accounts,omitempty"`
 }
 
 // TableName specifies the table name for User
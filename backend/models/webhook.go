@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+)
+
+// WebhookEndpoint is a URL a user wants payment events delivered to
+type WebhookEndpoint struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	URL    string `gorm:"not null;size:2048" json:"url"`
+	Secret string `gorm:"not null;size:64" json:"-"`                  // HMAC key, only returned when the endpoint is created
+	Events string `gorm:"not null;type:text" json:"events,omitempty"` // Comma separated event filter, empty means all
+	Active bool   `gorm:"not null;default:true" json:"active"`
+
+	// Relations
+	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// TableName specifies the table name for WebhookEndpoint
+func (WebhookEndpoint) TableName() string {
+	return "webhook_endpoints"
+}
+
+// WebhookDeliveryStatus represents the state of a queued delivery
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed" // Gave up after the maximum number of attempts
+)
+
+// WebhookDelivery is one event queued for, or sent to, an endpoint
+type WebhookDelivery struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	WebhookEndpointID uint                  `gorm:"not null;index" json:"webhook_endpoint_id"`
+	EventID           string                `gorm:"not null;size:40;index" json:"event_id"`
+	EventType         string                `gorm:"not null;size:50" json:"event_type"`
+	Payload           string                `gorm:"not null;type:text" json:"payload"`
+	Status            WebhookDeliveryStatus `gorm:"not null;size:20;index" json:"status"`
+
+	Attempts       int        `gorm:"not null" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"not null;index" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `gorm:"type:text" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+
+	// Relations
+	WebhookEndpoint WebhookEndpoint `gorm:"foreignKey:WebhookEndpointID;constraint:OnDelete:CASCADE;" json:"-"`
+}
+
+// TableName specifies the table name for WebhookDelivery
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -0,0 +1,109 @@
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"mime"
+	"net/smtp"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// templates holds one set per file, since every file defines its own "subject" and "body"
+var templates = parseTemplates()
+
+func parseTemplates() map[string]*template.Template {
+	entries, err := templateFiles.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+
+	sets := make(map[string]*template.Template, len(entries))
+	for _, e := range entries {
+		sets[e.Name()] = template.Must(template.ParseFS(templateFiles, "templates/"+e.Name()))
+	}
+	return sets
+}
+
+var ErrNotConfigured = errors.New("SMTP is not configured")
+
+// smtpConfig is read from SMTP_HOST, SMTP_PORT, SMTP_USER, SMTP_PASS and
+// SMTP_FROM. Without SMTP_USER mail is sent unauthenticated, which suits a
+// local SMTP stand-in such as MailHog on port 1025.
+type smtpConfig struct {
+	host string
+	port string
+	user string
+	pass string
+	from string
+}
+
+func loadConfig() smtpConfig {
+	return smtpConfig{
+		host: os.Getenv("SMTP_HOST"),
+		port: getEnv("SMTP_PORT", "1025"),
+		user: os.Getenv("SMTP_USER"),
+		pass: os.Getenv("SMTP_PASS"),
+		from: getEnv("SMTP_FROM", "payments@localhost"),
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+// render executes the "subject" and "body" blocks of a template file such as "payment_due.tmpl"
+func render(name string, data interface{}) (string, string, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return "", "", fmt.Errorf("unknown email template %s", name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
+
+// sendMail renders the template and sends it to a single recipient
+func sendMail(to string, name string, data interface{}) error {
+	cfg := loadConfig()
+	if cfg.host == "" {
+		return ErrNotConfigured
+	}
+
+	subject, body, err := render(name, data)
+	if err != nil {
+		return err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.from)
+	fmt.Fprintf(&msg, "To: %s\r\n", to)
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+
+	var auth smtp.Auth
+	if cfg.user != "" {
+		auth = smtp.PlainAuth("", cfg.user, cfg.pass, cfg.host)
+	}
+
+	return smtp.SendMail(cfg.host+":"+cfg.port, auth, cfg.from, []string{to}, msg.Bytes())
+}
@@ -0,0 +1,69 @@
+package notifications
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	runAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		file    string
+		data    interface{}
+		subject string
+		body    []string
+	}{
+		{
+			file:    "payment_due.tmpl",
+			data:    dueEmail{TemplateName: "Rent", RunAt: runAt, Transfers: []string{"10 USDC to 0xabc"}},
+			subject: `Payment "Rent" is due in less than 24 hours`,
+			body:    []string{"2026-03-01 09:00 UTC", "- 10 USDC to 0xabc"},
+		},
+		{
+			file:    "payment_executed.tmpl",
+			data:    executionEmail{TemplateName: "Rent", ChainID: 8453, TxHash: "0xfeed", Transfers: []string{"10 USDC"}},
+			subject: `Payment "Rent" was executed`,
+			body:    []string{"on chain 8453", "Transaction: 0xfeed", "- 10 USDC"},
+		},
+		{
+			file:    "payment_failed.tmpl",
+			data:    executionEmail{TemplateName: "Rent", ChainID: 8453, Reason: "insufficient funds", Recurring: true},
+			subject: `Payment "Rent" failed`,
+			body:    []string{"Reason: insufficient funds", "retried at its next run"},
+		},
+		{
+			file:    "permit_expiring.tmpl",
+			data:    permitEmail{TemplateName: "Rent", Symbol: "USDC", Deadline: runAt},
+			subject: `The USDC permit of "Rent" expires soon`,
+			body:    []string{"USDC"},
+		},
+		{
+			file:    "verify_email.tmpl",
+			data:    verifyEmail{Address: "0xabc", Link: "https://example.com/verify?token=t"},
+			subject: "Confirm your email address",
+			body:    []string{"https://example.com/verify?token=t"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			subject, body, err := render(tt.file, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if subject != tt.subject {
+				t.Errorf("subject = %q, want %q", subject, tt.subject)
+			}
+			for _, want := range tt.body {
+				if !strings.Contains(body, want) {
+					t.Errorf("body does not contain %q:\n%s", want, body)
+				}
+			}
+		})
+	}
+
+	if _, _, err := render("missing.tmpl", nil); err == nil {
+		t.Error("rendering an unknown template succeeded")
+	}
+}
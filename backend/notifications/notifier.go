@@ -0,0 +1,261 @@
+package notifications
+
+import (
+	"backend/database"
+	"backend/events"
+	"backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// dueWindow is how far ahead users are reminded of upcoming runs
+	dueWindow       = 24 * time.Hour
+	duePollInterval = 10 * time.Minute
+)
+
+type dueEmail struct {
+	TemplateName string
+	RunAt        time.Time
+	Transfers    []string
+}
+
+type permitEmail struct {
+	TemplateName string
+	Symbol       string
+	Deadline     time.Time
+}
+
+type executionEmail struct {
+	TemplateName string
+	ChainID      uint64
+	TxHash       string
+	Reason       string
+	Recurring    bool
+	Transfers    []string
+}
+
+// Start emails users about executions and expiring permits as they are
+// published and runs the due-payment reminder watcher
+func Start() {
+	events.Subscribe(onEvent)
+	go dueWatcher()
+}
+
+func onEvent(event events.Event) {
+	if permit, ok := event.Data.(*models.TokenPermit); ok && event.Type == events.PermitExpiring {
+		go notifyPermitExpiring(event.UserID, *permit)
+		return
+	}
+
+	execution, ok := event.Data.(*models.Execution)
+	if !ok {
+		return
+	}
+
+	var kind models.NotificationKind
+	switch event.Type {
+	case events.ExecutionConfirmed:
+		kind = models.NotificationKindPaymentExecuted
+	case events.ExecutionFailed:
+		kind = models.NotificationKindPaymentFailed
+	default:
+		return
+	}
+
+	go notifyExecution(event.UserID, kind, *execution)
+}
+
+// PreferencesFor returns the user's notification preferences, defaulting to all enabled
+func PreferencesFor(userID uint) (models.NotificationPreferences, error) {
+	prefs := models.NotificationPreferences{
+		UserID:          userID,
+		PaymentDue:      true,
+		PaymentExecuted: true,
+		PaymentFailed:   true,
+	}
+	err := database.DB.Where("user_id = ?", userID).First(&prefs).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return prefs, nil
+	}
+	return prefs, err
+}
+
+// recipient loads the user if they have a verified email and want this kind of notification
+func recipient(userID uint, kind models.NotificationKind) (models.User, bool) {
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return user, false
+	}
+	if user.Email == nil || user.EmailVerifiedAt == nil {
+		return user, false
+	}
+
+	prefs, err := PreferencesFor(userID)
+	if err != nil {
+		log.Printf("could not load notification preferences: userId=%d, err=%v", userID, err)
+		return user, false
+	}
+	return user, prefs.Wants(kind)
+}
+
+// deliver sends the email and records the attempt
+func deliver(user models.User, notification models.Notification, file string, data interface{}) {
+	notification.UserID = user.ID
+	notification.Email = *user.Email
+
+	if err := sendMail(*user.Email, file, data); err != nil {
+		log.Printf("could not send %s email: userId=%d, err=%v", notification.Kind, user.ID, err)
+		notification.Error = err.Error()
+	}
+
+	if err := database.DB.Create(&notification).Error; err != nil {
+		log.Printf("could not record notification: userId=%d, err=%v", user.ID, err)
+	}
+}
+
+func notifyExecution(userID uint, kind models.NotificationKind, execution models.Execution) {
+	user, ok := recipient(userID, kind)
+	if !ok {
+		return
+	}
+
+	var template models.PaymentTemplate
+	if err := database.DB.First(&template, execution.PaymentTemplateID).Error; err != nil {
+		log.Printf("could not load template %d for notification: %v", execution.PaymentTemplateID, err)
+		return
+	}
+
+	var transfers []models.ExecutionTransfer
+	database.DB.Preload("Asset").Where("execution_id = ?", execution.ID).Find(&transfers)
+
+	data := executionEmail{
+		TemplateName: template.Name,
+		ChainID:      execution.ChainID,
+		TxHash:       execution.TxHash,
+		Reason:       execution.Error,
+		Recurring:    template.RecurringInterval != nil && *template.RecurringInterval > 0,
+	}
+	for _, t := range transfers {
+		data.Transfers = append(data.Transfers, fmt.Sprintf("%v %s", t.Amount, t.Asset.Symbol))
+	}
+
+	file := "payment_executed.tmpl"
+	if kind == models.NotificationKindPaymentFailed {
+		file = "payment_failed.tmpl"
+	}
+
+	deliver(user, models.Notification{
+		Kind:              kind,
+		PaymentTemplateID: template.ID,
+		ExecutionID:       &execution.ID,
+	}, file, data)
+}
+
+// notifyPermitExpiring warns that a recurring template will stop paying once
+// its unused permit passes the deadline
+func notifyPermitExpiring(userID uint, permit models.TokenPermit) {
+	user, ok := recipient(userID, models.NotificationKindPermitExpiring)
+	if !ok {
+		return
+	}
+
+	var template models.PaymentTemplate
+	if err := database.DB.First(&template, permit.PaymentTemplateID).Error; err != nil {
+		log.Printf("could not load template %d for notification: %v", permit.PaymentTemplateID, err)
+		return
+	}
+
+	deliver(user, models.Notification{
+		Kind:              models.NotificationKindPermitExpiring,
+		PaymentTemplateID: template.ID,
+	}, "permit_expiring.tmpl", permitEmail{
+		TemplateName: template.Name,
+		Symbol:       permit.Asset.Symbol,
+		Deadline:     permit.Deadline,
+	})
+}
+
+// nextRun returns the first run of the template at or after now
+func nextRun(template models.PaymentTemplate, now time.Time) (time.Time, bool) {
+	if template.ScheduledAt == nil {
+		return time.Time{}, false
+	}
+
+	run := *template.ScheduledAt
+	if run.Before(now) {
+		if template.RecurringInterval == nil || *template.RecurringInterval <= 0 {
+			return time.Time{}, false
+		}
+		interval := time.Duration(*template.RecurringInterval) * time.Second
+		missed := now.Sub(run) / interval
+		run = run.Add((missed + 1) * interval)
+	}
+
+	if template.EndsAt != nil && run.After(*template.EndsAt) {
+		return time.Time{}, false
+	}
+	return run, true
+}
+
+func dueWatcher() {
+	ticker := time.NewTicker(duePollInterval)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		now := time.Now()
+
+		var templates []models.PaymentTemplate
+		err := database.DB.
+			Preload("Transfers.Asset").
+			Where("is_cancelled = ? AND scheduled_at IS NOT NULL AND scheduled_at <= ?", false, now.Add(dueWindow)).
+			Find(&templates).Error
+		if err != nil {
+			log.Printf("could not load due templates: %v", err)
+			continue
+		}
+
+		for _, t := range templates {
+			run, ok := nextRun(t, now)
+			if !ok || run.After(now.Add(dueWindow)) {
+				continue
+			}
+			remindDue(t, run)
+		}
+	}
+}
+
+// remindDue sends the reminder for one run unless it was already sent
+func remindDue(template models.PaymentTemplate, run time.Time) {
+	var sent int64
+	database.DB.Model(&models.Notification{}).
+		Where("kind = ? AND payment_template_id = ? AND run_at = ?", models.NotificationKindPaymentDue, template.ID, run).
+		Count(&sent)
+	if sent > 0 {
+		return
+	}
+
+	user, ok := recipient(template.UserID, models.NotificationKindPaymentDue)
+	if !ok {
+		return
+	}
+
+	data := dueEmail{TemplateName: template.Name, RunAt: run}
+	for _, t := range template.Transfers {
+		if t.FiatAmount != nil && t.FiatCurrency != nil {
+			data.Transfers = append(data.Transfers, fmt.Sprintf("%v %s in %s to %s", *t.FiatAmount, *t.FiatCurrency, t.Asset.Symbol, t.DestinationUserAddress))
+			continue
+		}
+		data.Transfers = append(data.Transfers, fmt.Sprintf("%v %s to %s", t.Amount, t.Asset.Symbol, t.DestinationUserAddress))
+	}
+
+	deliver(user, models.Notification{
+		Kind:              models.NotificationKindPaymentDue,
+		PaymentTemplateID: template.ID,
+		RunAt:             &run,
+	}, "payment_due.tmpl", data)
+}
@@ -0,0 +1,36 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"backend/models"
+)
+
+func TestNextRun(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	day := int64(86400)
+	end := start.Add(36 * time.Hour)
+
+	tests := []struct {
+		name     string
+		template models.PaymentTemplate
+		now      time.Time
+		want     time.Time
+		ok       bool
+	}{
+		{"one-off ahead", models.PaymentTemplate{ScheduledAt: &start}, start.Add(-time.Hour), start, true},
+		{"one-off passed", models.PaymentTemplate{ScheduledAt: &start}, start.Add(time.Hour), time.Time{}, false},
+		{"not scheduled", models.PaymentTemplate{}, start, time.Time{}, false},
+		{"recurring, next period", models.PaymentTemplate{ScheduledAt: &start, RecurringInterval: &day}, start.Add(30 * time.Hour), start.Add(48 * time.Hour), true},
+		{"recurring, next run after the end", models.PaymentTemplate{ScheduledAt: &start, RecurringInterval: &day, EndsAt: &end}, start.Add(30 * time.Hour), time.Time{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := nextRun(tt.template, tt.now)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("nextRun = %s, %v; want %s, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package notifications
+
+import (
+	"backend/database"
+	"backend/models"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const verificationTTL = 24 * time.Hour
+
+var ErrInvalidToken = errors.New("invalid or expired verification token")
+
+type verifyEmail struct {
+	Address string
+	Link    string
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RequestVerification stores a single-use token for the email and sends the
+// confirmation link to it. The user's email only changes once confirmed.
+func RequestVerification(user models.User, email string) error {
+	if loadConfig().host == "" {
+		return ErrNotConfigured
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	token := hex.EncodeToString(b)
+
+	verification := models.EmailVerification{
+		UserID:    user.ID,
+		Email:     email,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(verificationTTL),
+	}
+	if err := database.DB.Create(&verification).Error; err != nil {
+		return err
+	}
+
+	return sendMail(email, "verify_email.tmpl", verifyEmail{
+		Address: user.EthereumAddress,
+		Link:    getEnv("API_URL", "http://localhost:8080") + "/verify-email?token=" + token,
+	})
+}
+
+// ConfirmVerification sets the email behind the token as the user's verified address
+func ConfirmVerification(token string) (models.User, error) {
+	var user models.User
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		var verification models.EmailVerification
+		err := tx.
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", hashToken(token), time.Now()).
+			First(&verification).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidToken
+		} else if err != nil {
+			return err
+		}
+
+		if err := tx.First(&user, verification.UserID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		user.Email = &verification.Email
+		user.EmailVerifiedAt = &now
+		if err := tx.Model(&user).Select("Email", "EmailVerifiedAt").Updates(&user).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&verification).Update("used_at", &now).Error
+	})
+
+	return user, err
+}
@@ -0,0 +1,82 @@
+package pricing
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"backend/chain"
+)
+
+// Supported fiat currencies for fiat-denominated transfers
+const (
+	CurrencyUSD = "USD"
+	CurrencyEUR = "EUR"
+)
+
+var ErrNoSource = errors.New("no price source configured")
+
+// Source provides exchange rates between assets and fiat currencies
+type Source interface {
+	// Rate returns the price of one unit of the asset in the currency
+	Rate(symbol string, currency string) (float64, error)
+}
+
+// Default is the source the scheduler converts fiat amounts with
+var Default Source
+
+// IsSupportedCurrency reports whether transfers may be denominated in currency
+func IsSupportedCurrency(currency string) bool {
+	switch currency {
+	case CurrencyUSD, CurrencyEUR:
+		return true
+	}
+	return false
+}
+
+// FromEnv builds the source configured by PRICE_SOURCE_FILE, or nil when unset
+func FromEnv() (Source, error) {
+	path := os.Getenv("PRICE_SOURCE_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	source, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// Rate looks up a rate in the Default source
+func Rate(symbol string, currency string) (float64, error) {
+	if Default == nil {
+		return 0, ErrNoSource
+	}
+
+	rate, err := Default.Rate(strings.ToUpper(symbol), strings.ToUpper(currency))
+	if err != nil {
+		return 0, err
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("invalid %s/%s rate: %v", symbol, currency, rate)
+	}
+	return rate, nil
+}
+
+// Convert returns the asset amount worth fiatAmount at rate, both in asset
+// units and in the asset's smallest unit. Both are read as the decimals they
+// were written as, and the result is rounded half to even.
+func Convert(fiatAmount float64, rate float64, decimals uint8) (float64, *big.Int) {
+	fiat, _ := new(big.Rat).SetString(strconv.FormatFloat(fiatAmount, 'f', -1, 64))
+	price, _ := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+	value := chain.RatToBaseUnits(new(big.Rat).Quo(fiat, price), decimals)
+
+	// value / 10^decimals
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	units, _ := new(big.Rat).SetFrac(value, scale).Float64()
+	return units, value
+}
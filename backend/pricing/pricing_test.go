@@ -0,0 +1,32 @@
+package pricing
+
+import "testing"
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		fiatAmount float64
+		rate       float64
+		decimals   uint8
+		wantUnits  float64
+		wantValue  string
+	}{
+		{fiatAmount: 12.34, rate: 1, decimals: 6, wantUnits: 12.34, wantValue: "12340000"},
+		{fiatAmount: 0.3, rate: 1, decimals: 6, wantUnits: 0.3, wantValue: "300000"},
+		{fiatAmount: 0.29, rate: 1, decimals: 6, wantUnits: 0.29, wantValue: "290000"},
+		{fiatAmount: 100, rate: 1.08, decimals: 6, wantUnits: 92.592593, wantValue: "92592593"},
+		{fiatAmount: 0.07, rate: 1, decimals: 18, wantUnits: 0.07, wantValue: "70000000000000000"},
+		{fiatAmount: 1000, rate: 2500, decimals: 18, wantUnits: 0.4, wantValue: "400000000000000000"},
+		{fiatAmount: 10, rate: 3, decimals: 6, wantUnits: 3.333333, wantValue: "3333333"},
+		{fiatAmount: 20, rate: 3, decimals: 6, wantUnits: 6.666667, wantValue: "6666667"},
+		// Exactly halfway rounds to the even unit
+		{fiatAmount: 0.25, rate: 10, decimals: 2, wantUnits: 0.02, wantValue: "2"},
+		{fiatAmount: 0.35, rate: 10, decimals: 2, wantUnits: 0.04, wantValue: "4"},
+	}
+
+	for _, tt := range tests {
+		units, value := Convert(tt.fiatAmount, tt.rate, tt.decimals)
+		if value.String() != tt.wantValue || units != tt.wantUnits {
+			t.Errorf("Convert(%v, %v, %d) = %v, %s, want %v, %s", tt.fiatAmount, tt.rate, tt.decimals, units, value, tt.wantUnits, tt.wantValue)
+		}
+	}
+}
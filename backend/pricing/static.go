@@ -0,0 +1,43 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// StaticSource serves fixed rates keyed by asset symbol and then currency,
+// e.g. {"ETH": {"USD": 3000, "EUR": 2750}}
+type StaticSource map[string]map[string]float64
+
+// Rate implements Source
+func (s StaticSource) Rate(symbol string, currency string) (float64, error) {
+	rate, ok := s[symbol][currency]
+	if !ok {
+		return 0, fmt.Errorf("no %s/%s rate", symbol, currency)
+	}
+	return rate, nil
+}
+
+// LoadFile reads a StaticSource from a JSON file
+func LoadFile(path string) (StaticSource, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read price file: %w", err)
+	}
+
+	var raw StaticSource
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid price file: %w", err)
+	}
+
+	source := make(StaticSource, len(raw))
+	for symbol, rates := range raw {
+		source[strings.ToUpper(symbol)] = make(map[string]float64, len(rates))
+		for currency, rate := range rates {
+			source[strings.ToUpper(symbol)][strings.ToUpper(currency)] = rate
+		}
+	}
+	return source, nil
+}
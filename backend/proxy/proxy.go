@@ -0,0 +1,31 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Trusted reports whether the backend runs behind a proxy that sets
+// X-Forwarded-For, from TRUST_PROXY
+func Trusted() bool {
+	return os.Getenv("TRUST_PROXY") == "true"
+}
+
+// ClientIP is the address of the client. Behind a trusted proxy it is the last
+// X-Forwarded-For hop, which the proxy added; earlier hops come from the client
+// and are ignored. Otherwise it is the address of the connection.
+func ClientIP(r *http.Request) string {
+	if Trusted() {
+		hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+		if ip := net.ParseIP(strings.TrimSpace(hops[len(hops)-1])); ip != nil {
+			return ip.String()
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,43 @@
+package proxy
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		remoteAddr   string
+		forwardedFor string
+		want         string
+	}{
+		{name: "connection", remoteAddr: "198.51.100.1:4000", want: "198.51.100.1"},
+		{name: "forwarded header ignored", remoteAddr: "198.51.100.1:4000", forwardedFor: "203.0.113.9", want: "198.51.100.1"},
+		{name: "trusted proxy", env: map[string]string{"TRUST_PROXY": "true"}, remoteAddr: "10.0.0.2:4000", forwardedFor: "203.0.113.9", want: "203.0.113.9"},
+		{name: "last hop behind the proxy", env: map[string]string{"TRUST_PROXY": "true"}, remoteAddr: "10.0.0.2:4000", forwardedFor: "192.0.2.7, 203.0.113.9", want: "203.0.113.9"},
+		{name: "proxy without the header", env: map[string]string{"TRUST_PROXY": "true"}, remoteAddr: "10.0.0.2:4000", want: "10.0.0.2"},
+		{name: "rate limit setting is not read", env: map[string]string{"RATE_LIMIT_TRUST_PROXY": "true"}, remoteAddr: "10.0.0.2:4000", forwardedFor: "203.0.113.9", want: "10.0.0.2"},
+		{name: "address without port", remoteAddr: "198.51.100.1", want: "198.51.100.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TRUST_PROXY", "")
+			t.Setenv("RATE_LIMIT_TRUST_PROXY", "")
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if got := ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package ratelimit
+
+import (
+	"time"
+
+	"backend/database"
+	"backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DBStore keeps buckets in the database so every instance shares them
+type DBStore struct{}
+
+// Take implements Store; the bucket row is locked while it is updated
+func (DBStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	var result Result
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		fresh := models.RateLimitBucket{BucketKey: key, Tokens: float64(limit.Burst), UpdatedAt: now}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&fresh).Error; err != nil {
+			return err
+		}
+
+		var b models.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&b, "bucket_key = ?", key).Error; err != nil {
+			return err
+		}
+
+		b.Tokens, result = refill(b.Tokens, b.UpdatedAt, limit, now)
+		return tx.Model(&b).Updates(map[string]interface{}{"tokens": b.Tokens, "updated_at": now}).Error
+	})
+	return result, err
+}
@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// idleBucketTTL is how long an untouched bucket is kept; by then it is full anyway
+const idleBucketTTL = time.Hour
+
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+}
+
+// MemoryStore keeps buckets in process, which is enough for a single instance
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// NewMemoryStore returns a MemoryStore that drops idle buckets in the background
+func NewMemoryStore() *MemoryStore {
+	s := &MemoryStore{buckets: make(map[string]*bucket)}
+	go s.sweep()
+	return s
+}
+
+// Take implements Store
+func (s *MemoryStore) Take(key string, limit Limit, now time.Time) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updatedAt: now}
+		s.buckets[key] = b
+	}
+
+	var result Result
+	b.tokens, result = refill(b.tokens, b.updatedAt, limit, now)
+	b.updatedAt = now
+	return result, nil
+}
+
+func (s *MemoryStore) sweep() {
+	for range time.Tick(time.Minute) {
+		cutoff := time.Now().Add(-idleBucketTTL)
+		s.mu.Lock()
+		for key, b := range s.buckets {
+			if b.updatedAt.Before(cutoff) {
+				delete(s.buckets, key)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
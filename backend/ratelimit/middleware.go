@@ -0,0 +1,151 @@
+package ratelimit
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"backend/handlers"
+	"backend/jwtLogic"
+	"backend/proxy"
+
+	"github.com/gorilla/mux"
+)
+
+// Class groups routes sharing a limit
+type Class string
+
+const (
+	ClassDefault   Class = "default"
+	ClassAuth      Class = "auth"
+	ClassTemplates Class = "templates"
+)
+
+// Limiter applies a token bucket per client IP, signed-in address and API key
+type Limiter struct {
+	Store  Store
+	Limits map[Class]Limit
+	// Routes maps "METHOD /path/{template}" to its class; other routes use ClassDefault
+	Routes map[string]Class
+}
+
+// FromEnv builds a Limiter from RATE_LIMIT_DEFAULT, RATE_LIMIT_AUTH,
+// RATE_LIMIT_TEMPLATES and RATE_LIMIT_STORE. Client IPs follow TRUST_PROXY.
+func FromEnv(routes map[string]Class) (*Limiter, error) {
+	limiter := &Limiter{
+		Limits: make(map[Class]Limit),
+		Routes: routes,
+	}
+
+	for class, c := range map[Class]struct{ env, fallback string }{
+		ClassDefault:   {"RATE_LIMIT_DEFAULT", "120/m"},
+		ClassAuth:      {"RATE_LIMIT_AUTH", "10/m"},
+		ClassTemplates: {"RATE_LIMIT_TEMPLATES", "20/m"},
+	} {
+		value := os.Getenv(c.env)
+		if value == "" {
+			value = c.fallback
+		}
+		limit, err := ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", c.env, err)
+		}
+		limiter.Limits[class] = limit
+	}
+
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "", "memory":
+		limiter.Store = NewMemoryStore()
+	case "db":
+		limiter.Store = DBStore{}
+	default:
+		return nil, fmt.Errorf("unknown RATE_LIMIT_STORE %q", os.Getenv("RATE_LIMIT_STORE"))
+	}
+	return limiter, nil
+}
+
+// Middleware rejects requests over any of their buckets with 429 and reports
+// the tightest bucket in RateLimit-* headers
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		class := l.classify(r)
+		limit := l.Limits[class]
+		now := time.Now()
+
+		var tightest *Result
+		var retryAfter time.Duration
+		allowed := true
+		for _, identity := range l.identities(r) {
+			result, err := l.Store.Take(string(class)+":"+identity, limit, now)
+			if err != nil {
+				// Fail open rather than lock everyone out when the store is down
+				log.Printf("rate limit store error: %v", err)
+				continue
+			}
+			if !result.Allowed {
+				allowed = false
+				retryAfter = max(retryAfter, result.RetryAfter)
+			}
+			if tightest == nil || result.Remaining < tightest.Remaining {
+				tightest = &result
+			}
+		}
+
+		if tightest != nil {
+			w.Header().Set("RateLimit-Limit", fmt.Sprint(limit.Burst))
+			w.Header().Set("RateLimit-Remaining", fmt.Sprint(tightest.Remaining))
+			w.Header().Set("RateLimit-Reset", fmt.Sprint(ceilSeconds(tightest.Reset)))
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Burst, ceilSeconds(limit.Window)))
+		}
+		if !allowed {
+			w.Header().Set("Retry-After", fmt.Sprint(ceilSeconds(retryAfter)))
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (l *Limiter) classify(r *http.Request) Class {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ClassDefault
+	}
+	path, err := route.GetPathTemplate()
+	if err != nil {
+		return ClassDefault
+	}
+	if class, ok := l.Routes[r.Method+" "+path]; ok {
+		return class
+	}
+	return ClassDefault
+}
+
+// identities lists the buckets a request counts against. The address counts
+// once its token verifies and the API key once it matches an issued key, so
+// forged ones cannot spend another client's bucket; the IP bucket always applies.
+func (l *Limiter) identities(r *http.Request) []string {
+	identities := []string{"ip:" + proxy.ClientIP(r)}
+
+	if cookie, err := r.Cookie("token"); err == nil {
+		if address, _, err := jwtLogic.ParseToken(cookie.Value); err == nil {
+			identities = append(identities, "address:"+strings.ToLower(address))
+		}
+	}
+
+	if rawKey, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); found && strings.HasPrefix(rawKey, handlers.APIKeyPrefix) {
+		if key, err := handlers.VerifyAPIKey(rawKey); err == nil {
+			identities = append(identities, "key:"+key.Prefix)
+		}
+	}
+	return identities
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
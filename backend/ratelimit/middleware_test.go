@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/testdb"
+)
+
+// issuedKey is the only API key the test database knows
+const issuedKey = "gpk_abcdefghijklmnop"
+
+// useIssuedKey serves issuedKey from the api_keys table
+func useIssuedKey(t *testing.T) {
+	sum := sha256.Sum256([]byte(issuedKey))
+	testdb.Use(t, func(query string, args []driver.Value) (testdb.Rows, error) {
+		if !strings.Contains(query, "FROM `api_keys`") {
+			return testdb.Rows{}, errors.New("unexpected query: " + query)
+		}
+		rows := testdb.Rows{Columns: []string{"id", "prefix", "key_hash"}}
+		if len(args) > 0 && args[0] == issuedKey[:12] {
+			rows.Values = append(rows.Values, []driver.Value{int64(1), issuedKey[:12], hex.EncodeToString(sum[:])})
+		}
+		return rows, nil
+	})
+}
+
+// stubStore hands out a fixed result per identity
+type stubStore map[string]Result
+
+func (s stubStore) Take(key string, _ Limit, _ time.Time) (Result, error) {
+	return s[key], nil
+}
+
+func TestMiddlewareHeaders(t *testing.T) {
+	limit := Limit{Rate: 0.5, Burst: 30, Window: time.Minute}
+
+	tests := []struct {
+		name    string
+		results stubStore
+		apiKey  string
+		status  int
+		headers map[string]string
+	}{
+		{
+			name: "allowed",
+			results: stubStore{
+				"default:ip:192.0.2.1": {Allowed: true, Remaining: 12, Reset: 35500 * time.Millisecond},
+			},
+			status: http.StatusOK,
+			headers: map[string]string{
+				"RateLimit-Limit":     "30",
+				"RateLimit-Remaining": "12",
+				"RateLimit-Reset":     "36",
+				"RateLimit-Policy":    "30;w=60",
+				"Retry-After":         "",
+			},
+		},
+		{
+			name: "limited",
+			results: stubStore{
+				"default:ip:192.0.2.1": {Remaining: 0, Reset: 59 * time.Second, RetryAfter: 1500 * time.Millisecond},
+			},
+			status: http.StatusTooManyRequests,
+			headers: map[string]string{
+				"RateLimit-Remaining": "0",
+				"RateLimit-Reset":     "59",
+				"Retry-After":         "2",
+			},
+		},
+		{
+			name: "tightest bucket is reported",
+			results: stubStore{
+				"default:ip:192.0.2.1":     {Allowed: true, Remaining: 20, Reset: 20 * time.Second},
+				"default:key:gpk_abcdefgh": {Remaining: 0, Reset: 60 * time.Second, RetryAfter: 2 * time.Second},
+			},
+			apiKey: issuedKey,
+			status: http.StatusTooManyRequests,
+			headers: map[string]string{
+				"RateLimit-Remaining": "0",
+				"RateLimit-Reset":     "60",
+				"Retry-After":         "2",
+			},
+		},
+		{
+			name: "forged key does not spend the issued key's bucket",
+			results: stubStore{
+				"default:ip:192.0.2.1":     {Allowed: true, Remaining: 20, Reset: 20 * time.Second},
+				"default:key:gpk_abcdefgh": {Remaining: 0, Reset: 60 * time.Second, RetryAfter: 2 * time.Second},
+			},
+			apiKey: "gpk_abcdefghforgedpart",
+			status: http.StatusOK,
+			headers: map[string]string{
+				"RateLimit-Remaining": "20",
+				"Retry-After":         "",
+			},
+		},
+	}
+
+	useIssuedKey(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := &Limiter{Store: tt.results, Limits: map[Class]Limit{ClassDefault: limit}}
+			handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			r := httptest.NewRequest(http.MethodGet, "/templates", nil)
+			r.RemoteAddr = "192.0.2.1:1234"
+			if tt.apiKey != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.apiKey)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("status = %d, want %d", w.Code, tt.status)
+			}
+			for name, want := range tt.headers {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
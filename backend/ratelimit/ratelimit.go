@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket refilling Rate tokens per second up to Burst
+type Limit struct {
+	Rate  float64
+	Burst int
+	// Window is the period the limit was configured with, reported in RateLimit-Policy
+	Window time.Duration
+}
+
+// ParseLimit reads limits written as "<requests>/<period>", e.g. "10/m" or "100/1h".
+// The bucket holds the whole allowance, so it can be spent in one burst.
+func ParseLimit(value string) (Limit, error) {
+	count, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q, expected <requests>/<period>", value)
+	}
+
+	n, err := strconv.Atoi(count)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("invalid request count in %q", value)
+	}
+
+	var window time.Duration
+	switch period {
+	case "s":
+		window = time.Second
+	case "m":
+		window = time.Minute
+	case "h":
+		window = time.Hour
+	default:
+		window, err = time.ParseDuration(period)
+		if err != nil || window <= 0 {
+			return Limit{}, fmt.Errorf("invalid period in %q", value)
+		}
+	}
+
+	return Limit{Rate: float64(n) / window.Seconds(), Burst: n, Window: window}, nil
+}
+
+// Result is the state of a bucket after taking a token from it
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next token, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps token buckets by key
+type Store interface {
+	Take(key string, limit Limit, now time.Time) (Result, error)
+}
+
+// refill adds the tokens earned since updatedAt and takes one if there is one
+func refill(tokens float64, updatedAt time.Time, limit Limit, now time.Time) (float64, Result) {
+	elapsed := now.Sub(updatedAt).Seconds()
+	if elapsed > 0 {
+		tokens = math.Min(float64(limit.Burst), tokens+elapsed*limit.Rate)
+	}
+
+	var result Result
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = seconds((1 - tokens) / limit.Rate)
+	}
+	result.Remaining = int(tokens)
+	result.Reset = seconds((float64(limit.Burst) - tokens) / limit.Rate)
+	return tokens, result
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
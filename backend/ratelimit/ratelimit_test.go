@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Limit
+		wantErr bool
+	}{
+		{value: "10/s", want: Limit{Rate: 10, Burst: 10, Window: time.Second}},
+		{value: "120/m", want: Limit{Rate: 2, Burst: 120, Window: time.Minute}},
+		{value: "36/h", want: Limit{Rate: 0.01, Burst: 36, Window: time.Hour}},
+		{value: "5/10s", want: Limit{Rate: 0.5, Burst: 5, Window: 10 * time.Second}},
+		{value: "100/1h", want: Limit{Rate: 100.0 / 3600, Burst: 100, Window: time.Hour}},
+		{value: "10", wantErr: true},
+		{value: "0/m", wantErr: true},
+		{value: "-1/m", wantErr: true},
+		{value: "ten/m", wantErr: true},
+		{value: "10/d", wantErr: true},
+		{value: "10/-1m", wantErr: true},
+		{value: "10/0s", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseLimit(tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ParseLimit(%q) = %+v, want an error", tt.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseLimit(%q): %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLimit(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefill(t *testing.T) {
+	limit := Limit{Rate: 1, Burst: 10, Window: 10 * time.Second}
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		tokens     float64
+		elapsed    time.Duration
+		wantTokens float64
+		want       Result
+	}{
+		{
+			name:       "full bucket",
+			tokens:     10,
+			wantTokens: 9,
+			want:       Result{Allowed: true, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:       "refill is capped at the burst",
+			tokens:     5,
+			elapsed:    time.Hour,
+			wantTokens: 9,
+			want:       Result{Allowed: true, Remaining: 9, Reset: time.Second},
+		},
+		{
+			name:       "partial refill",
+			tokens:     0.5,
+			elapsed:    time.Second,
+			wantTokens: 0.5,
+			want:       Result{Allowed: true, Remaining: 0, Reset: 9500 * time.Millisecond},
+		},
+		{
+			name:       "empty bucket",
+			tokens:     0.25,
+			wantTokens: 0.25,
+			want:       Result{Remaining: 0, Reset: 9750 * time.Millisecond, RetryAfter: 750 * time.Millisecond},
+		},
+		{
+			name:       "clock going backwards adds nothing",
+			tokens:     0,
+			elapsed:    -time.Minute,
+			wantTokens: 0,
+			want:       Result{Remaining: 0, Reset: 10 * time.Second, RetryAfter: time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, got := refill(tt.tokens, now.Add(-tt.elapsed), limit, now)
+			if tokens != tt.wantTokens {
+				t.Errorf("tokens = %v, want %v", tokens, tt.wantTokens)
+			}
+			if got != tt.want {
+				t.Errorf("result = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package scheduler
+
+import (
+	"backend/chain"
+	"backend/models"
+	"backend/pricing"
+	"fmt"
+	"math/big"
+)
+
+// resolveAmounts works out what each transfer pays in this run, converting
+// fiat-denominated transfers at the current rate. The returned values are in
+// the asset's smallest unit and follow the order of template.Transfers.
+func resolveAmounts(template models.PaymentTemplate) ([]models.ExecutionTransfer, []*big.Int, error) {
+	records := make([]models.ExecutionTransfer, len(template.Transfers))
+	values := make([]*big.Int, len(template.Transfers))
+
+	for i, t := range template.Transfers {
+		record := models.ExecutionTransfer{
+			TransferID:   t.ID,
+			AssetID:      t.AssetID,
+			Amount:       t.Amount,
+			FiatAmount:   t.FiatAmount,
+			FiatCurrency: t.FiatCurrency,
+		}
+		value := chain.ToBaseUnits(t.Amount, t.Asset.Decimals)
+
+		if t.FiatAmount != nil && t.FiatCurrency != nil {
+			rate, err := pricing.Rate(t.Asset.Symbol, *t.FiatCurrency)
+			if err != nil {
+				return nil, nil, fmt.Errorf("could not price %s in %s: %w", t.Asset.Symbol, *t.FiatCurrency, err)
+			}
+			record.Rate = &rate
+			record.Amount, value = pricing.Convert(*t.FiatAmount, rate, t.Asset.Decimals)
+		}
+
+		record.BaseUnits = value.String()
+		records[i] = record
+		values[i] = value
+	}
+
+	return records, values, nil
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"backend/authorization"
+	"backend/chain"
+	"backend/database"
+	"backend/models"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// checkAuthorization makes sure the template still matches what the user
+// signed and that this run, paying values, stays within the signed max totals
+func checkAuthorization(template models.PaymentTemplate, chainID uint64, values []*big.Int) error {
+	auth := template.Authorization
+	if auth == nil {
+		return errors.New("template has no signed authorisation")
+	}
+
+	if auth.ChainID != chainID {
+		return fmt.Errorf("authorisation was signed for chain %d", auth.ChainID)
+	}
+
+	typedData := authorization.PaymentTypedData(template, template.User.EthereumAddress, auth.ChainID, auth.MaxTotals, auth.Nonce)
+	digest, err := authorization.Digest(typedData)
+	if err != nil {
+		return err
+	}
+
+	if digest.Hex() != auth.Digest {
+		return errors.New("template content deviates from the signed authorisation")
+	}
+
+	if err := authorization.VerifyAccountSignature(digest, auth.Signature, template.User.EthereumAddress, auth.ChainID); err != nil {
+		return fmt.Errorf("authorisation signature: %w", err)
+	}
+
+	var previous []models.ExecutionTransfer
+	err = database.DB.
+		Joins("JOIN executions ON executions.id = execution_transfers.execution_id").
+		Where("executions.payment_template_id = ? AND executions.status <> ?", template.ID, models.ExecutionStatusFailed).
+		Find(&previous).Error
+	if err != nil {
+		return err
+	}
+
+	for _, m := range auth.MaxTotals {
+		total := big.NewInt(0)
+		for i, t := range template.Transfers {
+			if t.AssetID == m.AssetID {
+				total.Add(total, values[i])
+			}
+		}
+		for _, p := range previous {
+			if value, ok := new(big.Int).SetString(p.BaseUnits, 10); ok && p.AssetID == m.AssetID {
+				total.Add(total, value)
+			}
+		}
+
+		if total.Cmp(chain.ToBaseUnits(m.Amount, m.Asset.Decimals)) > 0 {
+			return fmt.Errorf("run would exceed the signed max total for %s", m.Asset.Symbol)
+		}
+	}
+
+	return nil
+}
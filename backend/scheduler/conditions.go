@@ -0,0 +1,229 @@
+package scheduler
+
+import (
+	"backend/chain"
+	"backend/database"
+	"backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// RepeatCooldown is the least time between two runs of a condition, whether
+// it repeats or its last run could not be submitted
+const RepeatCooldown = 5 * time.Minute
+
+// chainReader is the part of an ethclient.Client conditions are evaluated with
+type chainReader interface {
+	ethereum.ContractCaller
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+	SuggestGasPrice(ctx context.Context) (*big.Int, error)
+}
+
+// runJob executes a triggered template and reports whether its transaction was submitted
+var runJob = executePayments
+
+// ConditionWatcher evaluates conditional templates, polling each chain on its own interval
+func ConditionWatcher() {
+	for chainID, network := range chain.Networks {
+		go pollConditions(chainID, network.PollInterval)
+	}
+}
+
+func pollConditions(chainID uint64, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var conditions []models.PaymentCondition
+		err := database.DB.
+			Preload("Asset").
+			Preload("PaymentTemplate").
+			Joins("JOIN payment_templates ON payment_templates.id = payment_conditions.payment_template_id").
+			Where("payment_conditions.chain_id = ? AND payment_conditions.status = ?", chainID, models.ConditionStatusWaiting).
+			Where("payment_templates.is_cancelled = ?", false).
+			Find(&conditions).Error
+		if err != nil {
+			log.Printf("could not load conditions: chainId=%d, err=%v", chainID, err)
+			continue
+		}
+		if len(conditions) == 0 {
+			continue
+		}
+
+		client, err := chain.GetClient(chainID)
+		if err != nil {
+			log.Printf("could not dial chain for conditions: chainId=%d, err=%v", chainID, err)
+			continue
+		}
+
+		for _, c := range conditions {
+			checkCondition(client, c)
+		}
+		client.Close()
+	}
+}
+
+// checkCondition expires, skips or triggers a single waiting condition. A
+// condition that does not repeat is only marked triggered once its payment was
+// submitted; until then it is retried after RepeatCooldown.
+func checkCondition(client chainReader, condition models.PaymentCondition) {
+	now := time.Now()
+	if now.After(condition.ExpiresAt) {
+		database.DB.Model(&models.PaymentCondition{ID: condition.ID}).Update("status", models.ConditionStatusExpired)
+		recordExecution(condition.PaymentTemplate.UserID, &models.Execution{
+			PaymentTemplateID: condition.PaymentTemplateID,
+			TemplateVersion:   condition.PaymentTemplate.Version,
+			ChainID:           condition.ChainID,
+			Status:            models.ExecutionStatusFailed,
+			Error:             "condition was not met before it expired",
+		})
+		return
+	}
+
+	if !readyToRepeat(condition, now) {
+		return
+	}
+
+	met, err := evaluateCondition(client, condition)
+	database.DB.Model(&models.PaymentCondition{ID: condition.ID}).Update("last_checked_at", &now)
+	if err != nil {
+		log.Printf("could not evaluate condition: templateId=%d, err=%v", condition.PaymentTemplateID, err)
+		return
+	}
+	if !met {
+		return
+	}
+	// Stays waiting until the template is approved
+	if awaitingApproval(condition.PaymentTemplate) {
+		return
+	}
+
+	database.DB.Model(&models.PaymentCondition{ID: condition.ID}).Update("triggered_at", &now)
+
+	submitted := runJob(Job{
+		RunAt:      now,
+		TemplateId: condition.PaymentTemplateID,
+		Revision:   condition.PaymentTemplate.ScheduleRevision,
+	})
+	if submitted && !condition.Repeat {
+		database.DB.Model(&models.PaymentCondition{ID: condition.ID}).Update("status", models.ConditionStatusTriggered)
+	}
+}
+
+// readyToRepeat reports whether a condition may run again: its cooldown passed
+// and its last payment is no longer waiting for confirmation, as the on-chain
+// value it watches may not reflect that payment yet. Executions submitted
+// longer ago than confirmationTimeout are no longer watched and do not count.
+func readyToRepeat(condition models.PaymentCondition, now time.Time) bool {
+	if condition.TriggeredAt == nil {
+		return true
+	}
+	if now.Sub(*condition.TriggeredAt) < RepeatCooldown {
+		return false
+	}
+
+	var pending int64
+	err := database.DB.Model(&models.Execution{}).
+		Where("payment_template_id = ? AND status = ?", condition.PaymentTemplateID, models.ExecutionStatusSubmitted).
+		Where("created_at > ?", now.Add(-confirmationTimeout)).
+		Count(&pending).Error
+	if err != nil {
+		log.Printf("could not check pending executions: templateId=%d, err=%v", condition.PaymentTemplateID, err)
+		return false
+	}
+	return pending == 0
+}
+
+// evaluateCondition reads the on-chain value the condition watches and compares it with the threshold
+func evaluateCondition(client chainReader, condition models.PaymentCondition) (bool, error) {
+	ctx := context.Background()
+
+	threshold, ok := new(big.Int).SetString(condition.Threshold, 10)
+	if !ok {
+		return false, errors.New("invalid threshold")
+	}
+
+	var value *big.Int
+	switch condition.Type {
+	case models.ConditionTypeBalanceAbove:
+		if condition.Asset == nil || condition.PaymentTemplate == nil {
+			return false, errors.New("balance condition has no asset")
+		}
+
+		var account models.SmartAccount
+		err := database.DB.
+			Where("user_id = ? AND chain_id = ?", condition.PaymentTemplate.UserID, condition.ChainID).
+			First(&account).Error
+		if err != nil {
+			return false, fmt.Errorf("no smart account registered for chain %d", condition.ChainID)
+		}
+		holder := common.HexToAddress(account.Address)
+
+		if strings.EqualFold(condition.Asset.ContractAddress, chain.NativeAssetAddress) {
+			value, err = client.BalanceAt(ctx, holder, nil)
+			if err != nil {
+				return false, err
+			}
+			break
+		}
+
+		data, err := parsedABI.Pack("balanceOf", holder)
+		if err != nil {
+			return false, err
+		}
+		token := common.HexToAddress(condition.Asset.ContractAddress)
+		out, err := client.CallContract(ctx, ethereum.CallMsg{To: &token, Data: data}, nil)
+		if err != nil {
+			return false, err
+		}
+		value = new(big.Int).SetBytes(out)
+
+	case models.ConditionTypeGasPriceBelow:
+		gasPrice, err := client.SuggestGasPrice(ctx)
+		if err != nil {
+			return false, err
+		}
+		value = gasPrice
+
+	case models.ConditionTypeContractCall:
+		to := common.HexToAddress(condition.ContractAddress)
+		out, err := client.CallContract(ctx, ethereum.CallMsg{To: &to, Data: common.FromHex(condition.CallData)}, nil)
+		if err != nil {
+			return false, err
+		}
+		if len(out) < 32 {
+			return false, errors.New("view call returned less than 32 bytes")
+		}
+		value = new(big.Int).SetBytes(out[:32])
+
+	default:
+		return false, fmt.Errorf("unsupported condition type: %s", condition.Type)
+	}
+
+	return compare(value, condition.Comparator, threshold)
+}
+
+func compare(value *big.Int, comparator models.ConditionComparator, threshold *big.Int) (bool, error) {
+	cmp := value.Cmp(threshold)
+	switch comparator {
+	case models.ComparatorEqual:
+		return cmp == 0, nil
+	case models.ComparatorGreater:
+		return cmp > 0, nil
+	case models.ComparatorGreaterOrEqual:
+		return cmp >= 0, nil
+	case models.ComparatorLess:
+		return cmp < 0, nil
+	case models.ComparatorLessOrEqual:
+		return cmp <= 0, nil
+	}
+	return false, fmt.Errorf("unsupported comparator: %s", comparator)
+}
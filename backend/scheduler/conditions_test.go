@@ -0,0 +1,164 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/models"
+	"backend/testdb"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// gasPriceReader reports a fixed gas price and counts how often it was asked
+type gasPriceReader struct {
+	price *big.Int
+	reads int
+}
+
+func (r *gasPriceReader) CallContract(context.Context, ethereum.CallMsg, *big.Int) ([]byte, error) {
+	return nil, errors.New("unexpected call")
+}
+
+func (r *gasPriceReader) BalanceAt(context.Context, common.Address, *big.Int) (*big.Int, error) {
+	return nil, errors.New("unexpected balance read")
+}
+
+func (r *gasPriceReader) SuggestGasPrice(context.Context) (*big.Int, error) {
+	r.reads++
+	return r.price, nil
+}
+
+// conditionStatus returns the status last stored on the condition, or "" if none was
+func conditionStatus(db *testdb.DB) models.ConditionStatus {
+	updates := db.Execs("UPDATE `payment_conditions` SET `status`")
+	if len(updates) == 0 {
+		return ""
+	}
+	return models.ConditionStatus(updates[len(updates)-1].Args[0].(string))
+}
+
+func TestCheckCondition(t *testing.T) {
+	tests := []struct {
+		name         string
+		repeat       bool
+		triggeredAgo time.Duration // Zero for a condition that never triggered
+		pending      int64         // Submitted executions awaiting confirmation
+		gasPrice     int64         // The condition holds below 100
+		submitted    bool
+		wantReads    int
+		wantRun      bool
+		wantStatus   models.ConditionStatus
+	}{
+		{name: "not met", gasPrice: 200, wantReads: 1},
+		{name: "submitted", gasPrice: 50, submitted: true, wantReads: 1, wantRun: true, wantStatus: models.ConditionStatusTriggered},
+		{name: "not submitted stays waiting", gasPrice: 50, wantReads: 1, wantRun: true},
+		{name: "failed run is retried after the cooldown", triggeredAgo: RepeatCooldown + time.Second, gasPrice: 50, submitted: true, wantReads: 1, wantRun: true, wantStatus: models.ConditionStatusTriggered},
+		{name: "failed run is not retried within the cooldown", triggeredAgo: time.Minute, gasPrice: 50},
+		{name: "repeating stays waiting", repeat: true, gasPrice: 50, submitted: true, wantReads: 1, wantRun: true},
+		{name: "repeating within the cooldown", repeat: true, triggeredAgo: time.Minute, gasPrice: 50},
+		{name: "repeating waits for confirmation", repeat: true, triggeredAgo: time.Hour, pending: 1, gasPrice: 50},
+		{name: "repeating after confirmation", repeat: true, triggeredAgo: time.Hour, gasPrice: 50, submitted: true, wantReads: 1, wantRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var countQueries []string
+			db := testdb.Use(t, func(query string, _ []driver.Value) (testdb.Rows, error) {
+				if !strings.Contains(query, "count(*)") {
+					return testdb.Rows{}, errors.New("unexpected query: " + query)
+				}
+				countQueries = append(countQueries, query)
+				return testdb.Rows{Columns: []string{"count(*)"}, Values: [][]driver.Value{{tt.pending}}}, nil
+			})
+
+			var jobs []Job
+			previous := runJob
+			runJob = func(job Job) bool {
+				jobs = append(jobs, job)
+				return tt.submitted
+			}
+			t.Cleanup(func() { runJob = previous })
+
+			now := time.Now()
+			condition := models.PaymentCondition{
+				ID:                1,
+				PaymentTemplateID: 2,
+				ChainID:           8453,
+				Type:              models.ConditionTypeGasPriceBelow,
+				Comparator:        models.ComparatorLess,
+				Threshold:         "100",
+				Repeat:            tt.repeat,
+				ExpiresAt:         now.Add(24 * time.Hour),
+				Status:            models.ConditionStatusWaiting,
+				PaymentTemplate:   &models.PaymentTemplate{ID: 2, ScheduleRevision: 3, ApprovalStatus: models.ApprovalStatusNotRequired},
+			}
+			if tt.triggeredAgo != 0 {
+				triggeredAt := now.Add(-tt.triggeredAgo)
+				condition.TriggeredAt = &triggeredAt
+			}
+			reader := &gasPriceReader{price: big.NewInt(tt.gasPrice)}
+
+			checkCondition(reader, condition)
+
+			if reader.reads != tt.wantReads {
+				t.Errorf("gas price read %d times, want %d", reader.reads, tt.wantReads)
+			}
+			if ran := len(jobs) == 1; ran != tt.wantRun {
+				t.Errorf("ran = %v, want %v", ran, tt.wantRun)
+			}
+			if tt.wantRun && (jobs[0].TemplateId != 2 || jobs[0].Revision != 3) {
+				t.Errorf("ran %+v, want template 2 at revision 3", jobs[0])
+			}
+			if tt.wantRun && len(db.Execs("SET `triggered_at`")) != 1 {
+				t.Error("triggered_at was not recorded")
+			}
+			if got := conditionStatus(db); got != tt.wantStatus {
+				t.Errorf("status = %q, want %q", got, tt.wantStatus)
+			}
+			for _, q := range countQueries {
+				if !strings.Contains(q, "created_at >") {
+					t.Errorf("pending executions are counted without ignoring stale ones: %s", q)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckConditionExpired(t *testing.T) {
+	db := testdb.Use(t, nil)
+	previous := runJob
+	runJob = func(Job) bool {
+		t.Error("expired condition ran")
+		return false
+	}
+	t.Cleanup(func() { runJob = previous })
+
+	reader := &gasPriceReader{price: big.NewInt(1)}
+	checkCondition(reader, models.PaymentCondition{
+		ID:                1,
+		PaymentTemplateID: 2,
+		Type:              models.ConditionTypeGasPriceBelow,
+		Comparator:        models.ComparatorLess,
+		Threshold:         "100",
+		ExpiresAt:         time.Now().Add(-time.Minute),
+		Status:            models.ConditionStatusWaiting,
+		PaymentTemplate:   &models.PaymentTemplate{ID: 2},
+	})
+
+	if got := conditionStatus(db); got != models.ConditionStatusExpired {
+		t.Errorf("status = %q, want %q", got, models.ConditionStatusExpired)
+	}
+	if len(db.Execs("INSERT INTO `executions`")) != 1 {
+		t.Error("no failed execution was recorded")
+	}
+	if reader.reads != 0 {
+		t.Error("expired condition was evaluated")
+	}
+}
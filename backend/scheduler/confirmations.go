@@ -0,0 +1,120 @@
+package scheduler
+
+import (
+	"backend/audit"
+	"backend/chain"
+	"backend/database"
+	"backend/events"
+	"backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// confirmationTimeout is how long a submitted execution is watched for a receipt
+const confirmationTimeout = 30 * time.Minute
+
+// watchConfirmation polls for the execution's receipt and marks it confirmed,
+// or failed when the transaction reverted
+func watchConfirmation(userID uint, execution models.Execution) {
+	client, err := chain.GetClient(execution.ChainID)
+	if err != nil {
+		log.Printf("could not watch execution %d: %v", execution.ID, err)
+		return
+	}
+	defer client.Close()
+
+	hash := common.HexToHash(execution.TxHash)
+	interval := chain.Networks[execution.ChainID].PollInterval
+	deadline := time.Now().Add(confirmationTimeout)
+
+	for time.Now().Before(deadline) {
+		time.Sleep(interval)
+
+		receipt, err := client.TransactionReceipt(context.Background(), hash)
+		if errors.Is(err, ethereum.NotFound) {
+			continue
+		}
+		if err != nil {
+			log.Printf("could not read receipt of execution %d: %v", execution.ID, err)
+			continue
+		}
+
+		before := execution
+		blockNumber := receipt.BlockNumber.Uint64()
+		now := time.Now()
+		execution.BlockNumber = &blockNumber
+		execution.ConfirmedAt = &now
+
+		eventType := events.ExecutionConfirmed
+		action := audit.ActionExecutionConfirm
+		execution.Status = models.ExecutionStatusConfirmed
+		transferStatus := models.TransferStatusCompleted
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			eventType = events.ExecutionFailed
+			action = audit.ActionExecutionFail
+			execution.Status = models.ExecutionStatusFailed
+			execution.Error = "transaction reverted"
+			transferStatus = models.TransferStatusFailed
+		}
+
+		err = database.DB.Model(&models.Execution{ID: execution.ID}).Updates(map[string]interface{}{
+			"status":       execution.Status,
+			"error":        execution.Error,
+			"block_number": execution.BlockNumber,
+			"confirmed_at": execution.ConfirmedAt,
+		}).Error
+		if err != nil {
+			log.Printf("could not update execution %d: %v", execution.ID, err)
+		}
+
+		audit.Record(nil, audit.Entry{
+			UserID:     userID,
+			Actor:      audit.ActorSystem,
+			Action:     action,
+			TargetType: audit.TargetExecution,
+			TargetID:   execution.ID,
+			Before:     before,
+			After:      execution,
+		})
+
+		setTransferStatus(execution.PaymentTemplateID, transferStatus)
+		events.Publish(userID, eventType, &execution)
+		return
+	}
+
+	log.Printf("execution %d not mined within %s: txHash=%s", execution.ID, confirmationTimeout, execution.TxHash)
+	markUnknown(userID, execution)
+}
+
+// markUnknown gives up on an execution no receipt was seen for, so it stops
+// holding back repeating conditions
+func markUnknown(userID uint, execution models.Execution) {
+	before := execution
+	execution.Status = models.ExecutionStatusUnknown
+	execution.Error = fmt.Sprintf("no receipt within %s", confirmationTimeout)
+
+	err := database.DB.Model(&models.Execution{ID: execution.ID}).
+		Where("status = ?", models.ExecutionStatusSubmitted).
+		Updates(map[string]interface{}{"status": execution.Status, "error": execution.Error}).Error
+	if err != nil {
+		log.Printf("could not update execution %d: %v", execution.ID, err)
+		return
+	}
+
+	audit.Record(nil, audit.Entry{
+		UserID:     userID,
+		Actor:      audit.ActorSystem,
+		Action:     audit.ActionExecutionUnknown,
+		TargetType: audit.TargetExecution,
+		TargetID:   execution.ID,
+		Before:     before,
+		After:      execution,
+	})
+}
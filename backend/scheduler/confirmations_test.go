@@ -0,0 +1,25 @@
+package scheduler
+
+import (
+	"testing"
+
+	"backend/models"
+	"backend/testdb"
+)
+
+func TestMarkUnknown(t *testing.T) {
+	db := testdb.Use(t, nil)
+
+	markUnknown(1, models.Execution{ID: 5, PaymentTemplateID: 2, TxHash: "0x01", Status: models.ExecutionStatusSubmitted})
+
+	updates := db.Execs("UPDATE `executions`")
+	if len(updates) != 1 {
+		t.Fatalf("got %d execution updates, want 1", len(updates))
+	}
+	if updates[0].Args[1] != string(models.ExecutionStatusUnknown) {
+		t.Errorf("execution updated with %v, want status %q", updates[0].Args, models.ExecutionStatusUnknown)
+	}
+	if len(db.Execs("INSERT INTO `audit_events`")) != 1 {
+		t.Error("no audit event was recorded")
+	}
+}
@@ -0,0 +1,285 @@
+package scheduler
+
+import (
+	"backend/authorization"
+	"backend/chain"
+	"backend/database"
+	"backend/events"
+	"backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"gorm.io/gorm"
+)
+
+const erc2612ABI = `[{
+	"name":"permit",
+	"type":"function",
+	"stateMutability":"nonpayable",
+	"inputs":[
+		{"name":"owner","type":"address"},
+		{"name":"spender","type":"address"},
+		{"name":"value","type":"uint256"},
+		{"name":"deadline","type":"uint256"},
+		{"name":"v","type":"uint8"},
+		{"name":"r","type":"bytes32"},
+		{"name":"s","type":"bytes32"}
+	],
+	"outputs":[]
+},{
+	"name":"nonces",
+	"type":"function",
+	"stateMutability":"view",
+	"inputs":[{"name":"owner","type":"address"}],
+	"outputs":[{"name":"","type":"uint256"}]
+},{
+	"name":"DOMAIN_SEPARATOR",
+	"type":"function",
+	"stateMutability":"view",
+	"inputs":[],
+	"outputs":[{"name":"","type":"bytes32"}]
+}]`
+
+var parsedPermitABI, _ = abi.JSON(strings.NewReader(erc2612ABI))
+
+var permitTypeHash = crypto.Keccak256Hash([]byte("Permit(address owner,address spender,uint256 value,uint256 nonce,uint256 deadline)"))
+
+// PermitWarningWindow is how long before its deadline an unused permit of a
+// recurring template is reported as expiring
+const PermitWarningWindow = 72 * time.Hour
+
+// pull describes an asset the smart account takes from the user's address
+// with transferFrom instead of sending from its own balance
+type pull struct {
+	owner    common.Address
+	permit   *ethereum.CallMsg // nil once the permit is already applied on-chain
+	permitID uint
+}
+
+func callView(client ethereum.ContractCaller, token common.Address, method string, args ...interface{}) ([]interface{}, error) {
+	data, err := parsedPermitABI.Pack(method, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := client.CallContract(context.Background(), ethereum.CallMsg{To: &token, Data: data}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return parsedPermitABI.Unpack(method, out)
+}
+
+func permitNonce(client ethereum.ContractCaller, token common.Address, owner common.Address) (uint64, error) {
+	out, err := callView(client, token, "nonces", owner)
+	if err != nil {
+		return 0, fmt.Errorf("could not read permit nonce: %w", err)
+	}
+	return out[0].(*big.Int).Uint64(), nil
+}
+
+// permitDigest is the EIP-2612 hash the owner signs for the token's domain
+func permitDigest(domainSeparator [32]byte, permit models.TokenPermit) (common.Hash, error) {
+	value, ok := new(big.Int).SetString(permit.Value, 10)
+	if !ok {
+		return common.Hash{}, errors.New("invalid permit value")
+	}
+
+	structHash := crypto.Keccak256Hash(
+		permitTypeHash.Bytes(),
+		common.LeftPadBytes(common.HexToAddress(permit.Owner).Bytes(), 32),
+		common.LeftPadBytes(common.HexToAddress(permit.Spender).Bytes(), 32),
+		math.U256Bytes(value),
+		math.U256Bytes(new(big.Int).SetUint64(permit.Nonce)),
+		math.U256Bytes(big.NewInt(permit.Deadline.Unix())),
+	)
+
+	return crypto.Keccak256Hash([]byte{0x19, 0x01}, domainSeparator[:], structHash.Bytes()), nil
+}
+
+// VerifyPermit checks the permit signature against the token's on-chain
+// domain and that its nonce has not been used yet
+func VerifyPermit(permit *models.TokenPermit, asset models.Asset) error {
+	if strings.EqualFold(asset.ContractAddress, chain.NativeAssetAddress) {
+		return errors.New("native asset does not support permit")
+	}
+
+	if time.Now().After(permit.Deadline) {
+		return errors.New("permit deadline has passed")
+	}
+
+	client, err := chain.GetClient(asset.ChainID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	token := common.HexToAddress(asset.ContractAddress)
+	out, err := callView(client, token, "DOMAIN_SEPARATOR")
+	if err != nil {
+		return fmt.Errorf("%s does not support permit: %w", asset.Symbol, err)
+	}
+
+	nonce, err := permitNonce(client, token, common.HexToAddress(permit.Owner))
+	if err != nil {
+		return err
+	}
+	if nonce > permit.Nonce {
+		return errors.New("permit nonce has already been used")
+	}
+
+	digest, err := permitDigest(out[0].([32]byte), *permit)
+	if err != nil {
+		return err
+	}
+
+	return authorization.VerifySignature(digest, permit.Signature, permit.Owner)
+}
+
+func encodePermit(permit models.TokenPermit) (*ethereum.CallMsg, error) {
+	sig := common.FromHex(permit.Signature)
+	if len(sig) != 65 {
+		return nil, authorization.ErrInvalidSignatureLength
+	}
+
+	v := sig[64]
+	if v < 27 {
+		v += 27
+	}
+
+	value, ok := new(big.Int).SetString(permit.Value, 10)
+	if !ok {
+		return nil, errors.New("invalid permit value")
+	}
+
+	data, err := parsedPermitABI.Pack(
+		"permit",
+		common.HexToAddress(permit.Owner),
+		common.HexToAddress(permit.Spender),
+		value,
+		big.NewInt(permit.Deadline.Unix()),
+		v,
+		common.BytesToHash(sig[:32]),
+		common.BytesToHash(sig[32:64]),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	token := common.HexToAddress(permit.Asset.ContractAddress)
+	return &ethereum.CallMsg{To: &token, Data: data}, nil
+}
+
+// preparePulls picks the latest permit per asset of the template. Permits
+// the token already consumed only switch the asset to transferFrom and unused
+// ones also add their permit call. An expired unused permit fails the run
+// rather than paying from the smart account's own balance instead.
+func preparePulls(client ethereum.ContractCaller, template models.PaymentTemplate) (map[uint]pull, error) {
+	latest := make(map[uint]models.TokenPermit)
+	for _, p := range template.Permits {
+		if current, ok := latest[p.AssetID]; !ok || p.ID > current.ID {
+			latest[p.AssetID] = p
+		}
+	}
+
+	pulls := make(map[uint]pull)
+	for assetID, p := range latest {
+		owner := common.HexToAddress(p.Owner)
+		if p.ConsumedAt != nil {
+			pulls[assetID] = pull{owner: owner}
+			continue
+		}
+
+		nonce, err := permitNonce(client, common.HexToAddress(p.Asset.ContractAddress), owner)
+		if err != nil {
+			return nil, err
+		}
+		if nonce > p.Nonce {
+			now := time.Now()
+			if err := database.DB.Model(&models.TokenPermit{}).Where("id = ?", p.ID).Update("consumed_at", &now).Error; err != nil {
+				return nil, fmt.Errorf("could not mark permit %d consumed: %w", p.ID, err)
+			}
+			pulls[assetID] = pull{owner: owner}
+			continue
+		}
+
+		if time.Now().After(p.Deadline) {
+			return nil, fmt.Errorf("permit for %s expired unused at %s, sign a new one", p.Asset.Symbol, p.Deadline.Format(time.RFC3339))
+		}
+
+		call, err := encodePermit(p)
+		if err != nil {
+			return nil, err
+		}
+		pulls[assetID] = pull{owner: owner, permit: call, permitID: p.ID}
+	}
+	return pulls, nil
+}
+
+// markPermitsConsumed records that the permits sent with a run are now applied
+func markPermitsConsumed(pulls map[uint]pull) {
+	now := time.Now()
+	for _, p := range pulls {
+		if p.permit == nil {
+			continue
+		}
+		if err := database.DB.Model(&models.TokenPermit{}).Where("id = ?", p.permitID).Update("consumed_at", &now).Error; err != nil {
+			log.Printf("could not mark permit consumed: permitId=%d, err=%v", p.permitID, err)
+		}
+	}
+}
+
+// ExpiringPermitsQuery selects unused permits of active recurring templates
+// whose deadline falls within PermitWarningWindow
+func ExpiringPermitsQuery() *gorm.DB {
+	return database.DB.
+		Joins("JOIN payment_templates ON payment_templates.id = token_permits.payment_template_id").
+		Where("token_permits.consumed_at IS NULL").
+		Where("token_permits.deadline < ?", time.Now().Add(PermitWarningWindow)).
+		Where("payment_templates.is_cancelled = ? AND payment_templates.recurring_interval > 0", false)
+}
+
+// PermitWatcher periodically warns about permits that are about to expire
+// unused, publishing a permit.expiring event once per permit
+func PermitWatcher() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for ; ; <-ticker.C {
+		var permits []models.TokenPermit
+		err := ExpiringPermitsQuery().
+			Preload("Asset").
+			Where("token_permits.expiry_warned_at IS NULL").
+			Find(&permits).Error
+		if err != nil {
+			log.Printf("could not load expiring permits: %v", err)
+			continue
+		}
+
+		now := time.Now()
+		for _, p := range permits {
+			log.Printf("permit expires unused at %s: templateId=%d, permitId=%d", p.Deadline.String(), p.PaymentTemplateID, p.ID)
+			if err := database.DB.Model(&models.TokenPermit{}).Where("id = ?", p.ID).Update("expiry_warned_at", &now).Error; err != nil {
+				log.Printf("could not mark permit warned: permitId=%d, err=%v", p.ID, err)
+				continue
+			}
+
+			var template models.PaymentTemplate
+			if err := database.DB.Select("id", "user_id").First(&template, p.PaymentTemplateID).Error; err != nil {
+				log.Printf("could not load template of permit %d: %v", p.ID, err)
+				continue
+			}
+			events.Publish(template.UserID, events.PermitExpiring, &p)
+		}
+	}
+}
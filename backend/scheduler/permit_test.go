@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"backend/models"
+	"backend/testdb"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// nonceCaller answers nonces(owner) with the same nonce for every owner
+type nonceCaller uint64
+
+func (n nonceCaller) CallContract(_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	return parsedPermitABI.Methods["nonces"].Outputs.Pack(new(big.Int).SetUint64(uint64(n)))
+}
+
+func TestPreparePulls(t *testing.T) {
+	owner := "0x6969174FD72466430a46e18234D0b530c9FD5f49"
+	usdc := models.Asset{ID: 1, Symbol: "USDC", ContractAddress: "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", ChainID: 8453}
+	signature := "0x" + strings.Repeat("11", 64) + "1b"
+	consumedAt := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name         string
+		permit       models.TokenPermit
+		chainNonce   uint64
+		wantErr      string
+		wantPermit   bool
+		wantConsumed bool
+	}{
+		{
+			name:   "already consumed",
+			permit: models.TokenPermit{ConsumedAt: &consumedAt, Deadline: time.Now().Add(-time.Hour)},
+		},
+		{
+			name:         "applied on-chain since",
+			permit:       models.TokenPermit{Nonce: 4, Deadline: time.Now().Add(-time.Hour)},
+			chainNonce:   5,
+			wantConsumed: true,
+		},
+		{
+			name:       "unused",
+			permit:     models.TokenPermit{Nonce: 5, Deadline: time.Now().Add(time.Hour)},
+			chainNonce: 5,
+			wantPermit: true,
+		},
+		{
+			name:       "expired unused",
+			permit:     models.TokenPermit{Nonce: 5, Deadline: time.Now().Add(-time.Minute)},
+			chainNonce: 5,
+			wantErr:    "permit for USDC expired unused",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := testdb.Use(t, nil)
+
+			permit := tt.permit
+			permit.ID, permit.AssetID, permit.Asset = 9, usdc.ID, usdc
+			permit.Owner, permit.Spender, permit.Value, permit.Signature = owner, owner, "1000000", signature
+			template := models.PaymentTemplate{ID: 2, Permits: []models.TokenPermit{permit}}
+
+			pulls, err := preparePulls(nonceCaller(tt.chainNonce), template)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			p, ok := pulls[usdc.ID]
+			if !ok || p.owner != common.HexToAddress(owner) {
+				t.Fatalf("got pulls %+v, want one from %s", pulls, owner)
+			}
+			if (p.permit != nil) != tt.wantPermit {
+				t.Errorf("permit call = %v, want %v", p.permit != nil, tt.wantPermit)
+			}
+			if consumed := len(db.Execs("SET `consumed_at`")) == 1; consumed != tt.wantConsumed {
+				t.Errorf("marked consumed = %v, want %v", consumed, tt.wantConsumed)
+			}
+		})
+	}
+}
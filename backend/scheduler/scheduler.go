@@ -1,7 +1,11 @@
 package scheduler
 
 import (
+	"backend/approvals"
+	"backend/audit"
+	"backend/chain"
 	"backend/database"
+	"backend/events"
 	"backend/models"
 	"context"
 	"crypto/ecdsa"
@@ -26,6 +30,21 @@ import (
 )
 
 const erc20ABI = `[{
+	"name":"transfer",
+	"type":"function",
+	"stateMutability":"nonpayable",
+	"inputs":[
+		{"name":"to","type":"address"},
+		{"name":"value","type":"uint256"}
+	],
+	"outputs":[{"type":"bool"}]
+},{
+	"name":"balanceOf",
+	"type":"function",
+	"stateMutability":"view",
+	"inputs":[{"name":"owner","type":"address"}],
+	"outputs":[{"name":"","type":"uint256"}]
+},{
 	"name":"transferFrom",
 	"type":"function",
 	"stateMutability":"nonpayable",
@@ -40,12 +59,11 @@ const erc20ABI = `[{
 type Job struct {
 	RunAt      time.Time
 	TemplateId uint
+	Revision   uint // ScheduleRevision of the template when the run was queued
 }
 
 var parsedABI, _ = abi.JSON(strings.NewReader(erc20ABI))
 
-var EXECUTOR_ADDRESS = "0x8b789Eb02B50c7c91Ff3eF2acF74d98d4DcC93fE"
-
 // @TODO close the chan
 var JobsChan = make(chan Job, 100)
 
@@ -85,10 +103,13 @@ func encodeExecute(
 
 	txs := make([]Transaction, len(calls))
 	for i, c := range calls {
-		zero := big.NewInt(0)
+		value := big.NewInt(0)
+		if c.Value != nil {
+			value = c.Value
+		}
 		txs[i] = Transaction{
 			To:    *c.To,
-			Value: zero,
+			Value: value,
 			Data:  c.Data,
 		}
 	}
@@ -116,31 +137,58 @@ func walletFromSeed(seed string) (*ecdsa.PrivateKey, common.Address, error) {
 	return privKey, account.Address, nil
 }
 
-func encodeCalls(template models.PaymentTemplate) []ethereum.CallMsg {
+// ExecutorAddress returns the address of the backend account derived from EXECUTOR_SEED
+func ExecutorAddress() (common.Address, error) {
+	_, addr, err := walletFromSeed(os.Getenv("EXECUTOR_SEED"))
+	return addr, err
+}
+
+// encodeCalls builds the calls the smart account runs on its own behalf, so
+// tokens are moved with transfer and native coin with a plain value call.
+// Assets in pulls are taken from the owner with transferFrom instead, after
+// the permit call when it still has to be applied.
+func encodeCalls(template models.PaymentTemplate, values []*big.Int, pulls map[uint]pull) []ethereum.CallMsg {
 	var calls []ethereum.CallMsg
+	for _, p := range pulls {
+		if p.permit != nil {
+			calls = append(calls, *p.permit)
+		}
+	}
+
 	for transferId, t := range template.Transfers {
-		from := common.HexToAddress(template.User.EthereumAddress)
 		to := common.HexToAddress(t.DestinationUserAddress)
-		contract := common.HexToAddress(t.Asset.ContractAddress)
-
-		// amount * 10^decimals
-		decimals := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(t.Asset.Decimals)), nil)
-		amount := new(big.Float).
-			Mul(big.NewFloat(t.Amount), new(big.Float).SetInt(decimals))
+		value := values[transferId]
 
-		value, _ := amount.Int(nil)
+		if strings.EqualFold(t.Asset.ContractAddress, chain.NativeAssetAddress) {
+			calls = append(calls, ethereum.CallMsg{
+				To:    &to,
+				Value: value,
+			})
+			continue
+		}
 
-		data, err := parsedABI.Pack(
-			"transferFrom",
-			from,
-			to,
-			value,
-		)
+		var data []byte
+		var err error
+		if p, ok := pulls[t.AssetID]; ok {
+			data, err = parsedABI.Pack(
+				"transferFrom",
+				p.owner,
+				to,
+				value,
+			)
+		} else {
+			data, err = parsedABI.Pack(
+				"transfer",
+				to,
+				value,
+			)
+		}
 		if err != nil {
 			log.Printf("error creating call: templateId=%d, transferIf=%d", template.ID, transferId)
 			continue
 		}
 
+		contract := common.HexToAddress(t.Asset.ContractAddress)
 		// Ethereum call payload
 		call := ethereum.CallMsg{
 			To:   &contract,
@@ -151,109 +199,267 @@ func encodeCalls(template models.PaymentTemplate) []ethereum.CallMsg {
 	return calls
 }
 
-func getClient(chainID int64) (*ethclient.Client, error) {
-	var rpc string
-
-	switch chainID {
-	case 10:
-		rpc = "https://invictus.ambire.com/optimism"
-	case 8453:
-		rpc = "https://invictus.ambire.com/base"
-	default:
-		return nil, fmt.Errorf("unsupported chain id: %d", chainID)
-	}
-
-	client, err := ethclient.Dial(rpc)
-	if err != nil {
-		return nil, err
-	}
-
-	return client, nil
-}
-
-func sendSelfCall(client *ethclient.Client, priv *ecdsa.PrivateKey, from common.Address, data []byte) error {
+// sendExecute signs and sends an executeBySender call from the executor to the smart account
+func sendExecute(client *ethclient.Client, priv *ecdsa.PrivateKey, from common.Address, account common.Address, data []byte) (common.Hash, error) {
 	ctx := context.Background()
 
 	nonce, err := client.PendingNonceAt(ctx, from)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	// Estimate gas
 	msg := ethereum.CallMsg{
 		From: from,
-		To:   &from, // self-call
+		To:   &account,
 		Data: data,
 	}
 	gasLimit, err := client.EstimateGas(ctx, msg)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	// Suggest gas price
 	gasPrice, err := client.SuggestGasPrice(ctx)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
 	chainID, err := client.ChainID(ctx)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
-	tx := types.NewTransaction(nonce, from, big.NewInt(0), gasLimit, gasPrice, data)
+	tx := types.NewTransaction(nonce, account, big.NewInt(0), gasLimit, gasPrice, data)
 	signedTx, err := types.SignTx(tx, types.LatestSignerForChainID(chainID), priv)
 	if err != nil {
-		return err
+		return common.Hash{}, err
 	}
 
-	return client.SendTransaction(ctx, signedTx)
+	return signedTx.Hash(), client.SendTransaction(ctx, signedTx)
+}
+
+// ScheduledRun is published when a template run is queued
+type ScheduledRun struct {
+	TemplateID uint      `json:"template_id"`
+	RunAt      time.Time `json:"run_at"`
+}
+
+// Schedule queues a run of the template for its current schedule revision and
+// lets the user know about it
+func Schedule(userID uint, templateID uint, revision uint, runAt time.Time) {
+	JobsChan <- Job{RunAt: runAt, TemplateId: templateID, Revision: revision}
+	events.Publish(userID, events.TemplateScheduled, ScheduledRun{TemplateID: templateID, RunAt: runAt})
+}
+
+// setTransferStatus mirrors the outcome of the latest run on the template's transfers
+func setTransferStatus(templateID uint, status models.TransferStatus) {
+	err := database.DB.Model(&models.Transfer{}).
+		Where("payment_template_id = ?", templateID).
+		Update("status", status).Error
+	if err != nil {
+		log.Printf("could not update transfers of templateId=%d: %v", templateID, err)
+	}
+}
+
+// recordExecution stores the outcome of a run and publishes it; failures are also logged
+func recordExecution(userID uint, execution *models.Execution) {
+	if execution.Status == models.ExecutionStatusFailed {
+		log.Printf("execution failed: templateId=%d, err=%s", execution.PaymentTemplateID, execution.Error)
+	}
+	if err := database.DB.Create(execution).Error; err != nil {
+		log.Printf("could not record execution: templateId=%d, err=%v", execution.PaymentTemplateID, err)
+		return
+	}
+
+	action := audit.ActionExecutionSubmit
+	if execution.Status == models.ExecutionStatusFailed {
+		action = audit.ActionExecutionFail
+	}
+	audit.Record(nil, audit.Entry{
+		UserID:     userID,
+		Actor:      audit.ActorSystem,
+		Action:     action,
+		TargetType: audit.TargetExecution,
+		TargetID:   execution.ID,
+		After:      execution,
+	})
+
+	if execution.Status == models.ExecutionStatusFailed {
+		setTransferStatus(execution.PaymentTemplateID, models.TransferStatusFailed)
+		events.Publish(userID, events.ExecutionFailed, execution)
+	} else {
+		setTransferStatus(execution.PaymentTemplateID, models.TransferStatusPending)
+		events.Publish(userID, events.ExecutionSubmitted, execution)
+	}
+}
+
+// awaitingApproval refreshes the template's approval state and reports whether
+// it may not execute yet. Such runs are skipped rather than recorded as failed;
+// approving the template queues its schedule again.
+func awaitingApproval(template *models.PaymentTemplate) bool {
+	approval, err := approvals.Refresh(template)
+	if err != nil {
+		log.Printf("could not evaluate approvals of templateId=%d: %v", template.ID, err)
+		return true
+	}
+	if approval.Status == models.ApprovalStatusNotRequired || approval.Status == models.ApprovalStatusApproved {
+		return false
+	}
+	log.Printf("skipping run of templateId=%d: %s approval, %d of %d approvals", template.ID, approval.Status, approval.Approvals, approval.Required)
+	return true
 }
 
-func executePayments(templateId uint) {
+// executePayments runs a queued job and reports whether its transaction was submitted
+func executePayments(job Job) bool {
+	templateId := job.TemplateId
 
 	var template models.PaymentTemplate
 	err := database.DB.
-		Preload("Transfers").
+		Preload("Transfers", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
 		Preload("User").
 		Preload("Transfers.SourceUser").
 		Preload("Transfers.Asset").
+		Preload("Authorization.MaxTotals.Asset").
+		Preload("Permits.Asset").
+		Preload("Condition.Asset").
 		First(&template, templateId).Error
 
-	if template.IsCancelled {
-		fmt.Printf("Payment %d was cancelled\n", templateId)
-		return
-	}
-
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			log.Printf("payment template not found: templateId=%d", templateId)
 		} else {
 			log.Printf("db error reading templateId=%d: %v", templateId, err)
 		}
-		return
+		return false
+	}
+
+	// The schedule was edited after this run was queued; the edit queued its own
+	if template.ScheduleRevision != job.Revision {
+		fmt.Printf("Payment %d was rescheduled\n", templateId)
+		return false
+	}
+
+	if template.IsCancelled {
+		fmt.Printf("Payment %d was cancelled\n", templateId)
+		return false
 	}
 
-	var calls []ethereum.CallMsg = encodeCalls(template)
+	if template.EndsAt != nil && time.Now().After(*template.EndsAt) {
+		fmt.Printf("Payment %d ended at %s\n", templateId, template.EndsAt.String())
+		return false
+	}
+
+	if template.RecurringInterval != nil && *template.RecurringInterval > 0 {
+		defer func() {
+			future := time.Now().Add(time.Duration(*template.RecurringInterval) * time.Second)
+			Schedule(template.UserID, templateId, job.Revision, future)
+		}()
+	}
+
+	if len(template.Transfers) == 0 {
+		log.Printf("payment template has no transfers: templateId=%d", templateId)
+		return false
+	}
+
+	if awaitingApproval(&template) {
+		return false
+	}
+
+	execution := models.Execution{
+		PaymentTemplateID: template.ID,
+		TemplateVersion:   template.Version,
+		ChainID:           template.Transfers[0].Asset.ChainID,
+		Status:            models.ExecutionStatusFailed,
+	}
+
+	records, values, err := resolveAmounts(template)
+	if err != nil {
+		execution.Error = err.Error()
+		recordExecution(template.UserID, &execution)
+		return false
+	}
+	execution.Transfers = records
+
+	if err := checkAuthorization(template, execution.ChainID, values); err != nil {
+		execution.Error = err.Error()
+		recordExecution(template.UserID, &execution)
+		return false
+	}
+
+	var account models.SmartAccount
+	err = database.DB.
+		Where("user_id = ? AND chain_id = ?", template.UserID, execution.ChainID).
+		First(&account).Error
+	if err != nil {
+		execution.Error = fmt.Sprintf("no smart account registered for chain %d", execution.ChainID)
+		recordExecution(template.UserID, &execution)
+		return false
+	}
+	execution.SmartAccountID = &account.ID
+
+	client, err := chain.GetClient(execution.ChainID)
+	if err != nil {
+		execution.Error = err.Error()
+		recordExecution(template.UserID, &execution)
+		return false
+	}
+	defer client.Close()
 
 	seedPhrase := os.Getenv("EXECUTOR_SEED")
-	priv, addr, _ := walletFromSeed(seedPhrase)
+	priv, addr, err := walletFromSeed(seedPhrase)
+	if err != nil {
+		execution.Error = "invalid executor seed"
+		recordExecution(template.UserID, &execution)
+		return false
+	}
 
-	data, _ := encodeExecute(calls)
+	if err := checkExecutorAuthorization(client, &account, addr); err != nil {
+		execution.Error = err.Error()
+		recordExecution(template.UserID, &execution)
+		return false
+	}
+	if err := database.DB.Save(&account).Error; err != nil {
+		log.Printf("could not update smart account %d: %v", account.ID, err)
+	}
+	if !account.ExecutorAuthorized {
+		execution.Error = fmt.Sprintf("executor is not authorised on smart account %s", account.Address)
+		recordExecution(template.UserID, &execution)
+		return false
+	}
 
-	client, err := getClient(int64(template.Transfers[0].Asset.ChainID))
+	pulls, err := preparePulls(client, template)
 	if err != nil {
-		log.Fatal(err)
-		return
+		execution.Error = err.Error()
+		recordExecution(template.UserID, &execution)
+		return false
 	}
-	sendSelfCall(client, priv, addr, data)
-	fmt.Printf("calls sent %d\n", template.ID)
 
-	if template.RecurringInterval != nil && *template.RecurringInterval > 0 {
-		future := time.Now().Add(time.Duration(*template.RecurringInterval) * time.Second)
-		fmt.Println(future.String())
-		JobsChan <- Job{RunAt: future, TemplateId: templateId}
+	var calls []ethereum.CallMsg = encodeCalls(template, values, pulls)
+
+	data, err := encodeExecute(calls)
+	if err != nil {
+		execution.Error = err.Error()
+		recordExecution(template.UserID, &execution)
+		return false
+	}
+
+	txHash, err := sendExecute(client, priv, addr, common.HexToAddress(account.Address), data)
+	if err != nil {
+		execution.Error = err.Error()
+		recordExecution(template.UserID, &execution)
+		return false
 	}
+
+	markPermitsConsumed(pulls)
+
+	execution.Status = models.ExecutionStatusSubmitted
+	execution.TxHash = txHash.Hex()
+	recordExecution(template.UserID, &execution)
+	fmt.Printf("calls sent %d\n", template.ID)
+
+	go watchConfirmation(template.UserID, execution)
+	return true
 }
 
 func JobWatcher() {
@@ -263,7 +469,7 @@ func JobWatcher() {
 			defer timer.Stop()
 
 			<-timer.C
-			executePayments(j.TemplateId)
+			executePayments(j)
 		}(job)
 	}
 }
@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"backend/chain"
+	"backend/models"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Ambire accounts keep a privileges mapping; a non-zero entry means the
+// address may call executeBySender on the account
+const ambireAccountABI = `[{
+	"name":"privileges",
+	"type":"function",
+	"stateMutability":"view",
+	"inputs":[{"name":"","type":"address"}],
+	"outputs":[{"name":"","type":"bytes32"}]
+}]`
+
+var parsedAmbireABI, _ = abi.JSON(strings.NewReader(ambireAccountABI))
+
+// ErrNotAccountOwner is returned when the user holds no privileges on the account
+var ErrNotAccountOwner = errors.New("your address holds no privileges on this account")
+
+// hasPrivileges reads whether address holds privileges on the Ambire account
+func hasPrivileges(caller ethereum.ContractCaller, account, address common.Address) (bool, error) {
+	data, err := parsedAmbireABI.Pack("privileges", address)
+	if err != nil {
+		return false, err
+	}
+
+	out, err := caller.CallContract(context.Background(), ethereum.CallMsg{To: &account, Data: data}, nil)
+	if err != nil {
+		return false, fmt.Errorf("could not read account privileges: %w", err)
+	}
+	return len(out) == 32 && common.BytesToHash(out) != (common.Hash{}), nil
+}
+
+// checkExecutorAuthorization reads the executor's privileges from the account
+// contract and stores the result on the account
+func checkExecutorAuthorization(client *ethclient.Client, account *models.SmartAccount, executor common.Address) error {
+	switch account.AccountType {
+	case models.SmartAccountTypeAmbire, models.SmartAccountTypeAmbire7702:
+	default:
+		return fmt.Errorf("unsupported account type: %s", account.AccountType)
+	}
+
+	authorized, err := hasPrivileges(client, common.HexToAddress(account.Address), executor)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	account.ExecutorAuthorized = authorized
+	account.AuthorizationCheckedAt = &now
+	return nil
+}
+
+// VerifySmartAccount checks on-chain whether the executor is authorised on the account
+func VerifySmartAccount(account *models.SmartAccount) error {
+	executor, err := ExecutorAddress()
+	if err != nil {
+		return fmt.Errorf("invalid executor seed: %w", err)
+	}
+
+	client, err := chain.GetClient(account.ChainID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return checkExecutorAuthorization(client, account, executor)
+}
+
+// VerifyAccountOwner checks on-chain that owner controls the account, so users
+// cannot register someone else's account. A delegated EOA is its own owner.
+func VerifyAccountOwner(chainID uint64, accountType models.SmartAccountType, account, owner common.Address) error {
+	switch accountType {
+	case models.SmartAccountTypeAmbire7702:
+		if account != owner {
+			return ErrNotAccountOwner
+		}
+		return nil
+	case models.SmartAccountTypeAmbire:
+	default:
+		return fmt.Errorf("unsupported account type: %s", accountType)
+	}
+
+	client, err := chain.GetClient(chainID)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	owned, err := hasPrivileges(client, account, owner)
+	if err != nil {
+		return err
+	}
+	if !owned {
+		return ErrNotAccountOwner
+	}
+	return nil
+}
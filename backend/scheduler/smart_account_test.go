@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"backend/models"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// privilegesCaller answers privileges(address) calls from a map
+type privilegesCaller map[common.Address]common.Hash
+
+func (c privilegesCaller) CallContract(_ context.Context, call ethereum.CallMsg, _ *big.Int) ([]byte, error) {
+	args, err := parsedAmbireABI.Methods["privileges"].Inputs.Unpack(call.Data[4:])
+	if err != nil {
+		return nil, err
+	}
+	hash := c[args[0].(common.Address)]
+	return hash.Bytes(), nil
+}
+
+func TestHasPrivileges(t *testing.T) {
+	account := common.HexToAddress("0x1234567890abcdef1234567890abcdef12345678")
+	owner := common.HexToAddress("0x6969174FD72466430a46e18234D0b530c9FD5f49")
+	stranger := common.HexToAddress("0x0000000000000000000000000000000000000bad")
+	caller := privilegesCaller{owner: common.HexToHash("0x01")}
+
+	for address, want := range map[common.Address]bool{owner: true, stranger: false} {
+		got, err := hasPrivileges(caller, account, address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("hasPrivileges(%s) = %v, want %v", address, got, want)
+		}
+	}
+}
+
+func TestVerifyAccountOwnerDelegatedEOA(t *testing.T) {
+	user := common.HexToAddress("0x6969174FD72466430a46e18234D0b530c9FD5f49")
+	other := common.HexToAddress("0x1234567890abcdef1234567890abcdef12345678")
+
+	if err := VerifyAccountOwner(8453, models.SmartAccountTypeAmbire7702, user, user); err != nil {
+		t.Errorf("own EOA: %v", err)
+	}
+	if err := VerifyAccountOwner(8453, models.SmartAccountTypeAmbire7702, other, user); !errors.Is(err, ErrNotAccountOwner) {
+		t.Errorf("other EOA: got %v, want %v", err, ErrNotAccountOwner)
+	}
+	if err := VerifyAccountOwner(8453, "safe", other, user); err == nil {
+		t.Error("unsupported account type was accepted")
+	}
+}
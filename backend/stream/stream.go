@@ -0,0 +1,54 @@
+package stream
+
+import (
+	"backend/events"
+	"sync"
+)
+
+// bufferSize is how many events a slow client may fall behind before events are dropped for it
+const bufferSize = 32
+
+var (
+	mu      sync.Mutex
+	clients = make(map[uint]map[chan events.Event]struct{})
+)
+
+// Start forwards every published event to the connected clients of its user
+func Start() {
+	events.Subscribe(broadcast)
+}
+
+// Subscribe registers a client of the user; call cancel once it disconnects
+func Subscribe(userID uint) (<-chan events.Event, func()) {
+	ch := make(chan events.Event, bufferSize)
+
+	mu.Lock()
+	if clients[userID] == nil {
+		clients[userID] = make(map[chan events.Event]struct{})
+	}
+	clients[userID][ch] = struct{}{}
+	mu.Unlock()
+
+	cancel := func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(clients[userID], ch)
+		if len(clients[userID]) == 0 {
+			delete(clients, userID)
+		}
+	}
+	return ch, cancel
+}
+
+func broadcast(event events.Event) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for ch := range clients[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+			// Client is not keeping up; it refetches on reconnect
+		}
+	}
+}
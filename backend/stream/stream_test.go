@@ -0,0 +1,54 @@
+package stream
+
+import (
+	"testing"
+
+	"backend/events"
+)
+
+func TestBroadcastReachesOnlyTheUsersClients(t *testing.T) {
+	mine, cancelMine := Subscribe(1)
+	defer cancelMine()
+	other, cancelOther := Subscribe(2)
+	defer cancelOther()
+
+	broadcast(events.Event{ID: "evt_1", UserID: 1, Type: events.TemplateCreated})
+
+	select {
+	case event := <-mine:
+		if event.ID != "evt_1" {
+			t.Errorf("got event %s, want evt_1", event.ID)
+		}
+	default:
+		t.Fatal("subscribed client did not get the event")
+	}
+	select {
+	case event := <-other:
+		t.Errorf("client of another user got event %s", event.ID)
+	default:
+	}
+}
+
+func TestBroadcastDropsEventsForSlowClients(t *testing.T) {
+	ch, cancel := Subscribe(3)
+	defer cancel()
+
+	// Broadcasting must not block on a client that stopped reading
+	for i := 0; i < bufferSize+5; i++ {
+		broadcast(events.Event{UserID: 3})
+	}
+	if len(ch) != bufferSize {
+		t.Errorf("client has %d buffered events, want %d", len(ch), bufferSize)
+	}
+}
+
+func TestCancelUnsubscribes(t *testing.T) {
+	_, cancel := Subscribe(4)
+	cancel()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := clients[4]; ok {
+		t.Error("user still has clients after its only client cancelled")
+	}
+}
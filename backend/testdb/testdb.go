@@ -0,0 +1,150 @@
+// Package testdb points database.DB at an in-memory fake for tests. It records
+// the statements that change data and answers queries with a function of the
+// test, so no MySQL server is needed.
+package testdb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"backend/database"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Rows is the result of a query
+type Rows struct {
+	Columns []string
+	Values  [][]driver.Value
+}
+
+// QueryFunc answers a SELECT with its rows; returning an error fails the query
+type QueryFunc func(query string, args []driver.Value) (Rows, error)
+
+// Statement is an INSERT, UPDATE or DELETE the code under test ran
+type Statement struct {
+	Query string
+	Args  []driver.Value
+}
+
+// DB is the fake behind database.DB
+type DB struct {
+	query QueryFunc
+
+	// Affected, when set, is the number of rows a statement changes; by
+	// default every statement changes one
+	Affected func(query string, args []driver.Value) int64
+
+	mu    sync.Mutex
+	execs []Statement
+}
+
+// Use points database.DB at a fake answering queries with query, until the
+// test ends. A nil query fails every query.
+func Use(t *testing.T, query QueryFunc) *DB {
+	t.Helper()
+	if query == nil {
+		query = func(q string, _ []driver.Value) (Rows, error) { return Rows{}, errors.New("unexpected query: " + q) }
+	}
+
+	fake := &DB{query: query}
+	conn := sql.OpenDB(fake)
+	db, err := gorm.Open(mysql.New(mysql.Config{Conn: conn, SkipInitializeWithVersion: true}), &gorm.Config{Logger: logger.Discard, TranslateError: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() {
+		database.DB = previous
+		conn.Close()
+	})
+	return fake
+}
+
+// Execs returns the statements run so far whose query contains substr
+func (db *DB) Execs(substr string) []Statement {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	var matching []Statement
+	for _, s := range db.execs {
+		if strings.Contains(s.Query, substr) {
+			matching = append(matching, s)
+		}
+	}
+	return matching
+}
+
+// Connect implements driver.Connector
+func (db *DB) Connect(context.Context) (driver.Conn, error) { return conn{db}, nil }
+
+// Driver implements driver.Connector
+func (db *DB) Driver() driver.Driver { return nil }
+
+type conn struct{ db *DB }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.db, query}, nil }
+func (c conn) Close() error                              { return nil }
+func (c conn) Begin() (driver.Tx, error)                 { return tx{}, nil }
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type stmt struct {
+	db    *DB
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+
+// Exec records the statement and reports the rows it changed
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+	s.db.execs = append(s.db.execs, Statement{Query: s.query, Args: args})
+
+	affected := int64(1)
+	if s.db.Affected != nil {
+		affected = s.db.Affected(s.query, args)
+	}
+	return result{id: int64(len(s.db.execs)), affected: affected}, nil
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	r, err := s.db.query(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{r}, nil
+}
+
+type result struct{ id, affected int64 }
+
+func (r result) LastInsertId() (int64, error) { return r.id, nil }
+func (r result) RowsAffected() (int64, error) { return r.affected, nil }
+
+type rows struct{ Rows }
+
+func (r *rows) Columns() []string { return r.Rows.Columns }
+func (r *rows) Close() error      { return nil }
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.Values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.Values[0])
+	r.Values = r.Values[1:]
+	return nil
+}
@@ -0,0 +1,45 @@
+package webhooks
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{"https://203.0.113.9/hooks", nil},
+		{"https://[2001:4860:4860::8888]/hooks", nil},
+		{"https://127.0.0.1/hooks", ErrPrivateAddress},
+		{"https://10.1.2.3/hooks", ErrPrivateAddress},
+		{"https://169.254.169.254/latest/meta-data", ErrPrivateAddress},
+		{"https://100.64.0.1/hooks", ErrPrivateAddress},
+		{"https://[::1]/hooks", ErrPrivateAddress},
+		{"https://[64:ff9b::a00:1]/hooks", ErrPrivateAddress},
+	}
+	for _, tt := range tests {
+		if err := ValidateURL(tt.url); !errors.Is(err, tt.want) {
+			t.Errorf("ValidateURL(%q) = %v, want %v", tt.url, err, tt.want)
+		}
+	}
+
+	for _, url := range []string{"http://203.0.113.9/hooks", "https://", "not a url"} {
+		if err := ValidateURL(url); err == nil {
+			t.Errorf("ValidateURL(%q) accepted", url)
+		}
+	}
+}
+
+func TestDeliveriesRefusePrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	_, err := newHTTPClient().Get(server.URL)
+	if !errors.Is(err, ErrPrivateAddress) {
+		t.Errorf("delivery to %s: err = %v, want %v", server.URL, err, ErrPrivateAddress)
+	}
+}
@@ -0,0 +1,200 @@
+package webhooks
+
+import (
+	"backend/database"
+	"backend/events"
+	"backend/models"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxAttempts is how many times a delivery is tried before it is marked failed
+	MaxAttempts = 8
+
+	// SignatureHeader carries "t=<unix>,v1=<hex hmac-sha256 of "<unix>.<body>">"
+	SignatureHeader = "X-GoPayments-Signature"
+	EventHeader     = "X-GoPayments-Event"
+	DeliveryHeader  = "X-GoPayments-Delivery"
+
+	pollInterval = 5 * time.Second
+	baseBackoff  = 30 * time.Second
+	maxBackoff   = 6 * time.Hour
+	batchSize    = 50
+)
+
+var httpClient = newHTTPClient()
+
+// Start queues deliveries for published events and runs the delivery worker
+func Start() {
+	events.Subscribe(enqueue)
+	go deliveryWorker()
+}
+
+// NewSecret generates a signing secret for an endpoint
+func NewSecret() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return "whsec_" + hex.EncodeToString(b)
+}
+
+// Sign returns the signature header value for body sent at t
+func Sign(secret string, t time.Time, body []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Subscribed reports whether the endpoint's filter includes the event type
+func Subscribed(endpoint models.WebhookEndpoint, eventType events.Type) bool {
+	if endpoint.Events == "" {
+		return true
+	}
+	for _, e := range strings.Split(endpoint.Events, ",") {
+		if strings.TrimSpace(e) == string(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue persists a pending delivery of the event for each of the user's matching endpoints
+func enqueue(event events.Event) {
+	var endpoints []models.WebhookEndpoint
+	if err := database.DB.Where("user_id = ? AND active = ?", event.UserID, true).Find(&endpoints).Error; err != nil {
+		log.Printf("could not load webhook endpoints: userId=%d, err=%v", event.UserID, err)
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("could not encode event %s: %v", event.ID, err)
+		return
+	}
+
+	for _, endpoint := range endpoints {
+		if !Subscribed(endpoint, event.Type) {
+			continue
+		}
+
+		delivery := models.WebhookDelivery{
+			WebhookEndpointID: endpoint.ID,
+			EventID:           event.ID,
+			EventType:         string(event.Type),
+			Payload:           string(payload),
+			Status:            models.WebhookDeliveryStatusPending,
+			NextAttemptAt:     time.Now(),
+		}
+		if err := database.DB.Create(&delivery).Error; err != nil {
+			log.Printf("could not queue webhook delivery: endpointId=%d, err=%v", endpoint.ID, err)
+		}
+	}
+}
+
+// Redeliver queues a fresh copy of a previous delivery
+func Redeliver(previous models.WebhookDelivery) (models.WebhookDelivery, error) {
+	delivery := models.WebhookDelivery{
+		WebhookEndpointID: previous.WebhookEndpointID,
+		EventID:           previous.EventID,
+		EventType:         previous.EventType,
+		Payload:           previous.Payload,
+		Status:            models.WebhookDeliveryStatusPending,
+		NextAttemptAt:     time.Now(),
+	}
+	err := database.DB.Create(&delivery).Error
+	return delivery, err
+}
+
+func deliveryWorker() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var deliveries []models.WebhookDelivery
+		err := database.DB.
+			Preload("WebhookEndpoint").
+			Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryStatusPending, time.Now()).
+			Order("next_attempt_at").
+			Limit(batchSize).
+			Find(&deliveries).Error
+		if err != nil {
+			log.Printf("could not load webhook deliveries: %v", err)
+			continue
+		}
+
+		for _, d := range deliveries {
+			attempt(d)
+		}
+	}
+}
+
+// attempt sends the delivery once and schedules a retry with exponential backoff on failure
+func attempt(delivery models.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+
+	status, err := send(delivery)
+	delivery.ResponseStatus = status
+
+	switch {
+	case err == nil:
+		delivery.Status = models.WebhookDeliveryStatusDelivered
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+	case delivery.Attempts >= MaxAttempts:
+		delivery.Status = models.WebhookDeliveryStatusFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		delivery.NextAttemptAt = now.Add(backoff(delivery.Attempts))
+	}
+
+	if err := database.DB.Omit("WebhookEndpoint").Save(&delivery).Error; err != nil {
+		log.Printf("could not update webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+func send(delivery models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequest(http.MethodPost, delivery.WebhookEndpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.EventType)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(SignatureHeader, Sign(delivery.WebhookEndpoint.Secret, time.Now(), body))
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func backoff(attempts int) time.Duration {
+	wait := baseBackoff << (attempts - 1)
+	if wait <= 0 || wait > maxBackoff {
+		return maxBackoff
+	}
+	return wait
+}
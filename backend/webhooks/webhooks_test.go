@@ -0,0 +1,55 @@
+package webhooks
+
+import (
+	"testing"
+	"time"
+
+	"backend/events"
+	"backend/models"
+)
+
+func TestSign(t *testing.T) {
+	got := Sign("whsec_test", time.Unix(1700000000, 0), []byte(`{"type":"template.created"}`))
+	want := "t=1700000000,v1=fca782e46901d40ab67069f857747ab01fb3c9361335069552ed56cf4c6bfaa4"
+	if got != want {
+		t.Errorf("Sign = %q, want %q", got, want)
+	}
+}
+
+func TestSubscribed(t *testing.T) {
+	tests := []struct {
+		filter string
+		event  events.Type
+		want   bool
+	}{
+		{"", events.TemplateCreated, true},
+		{"template.created", events.TemplateCreated, true},
+		{"template.updated, template.created", events.TemplateCreated, true},
+		{"template.updated", events.TemplateCreated, false},
+		{"template", events.TemplateCreated, false},
+	}
+	for _, tt := range tests {
+		if got := Subscribed(models.WebhookEndpoint{Events: tt.filter}, tt.event); got != tt.want {
+			t.Errorf("Subscribed(%q, %s) = %v, want %v", tt.filter, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{5, 8 * time.Minute},
+		{MaxAttempts, 64 * time.Minute},
+		{11, 6 * time.Hour},
+		{100, 6 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
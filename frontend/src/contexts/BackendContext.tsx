@@ -99,12 +99,54 @@ const BackendContext = createContext<BackendContextValue | undefined>(
 // Backend API base URL - adjust this to match your backend
 const API_BASE_URL = import.meta.env.VITE_API_URL || "http://localhost:8080";
 
+// CSRF token of the current session, sent on every state-changing request
+let csrfToken: string | null = null;
+const rememberCsrfToken = (response: Response) => {
+  const token = response.headers.get("X-CSRF-Token");
+  if (token) csrfToken = token;
+};
+const csrfHeaders = (): Record<string, string> =>
+  csrfToken ? { "X-CSRF-Token": csrfToken } : {};
+
+// Events of the backend's /stream that change the History page
+const STREAM_EVENTS = [
+  "template.created",
+  "template.updated",
+  "template.cancelled",
+  "template.scheduled",
+  "execution.submitted",
+  "execution.confirmed",
+  "execution.failed",
+];
+const STREAM_RETRY_MS = 5000;
+
+// Path of the next page in a Link header, if any
+const nextPageLink = (response: Response): string | null => {
+  const link = response.headers.get("Link");
+  const match = link?.match(/<([^>]+)>;\s*rel="next"/);
+  return match ? match[1] : null;
+};
+
+// Runs of a recurring payment covered by its signed max totals
+const RECURRING_MAX_RUNS = 12;
+
+// Most the backend may move per asset: one run for a scheduled payment,
+// RECURRING_MAX_RUNS runs for a recurring one
+const authorizationMaxTotals = (movements: Movement[], type: TypeOfBatch) => {
+  const runs = type === TypeOfBatch.Recurring ? RECURRING_MAX_RUNS : 1;
+  const totals = new Map<number, number>();
+  for (const m of movements) {
+    totals.set(m.asset.id, (totals.get(m.asset.id) ?? 0) + m.amount * runs);
+  }
+  return [...totals].map(([assetId, amount]) => ({ assetId, amount }));
+};
+
 // Provider props
 type BackendProviderProps = React.PropsWithChildren;
 
 // Create the provider component
 export function BackendProvider({ children }: BackendProviderProps) {
-  const { account: ethereumAccount } = useEthereum();
+  const { account: ethereumAccount, signTypedData } = useEthereum();
 
   // State for user data
   const [user, setUser] = useState<User>({ status: "not_connected" });
@@ -123,11 +165,24 @@ export function BackendProvider({ children }: BackendProviderProps) {
     async (userAddress: string) => {
       setUser({ status: "loading" });
       try {
-        const response = await fetch(`${API_BASE_URL}/users/${userAddress}`, {
-          method: "GET",
-          headers: { "Content-Type": "application/json" },
-          credentials: "include",
-        });
+        const getUser = () =>
+          fetch(`${API_BASE_URL}/users/${userAddress}`, {
+            method: "GET",
+            headers: { "Content-Type": "application/json" },
+            credentials: "include",
+          });
+        let response = await getUser();
+        if (response.status === 401) {
+          // The access token expired; try to renew it with the refresh token
+          const refreshed = await fetch(`${API_BASE_URL}/auth/refresh`, {
+            method: "POST",
+            credentials: "include",
+          });
+          if (refreshed.ok) {
+            rememberCsrfToken(refreshed);
+            response = await getUser();
+          }
+        }
 
         if (!response.ok) {
           if (response.status === 404) {
@@ -148,6 +203,13 @@ export function BackendProvider({ children }: BackendProviderProps) {
           }
         } else {
           const userData = (await response.json()) as UserInfo;
+          if (!csrfToken) {
+            // After a page reload only the cookies are left
+            const csrf = await fetch(`${API_BASE_URL}/auth/csrf`, {
+              credentials: "include",
+            });
+            if (csrf.ok) rememberCsrfToken(csrf);
+          }
           setUser({ status: "ready", user: userData });
         }
       } catch (error) {
@@ -178,7 +240,7 @@ export function BackendProvider({ children }: BackendProviderProps) {
         body: JSON.stringify({ userAddress: account, message, signature }),
         credentials: "include",
       }).then((r) => {
-        console.log(r);
+        rememberCsrfToken(r);
       });
       fetchUser(user.address);
     },
@@ -188,6 +250,7 @@ export function BackendProvider({ children }: BackendProviderProps) {
     async (templateId: number) => {
       await fetch(`${API_BASE_URL}/templates/${templateId}`, {
         method: "DELETE",
+        headers: csrfHeaders(),
         credentials: "include",
       });
 
@@ -200,6 +263,7 @@ export function BackendProvider({ children }: BackendProviderProps) {
     async (templateId: number, newName: string) => {
       await fetch(`${API_BASE_URL}/templates/${templateId}`, {
         method: "PUT",
+        headers: csrfHeaders(),
         credentials: "include",
         body: JSON.stringify({ newName }),
       });
@@ -232,26 +296,26 @@ export function BackendProvider({ children }: BackendProviderProps) {
     setTemplatesError(null);
 
     try {
-      const response = await fetch(
-        `${API_BASE_URL}/templates/${user.user.ethereum_address}`,
-        {
+      // The backend returns templates a page at a time, linking the next one
+      const allTemplates: PaymentTemplate[] = [];
+      let next: string | null = `/templates/${user.user.ethereum_address}`;
+      while (next) {
+        const response = await fetch(`${API_BASE_URL}${next}`, {
           method: "GET",
           headers: { "Content-Type": "application/json" },
           credentials: "include",
-        },
-      );
+        });
 
-      if (!response.ok) {
-        if (response.status === 404) {
+        if (!response.ok) {
           // User not found means no templates
-          setTemplates([]);
-        } else {
+          if (response.status === 404) break;
           throw new Error(`Failed to fetch templates: ${response.statusText}`);
         }
-      } else {
         const templatesData = (await response.json()) as PaymentTemplate[];
-        setTemplates(templatesData || []);
+        allTemplates.push(...(templatesData || []));
+        next = nextPageLink(response);
       }
+      setTemplates(allTemplates);
     } catch (error) {
       console.error("Error fetching templates:", error);
       const errorMessage =
@@ -263,6 +327,48 @@ export function BackendProvider({ children }: BackendProviderProps) {
     }
   }, [user]);
 
+  // Keep the History page live: templates are fetched again whenever the
+  // backend streams an event about them
+  useEffect(() => {
+    if (user.status !== "ready") return;
+
+    let source: EventSource | null = null;
+    let retry: ReturnType<typeof setTimeout> | undefined;
+    let opened = false;
+    const connect = () => {
+      source = new EventSource(`${API_BASE_URL}/stream`, {
+        withCredentials: true,
+      });
+      for (const type of STREAM_EVENTS) {
+        source.addEventListener(type, () => fetchTemplates());
+      }
+      source.onopen = () => {
+        // Events missed while disconnected are not replayed
+        if (opened) fetchTemplates();
+        opened = true;
+      };
+      source.onerror = () => {
+        // The browser reconnects by itself unless the stream was refused,
+        // e.g. once the access token expired
+        if (source?.readyState !== EventSource.CLOSED) return;
+        retry = setTimeout(async () => {
+          const refreshed = await fetch(`${API_BASE_URL}/auth/refresh`, {
+            method: "POST",
+            credentials: "include",
+          });
+          if (refreshed.ok) rememberCsrfToken(refreshed);
+          connect();
+        }, STREAM_RETRY_MS);
+      };
+    };
+    connect();
+
+    return () => {
+      clearTimeout(retry);
+      source?.close();
+    };
+  }, [user.status, fetchTemplates]);
+
   // Fetch assets once on mount
   useEffect(() => {
     const fetchAssets = async () => {
@@ -308,23 +414,59 @@ export function BackendProvider({ children }: BackendProviderProps) {
       scheduledAt: number;
       timeInterval?: number;
     }) => {
+      const request: Record<string, unknown> = {
+        userAddress: account,
+        chainId,
+        transfers: movements,
+        type,
+        scheduledAt,
+        timeInterval,
+      };
+
+      // Scheduled and recurring payments run without the wallet, so the
+      // backend needs a signed authorization of the template
+      if (type !== TypeOfBatch.Now) {
+        const maxTotals = authorizationMaxTotals(movements, type);
+        const typedDataResponse = await fetch(
+          `${API_BASE_URL}/templates/${account}/typed-data`,
+          {
+            method: "POST",
+            headers: { "Content-Type": "application/json", ...csrfHeaders() },
+            body: JSON.stringify({ ...request, authorization: { maxTotals } }),
+            credentials: "include",
+          },
+        );
+        if (!typedDataResponse.ok) {
+          throw new Error(
+            `Failed to prepare authorization: ${await typedDataResponse.text()}`,
+          );
+        }
+        const typedData = (await typedDataResponse.json()) as {
+          message: { nonce: string };
+        };
+
+        const signature = await signTypedData(typedData);
+        if (!signature) throw new Error("Authorization was not signed");
+        request.authorization = {
+          maxTotals,
+          signature,
+          nonce: typedData.message.nonce,
+        };
+      }
+
       const response = await fetch(`${API_BASE_URL}/templates/${account}`, {
         method: "POST",
-        headers: { "Content-Type": "application/json" },
-        body: JSON.stringify({
-          userAddress: account,
-          chainId,
-          transfers: movements,
-          type,
-          scheduledAt,
-          timeInterval,
-        }),
+        headers: { "Content-Type": "application/json", ...csrfHeaders() },
+        body: JSON.stringify(request),
         credentials: "include",
       });
+      if (!response.ok) {
+        throw new Error(`Failed to create payment: ${await response.text()}`);
+      }
 
       fetchTemplates();
     },
-    [],
+    [signTypedData, fetchTemplates],
   );
 
   const value: BackendContextValue = {
@@ -14,6 +14,8 @@ export type EthereumAccountState =
   | { status: "error"; error: string }
   | { status: "idle" };
 
+const API_BASE_URL = import.meta.env.VITE_API_URL || "http://localhost:8080";
+
 // Ethereum provider interface (EIP-1193)
 interface EthereumProviderAPI {
   request(args: { method: string; params?: unknown[] }): Promise<unknown[]>;
@@ -40,6 +42,7 @@ interface EthereumContextValue {
     chainId: number,
     calls: { to: string; value: bigint; data: string }[],
   ) => Promise<string>;
+  signTypedData: (typedData: object) => Promise<string | null>;
 }
 
 // Create the context
@@ -93,11 +96,26 @@ export function EthereumProvider({ children }: EthereumProviderProps) {
     if (!window.ethereum) return null;
 
     if (account.status !== "connected") return null;
-    const message = `Welcome to GoPayments!
-Address: ${account.account}
-Timestamp: ${Date.now()}
-
-Sign this message to log in. Do not share this message with anyone.`;
+    const nonceResponse = await fetch(
+      `${API_BASE_URL}/auth/nonce?address=${account.account}`,
+    );
+    if (!nonceResponse.ok) return null;
+    const { nonce } = (await nonceResponse.json()) as { nonce: string };
+    const chainId = (await window.ethereum.request({
+      method: "eth_chainId",
+    })) as unknown as string;
+
+    // Sign-In with Ethereum (EIP-4361) message
+    const message = `${window.location.host} wants you to sign in with your Ethereum account:
+${account.account}
+
+Sign in to GoPayments. Do not share this message with anyone.
+
+URI: ${window.location.origin}
+Version: 1
+Chain ID: ${parseInt(chainId, 16)}
+Nonce: ${nonce}
+Issued At: ${new Date().toISOString()}`;
     const signature = await window.ethereum
       .request({
         method: "personal_sign",
@@ -142,11 +160,32 @@ Sign this message to log in. Do not share this message with anyone.`;
     [window, account],
   );
 
+  // Signs EIP-712 typed data, e.g. the authorization of a scheduled payment
+  const signTypedData = useCallback(
+    async (typedData: object): Promise<string | null> => {
+      if (!window.ethereum) return null;
+      if (account.status !== "connected") return null;
+
+      const signature = await window.ethereum
+        .request({
+          method: "eth_signTypedData_v4",
+          params: [account.account, JSON.stringify(typedData)],
+        })
+        .catch((e) => {
+          console.log("Error signing", e);
+        });
+      if (typeof signature !== "string") return null;
+      return signature;
+    },
+    [window, account],
+  );
+
   const value: EthereumContextValue = {
     account,
     requestAccount,
     signLoginMessage,
     sendCallsViaWallet,
+    signTypedData,
   };
 
   return (